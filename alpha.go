@@ -0,0 +1,75 @@
+package gltf
+
+import "fmt"
+
+// AlphaMode is how a material's alpha, the fourth component of its base color, is used.
+type AlphaMode string
+
+// The alpha modes glTF allows.  See https://registry.khronos.org/glTF/specs/2.0/glTF-2.0.html#alpha-coverage
+const (
+	// AlphaOpaque ignores the alpha: the material is drawn fully opaque.
+	AlphaOpaque AlphaMode = "OPAQUE"
+	// AlphaMask draws the material fully opaque where its alpha is at least the cutoff, and not at all elsewhere.
+	AlphaMask AlphaMode = "MASK"
+	// AlphaBlend blends the material with what's behind it, by its alpha.
+	AlphaBlend AlphaMode = "BLEND"
+)
+
+// defaultAlphaCutoff is the spec's cutoff for AlphaMask when a material doesn't give one.
+const defaultAlphaCutoff = 0.5
+
+// returns the alphaMode and alphaCutoff the Material's glTF material gets.  An empty mode is written as nothing, which
+// viewers take as OPAQUE, and a cutoff of 0 as nothing, which only MASK uses.
+func (material Material) alpha() (string, float64) {
+	switch material.AlphaMode {
+	case AlphaOpaque:
+		// OPAQUE is the spec default, so there's no need to write it.
+		return "", 0
+	case AlphaMask:
+		if material.AlphaCutoff == 0 {
+			return string(AlphaMask), defaultAlphaCutoff
+		}
+
+		return string(AlphaMask), float64(material.AlphaCutoff)
+	case AlphaBlend:
+		return string(AlphaBlend), 0
+	}
+
+	if material.Opacity < 1 {
+		return string(AlphaBlend), 0
+	}
+
+	return "", 0
+}
+
+// makes sure the Material's AlphaMode is one glTF allows, or unset, and its AlphaCutoff isn't negative.
+func (material Material) checkAlpha() error {
+	switch material.AlphaMode {
+	case "", AlphaOpaque, AlphaMask, AlphaBlend:
+	default:
+		return fmt.Errorf("alpha mode %q is not %s, %s or %s", material.AlphaMode, AlphaOpaque, AlphaMask, AlphaBlend)
+	}
+
+	if material.AlphaCutoff < 0 {
+		return fmt.Errorf("alpha cutoff is %g; it can't be less than 0", material.AlphaCutoff)
+	}
+
+	return nil
+}
+
+// makes sure the glTF material's alphaMode is one the spec allows, or unset.
+func (material GltfMaterial) checkAlpha() error {
+	switch mode := material.AlphaMode.(type) {
+	case nil:
+		return nil
+	case string:
+		switch AlphaMode(mode) {
+		case AlphaOpaque, AlphaMask, AlphaBlend:
+			return nil
+		}
+
+		return fmt.Errorf("alphaMode %q is not %s, %s or %s", mode, AlphaOpaque, AlphaMask, AlphaBlend)
+	default:
+		return fmt.Errorf("alphaMode is %v, which isn't a string", mode)
+	}
+}
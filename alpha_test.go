@@ -0,0 +1,65 @@
+package gltf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHalfOpacityBlends(t *testing.T) {
+	tests := []struct {
+		name         string
+		vertexColors bool
+		factor       []float64
+	}{
+		{"vertex colors", true, []float64{0, 1, 0, 0.5}},
+		// the atlas already holds the color, so the factor only carries the alpha.
+		{"atlas", false, []float64{1, 1, 1, 0.5}},
+	}
+
+	for _, test := range tests {
+		green := Material{DiffuseColor: [3]float32{0, 1, 0}, Opacity: 0.5}
+		gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{testTriangle(green)}}, PipelineOptions{Options: Options{VertexColors: test.vertexColors}})
+		material := gltfDoc.Materials[0]
+
+		if material.AlphaMode != string(AlphaBlend) {
+			t.Errorf("%s: alphaMode is %v, want %s", test.name, material.AlphaMode, AlphaBlend)
+		}
+
+		if material.AlphaCutoff != 0 {
+			t.Errorf("%s: alphaCutoff is %g, want it left out", test.name, material.AlphaCutoff)
+		}
+
+		if got := material.PbrMetallicRoughness.BaseColorFactor; !reflect.DeepEqual(got, test.factor) {
+			t.Errorf("%s: baseColorFactor is %v, want %v", test.name, got, test.factor)
+		}
+	}
+}
+
+func TestMaterialAlpha(t *testing.T) {
+	tests := []struct {
+		name     string
+		material Material
+		mode     string
+		cutoff   float64
+	}{
+		{"opaque by default", Material{Opacity: 1}, "", 0},
+		{"translucent", Material{Opacity: 0.5}, "BLEND", 0},
+		{"opaque though translucent", Material{Opacity: 0.5, AlphaMode: AlphaOpaque}, "", 0},
+		{"mask at the default cutoff", Material{Opacity: 1, AlphaMode: AlphaMask}, "MASK", 0.5},
+		{"mask at its own cutoff", Material{Opacity: 1, AlphaMode: AlphaMask, AlphaCutoff: 0.25}, "MASK", 0.25},
+		// only MASK has a cutoff.
+		{"blend ignores the cutoff", Material{Opacity: 1, AlphaMode: AlphaBlend, AlphaCutoff: 0.25}, "BLEND", 0},
+	}
+
+	for _, test := range tests {
+		if mode, cutoff := test.material.alpha(); mode != test.mode || cutoff != test.cutoff {
+			t.Errorf("%s: alpha is %q, %g; want %q, %g", test.name, mode, cutoff, test.mode, test.cutoff)
+		}
+	}
+
+	for _, bad := range []Material{{AlphaMode: "blend"}, {AlphaMode: AlphaMask, AlphaCutoff: -1}} {
+		if err := bad.checkAlpha(); err == nil {
+			t.Errorf("alpha mode %q with cutoff %g was accepted", bad.AlphaMode, bad.AlphaCutoff)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package gltf
+
+import (
+	"math"
+	"sort"
+)
+
+// UVOverlap identifies two triangles, by their index in Geometry.Faces, whose areas in UV space overlap.
+type UVOverlap struct {
+	A int
+	B int
+}
+
+// CheckUVOverlap reports every pair of triangles whose UVs overlap.  Triangles that merely share an edge or a corner
+// don't count; their interiors have to intersect.  Overlapping UVs are fine for ordinary texturing but break
+// lightmap baking, so this is meant to help decide whether a mesh needs to be re-unwrapped.  Nothing is changed.
+//
+// Triangles are bucketed into a uniform grid over the UV bounds, so only triangles that share a grid cell are tested
+// against each other.
+func CheckUVOverlap(geo Geometry) []UVOverlap {
+	overlaps := []UVOverlap{}
+
+	if len(geo.Faces) < 2 {
+		return overlaps
+	}
+
+	triangles := make([][3]Vector2, len(geo.Faces))
+
+	minU, minV := float32(math.MaxFloat32), float32(math.MaxFloat32)
+	maxU, maxV := float32(-math.MaxFloat32), float32(-math.MaxFloat32)
+
+	for i, f := range geo.Faces {
+		for c, index := range f.TriangleIndices {
+			uv := geo.Vertices[index].UV
+			triangles[i][c] = uv
+
+			minU = float32(math.Min(float64(minU), float64(uv.U)))
+			minV = float32(math.Min(float64(minV), float64(uv.V)))
+			maxU = float32(math.Max(float64(maxU), float64(uv.U)))
+			maxV = float32(math.Max(float64(maxV), float64(uv.V)))
+		}
+	}
+
+	// aim for about one triangle per cell.
+	gridSize := int(math.Ceil(math.Sqrt(float64(len(triangles)))))
+	cellU := (maxU - minU) / float32(gridSize)
+	cellV := (maxV - minV) / float32(gridSize)
+
+	cellOf := func(u, v float32) (int, int) {
+		x, y := 0, 0
+
+		if cellU > 0 {
+			x = int((u - minU) / cellU)
+		}
+
+		if cellV > 0 {
+			y = int((v - minV) / cellV)
+		}
+
+		// the maximum edge of the bounds lands exactly on gridSize.
+		if x >= gridSize {
+			x = gridSize - 1
+		}
+
+		if y >= gridSize {
+			y = gridSize - 1
+		}
+
+		return x, y
+	}
+
+	grid := make(map[[2]int][]int)
+
+	for i, t := range triangles {
+		// triangles without any area in UV space can't overlap anything.
+		if uvArea(t) == 0 {
+			continue
+		}
+
+		lo, hi := uvBounds(t)
+		x0, y0 := cellOf(lo.U, lo.V)
+		x1, y1 := cellOf(hi.U, hi.V)
+
+		for x := x0; x <= x1; x++ {
+			for y := y0; y <= y1; y++ {
+				grid[[2]int{x, y}] = append(grid[[2]int{x, y}], i)
+			}
+		}
+	}
+
+	// a pair that spans several cells would be found in each of them, so remember which pairs were already tested.
+	tested := make(map[UVOverlap]bool)
+
+	for _, cell := range grid {
+		for i := 0; i < len(cell); i++ {
+			for j := i + 1; j < len(cell); j++ {
+				pair := UVOverlap{A: cell[i], B: cell[j]}
+
+				if pair.A > pair.B {
+					pair.A, pair.B = pair.B, pair.A
+				}
+
+				if tested[pair] {
+					continue
+				}
+
+				tested[pair] = true
+
+				if uvTrianglesOverlap(triangles[pair.A], triangles[pair.B]) {
+					overlaps = append(overlaps, pair)
+				}
+			}
+		}
+	}
+
+	// map iteration order is random, so sort the results to keep them stable from run to run.
+	sort.Slice(overlaps, func(i, j int) bool {
+		if overlaps[i].A != overlaps[j].A {
+			return overlaps[i].A < overlaps[j].A
+		}
+
+		return overlaps[i].B < overlaps[j].B
+	})
+
+	return overlaps
+}
+
+// returns the corners of the UV-space bounding box of the supplied triangle.
+func uvBounds(t [3]Vector2) (lo, hi Vector2) {
+	lo, hi = t[0], t[0]
+
+	for _, uv := range t[1:] {
+		lo.U = float32(math.Min(float64(lo.U), float64(uv.U)))
+		lo.V = float32(math.Min(float64(lo.V), float64(uv.V)))
+		hi.U = float32(math.Max(float64(hi.U), float64(uv.U)))
+		hi.V = float32(math.Max(float64(hi.V), float64(uv.V)))
+	}
+
+	return lo, hi
+}
+
+// returns the signed area of the supplied triangle in UV space.
+func uvArea(t [3]Vector2) float32 {
+	return ((t[1].U-t[0].U)*(t[2].V-t[0].V) - (t[2].U-t[0].U)*(t[1].V-t[0].V)) / 2
+}
+
+// reports whether the interiors of two UV triangles intersect, using the separating axis test on the six edge
+// normals.  Projections that only touch count as separated, so neighboring triangles aren't reported.
+func uvTrianglesOverlap(a, b [3]Vector2) bool {
+	const epsilon = 1e-7
+
+	for _, t := range [2][3]Vector2{a, b} {
+		for i := 0; i < 3; i++ {
+			edgeU := t[(i+1)%3].U - t[i].U
+			edgeV := t[(i+1)%3].V - t[i].V
+
+			// the edge normal is the axis to project onto.
+			axisU, axisV := -edgeV, edgeU
+
+			minA, maxA := projectTriangle(a, axisU, axisV)
+			minB, maxB := projectTriangle(b, axisU, axisV)
+
+			if maxA-minB <= epsilon || maxB-minA <= epsilon {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func projectTriangle(t [3]Vector2, axisU, axisV float32) (min, max float32) {
+	min = t[0].U*axisU + t[0].V*axisV
+	max = min
+
+	for _, uv := range t[1:] {
+		p := uv.U*axisU + uv.V*axisV
+
+		min = float32(math.Min(float64(min), float64(p)))
+		max = float32(math.Max(float64(max), float64(p)))
+	}
+
+	return min, max
+}
+
+// WindingReport describes how the winding of a Geometry's triangles compares with its vertex normals.
+type WindingReport struct {
+	Agreeing    int // triangles whose winding faces the same way as their vertex normals.
+	Disagreeing int // triangles whose winding faces away from their vertex normals.
+	Ambiguous   int // degenerate triangles, or triangles whose normals are perpendicular to (or cancel out over) the face.
+
+	DisagreeingFaces []int // indices into Geometry.Faces of the disagreeing triangles.
+	AmbiguousFaces   []int // indices into Geometry.Faces of the ambiguous triangles.
+}
+
+// AnalyzeWinding compares the winding of every triangle with the average of its three vertex normals, in a single
+// pass.  Nothing is changed; the report is meant to help decide whether the source data needs fixing.  Only the
+// disagreeing and ambiguous triangles are recorded individually, so a well-formed mesh allocates nothing per triangle.
+func AnalyzeWinding(geo Geometry) WindingReport {
+	const epsilon = 1e-6
+
+	report := WindingReport{}
+
+	for i, f := range geo.Faces {
+		n := faceNormal(geo, f)
+
+		a := geo.Vertices[f.TriangleIndices[0]].Normal
+		b := geo.Vertices[f.TriangleIndices[1]].Normal
+		c := geo.Vertices[f.TriangleIndices[2]].Normal
+
+		// the length of the face normal is twice the triangle's area, so dividing by it makes the threshold
+		// independent of the triangle's size.
+		length := math.Sqrt(float64(dot(n, n)))
+		agreement := float64(dot(n, a)+dot(n, b)+dot(n, c)) / 3
+
+		switch {
+		case length < epsilon || math.Abs(agreement/length) < epsilon:
+			report.Ambiguous++
+			report.AmbiguousFaces = append(report.AmbiguousFaces, i)
+		case agreement > 0:
+			report.Agreeing++
+		default:
+			report.Disagreeing++
+			report.DisagreeingFaces = append(report.DisagreeingFaces, i)
+		}
+	}
+
+	return report
+}
+
+// FaceColorReport describes whether a Geometry's vertex colors are constant across each triangle, and estimates what
+// it costs to keep those per-face colors in glTF with vertex colors and with a texture atlas.  glTF has no flat
+// interpolation, so either way a vertex shared by triangles of different colors has to be split.
+type FaceColorReport struct {
+	PerFaceConstant bool  // true if the three corners of every triangle have the same color.
+	VaryingFaces    []int // indices into Geometry.Faces of the triangles whose corners differ.
+	DistinctColors  int   // the number of different face colors, which is also the number of atlas pixels needed.
+
+	Vertices         int // the vertex count as it is now.
+	WeldedVertices   int // the vertex count if colors were ignored and identical vertices were shared.
+	UnweldedVertices int // the vertex count needed to give every face its own color; shared only where colors match.
+
+	VertexColorBytes int // the estimated buffer size with POSITION, NORMAL and COLOR_0 for UnweldedVertices.
+	AtlasBytes       int // the estimated buffer size with POSITION, NORMAL and TEXCOORD_0 for UnweldedVertices, plus raw atlas pixels.
+}
+
+// AnalyzeFaceColors reports whether the Geometry's colors are flat per face, and how much each export strategy would
+// cost, so the caller can choose between vertex colors and a texture atlas.  Each triangle's color is taken from its
+// first (provoking) vertex.  Nothing is changed.
+func AnalyzeFaceColors(geo Geometry) FaceColorReport {
+	type vertexKey struct {
+		Position Vector3
+		Normal   Vector3
+	}
+
+	type coloredKey struct {
+		vertexKey
+		Color Vector4
+	}
+
+	report := FaceColorReport{PerFaceConstant: true, Vertices: len(geo.Vertices)}
+
+	colors := make(map[Vector4]bool)
+	welded := make(map[vertexKey]bool)
+	unwelded := make(map[coloredKey]bool)
+
+	for i, f := range geo.Faces {
+		color := geo.Vertices[f.TriangleIndices[0]].Color
+
+		if geo.Vertices[f.TriangleIndices[1]].Color != color || geo.Vertices[f.TriangleIndices[2]].Color != color {
+			report.PerFaceConstant = false
+			report.VaryingFaces = append(report.VaryingFaces, i)
+		}
+
+		colors[color] = true
+
+		for _, index := range f.TriangleIndices {
+			v := geo.Vertices[index]
+			key := vertexKey{Position: v.Position, Normal: v.Normal}
+
+			welded[key] = true
+			unwelded[coloredKey{vertexKey: key, Color: color}] = true
+		}
+	}
+
+	report.DistinctColors = len(colors)
+	report.WeldedVertices = len(welded)
+	report.UnweldedVertices = len(unwelded)
+
+	// float positions and normals are 12 bytes each, float RGBA colors are 16, float UVs are 8, and RGBA pixels are 4.
+	report.VertexColorBytes = report.UnweldedVertices * (12 + 12 + 16)
+	report.AtlasBytes = report.UnweldedVertices*(12+12+8) + report.DistinctColors*4
+
+	return report
+}
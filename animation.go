@@ -0,0 +1,235 @@
+package gltf
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// An animation is a set of channels, each of which drives one property of one node from a sampler.  A sampler pairs an
+// accessor of keyframe times, in seconds, with an accessor of the values at those times, and says how to interpolate
+// between them.
+
+// The node properties an animation channel can target.
+const (
+	PathTranslation = "translation"
+	PathRotation    = "rotation"
+	PathScale       = "scale"
+	PathWeights     = "weights"
+)
+
+// The ways an animation sampler can interpolate between keyframes.
+const (
+	InterpolationLinear      = "LINEAR"
+	InterpolationStep        = "STEP"
+	InterpolationCubicSpline = "CUBICSPLINE"
+)
+
+// Animation is a glTF animation.  Channels refer to Samplers by their index in this Animation, not the document.
+type Animation struct {
+	Channels   []AnimationChannel `json:"channels"`
+	Extensions interface{}        `json:"extensions,omitempty"`
+	Extras     interface{}        `json:"extras,omitempty"`
+	Name       string             `json:"name,omitempty"`
+	Samplers   []AnimationSampler `json:"samplers"`
+}
+
+// AnimationChannel drives the target property with the animation's sampler at index Sampler.
+type AnimationChannel struct {
+	Sampler int                    `json:"sampler" validator:"gte=0"`
+	Target  AnimationChannelTarget `json:"target"`
+}
+
+// AnimationChannelTarget is the node and property an AnimationChannel drives.  Path is one of the Path constants.
+type AnimationChannelTarget struct {
+	Node *int   `json:"node,omitempty"`
+	Path string `json:"path"`
+}
+
+// AnimationSampler pairs the accessor of keyframe times at Input with the accessor of values at Output.  An empty
+// Interpolation is the spec default, InterpolationLinear.
+type AnimationSampler struct {
+	Input         int    `json:"input" validator:"gte=0"`
+	Interpolation string `json:"interpolation,omitempty"`
+	Output        int    `json:"output" validator:"gte=0"`
+}
+
+// Keyframe is the value of an animated property at a time, in seconds.  Value has 3 components for translation and
+// scale, 4 for a rotation quaternion (x, y, z, w), and one per morph target for weights.  InTangent and OutTangent are
+// only for InterpolationCubicSpline, and are the same size as Value.
+type Keyframe struct {
+	Time       float64
+	Value      []float64
+	InTangent  []float64
+	OutTangent []float64
+}
+
+// AnimationTrack is the keyframes of one property of one node, in time order.
+type AnimationTrack struct {
+	Node          int
+	Path          string
+	Interpolation string // one of the Interpolation constants; empty means InterpolationLinear.
+	Keyframes     []Keyframe
+}
+
+// AddAnimation checks the supplied tracks, packs their keyframe times and values into accessors, and adds an animation
+// with a channel and sampler for each track.  Tracks with exactly the same times share one time accessor.  Linear and
+// step rotations are normalized, as the spec requires.  The new animation's index is returned.
+func (gltfDoc *GlTF) AddAnimation(name string, tracks []AnimationTrack) (animationIndex int, err error) {
+	if len(tracks) == 0 {
+		return -1, errors.New("animation has no tracks")
+	}
+
+	for t, track := range tracks {
+		if err := gltfDoc.checkTrack(track); err != nil {
+			return -1, fmt.Errorf("track %d: %w", t, err)
+		}
+	}
+
+	animation := Animation{Name: name}
+	outBuf := gltfDoc.beginAppend()
+
+	// the time accessors made so far, and the times in each.
+	timeAccessors := []int{}
+	timeLists := [][]float32{}
+
+	for _, track := range tracks {
+		times := make([]float32, len(track.Keyframes))
+		values := []float32{}
+
+		for k, keyframe := range track.Keyframes {
+			times[k] = float32(keyframe.Time)
+
+			// a cubic spline keyframe is stored as its in tangent, value and out tangent, one after the other.
+			if track.Interpolation == InterpolationCubicSpline {
+				values = appendFloat32s(values, keyframe.InTangent)
+				values = appendFloat32s(values, keyframe.Value)
+				values = appendFloat32s(values, keyframe.OutTangent)
+			} else if track.Path == PathRotation {
+				// the spec wants unit quaternions, and rounded ones are close enough to fix rather than reject.
+				length := quaternionLength(keyframe.Value)
+				v := keyframe.Value
+
+				values = appendFloat32s(values, []float64{v[0] / length, v[1] / length, v[2] / length, v[3] / length})
+			} else {
+				values = appendFloat32s(values, keyframe.Value)
+			}
+		}
+
+		input := -1
+
+		for i, existing := range timeLists {
+			if reflect.DeepEqual(existing, times) {
+				input = timeAccessors[i]
+				break
+			}
+		}
+
+		if input < 0 {
+			input = getAccessorIndexFromFloats(outBuf, times, Scalar, &gltfDoc.BufferViews, &gltfDoc.Accessors)
+
+			// the spec requires the bounds of the times, so viewers know how long the animation is.
+			gltfDoc.Accessors[input].Min = []float32{times[0]}
+			gltfDoc.Accessors[input].Max = []float32{times[len(times)-1]}
+
+			timeAccessors = append(timeAccessors, input)
+			timeLists = append(timeLists, times)
+		}
+
+		// weights are scalars, one per morph target per keyframe; the rest are vectors.
+		outputType := Scalar
+
+		switch track.Path {
+		case PathTranslation, PathScale:
+			outputType = Vec3
+		case PathRotation:
+			outputType = Vec4
+		}
+
+		output := getAccessorIndexFromFloats(outBuf, values, outputType, &gltfDoc.BufferViews, &gltfDoc.Accessors)
+
+		node := track.Node
+
+		animation.Samplers = append(animation.Samplers, AnimationSampler{Input: input, Interpolation: track.Interpolation, Output: output})
+		animation.Channels = append(animation.Channels, AnimationChannel{
+			Sampler: len(animation.Samplers) - 1,
+			Target:  AnimationChannelTarget{Node: &node, Path: track.Path},
+		})
+	}
+
+	gltfDoc.endAppend(outBuf)
+
+	gltfDoc.Animations = append(gltfDoc.Animations, animation)
+
+	return len(gltfDoc.Animations) - 1, nil
+}
+
+// makes sure a track targets a node that exists with a property and interpolation glTF knows, and that its keyframes
+// are in order and all the right size.
+func (gltfDoc GlTF) checkTrack(track AnimationTrack) error {
+	if track.Node < 0 || track.Node >= len(gltfDoc.Nodes) {
+		return fmt.Errorf("node %d does not exist", track.Node)
+	}
+
+	if len(track.Keyframes) == 0 {
+		return errors.New("track has no keyframes")
+	}
+
+	components := len(track.Keyframes[0].Value)
+
+	switch track.Path {
+	case PathTranslation, PathScale:
+		components = 3
+	case PathRotation:
+		components = 4
+	case PathWeights:
+		if components == 0 {
+			return errors.New("weights keyframes need a value for each morph target")
+		}
+	default:
+		return fmt.Errorf("path %q is not %q, %q, %q or %q", track.Path, PathTranslation, PathRotation, PathScale, PathWeights)
+	}
+
+	cubic := false
+
+	switch track.Interpolation {
+	case "", InterpolationLinear, InterpolationStep:
+	case InterpolationCubicSpline:
+		cubic = true
+
+		if len(track.Keyframes) < 2 {
+			return errors.New("a cubic spline needs at least 2 keyframes")
+		}
+	default:
+		return fmt.Errorf("interpolation %q is not %q, %q or %q", track.Interpolation, InterpolationLinear, InterpolationStep, InterpolationCubicSpline)
+	}
+
+	for k, keyframe := range track.Keyframes {
+		switch {
+		case math.IsNaN(keyframe.Time) || math.IsInf(keyframe.Time, 0) || keyframe.Time < 0:
+			return fmt.Errorf("keyframe %d is at %g seconds; times have to be finite and not negative", k, keyframe.Time)
+		case k > 0 && keyframe.Time <= track.Keyframes[k-1].Time:
+			return fmt.Errorf("keyframe %d is at %g seconds, which isn't after the keyframe before it", k, keyframe.Time)
+		case len(keyframe.Value) != components:
+			return fmt.Errorf("keyframe %d has %d components; %s needs %d", k, len(keyframe.Value), track.Path, components)
+		case cubic && (len(keyframe.InTangent) != components || len(keyframe.OutTangent) != components):
+			return fmt.Errorf("keyframe %d needs in and out tangents of %d components for a cubic spline", k, components)
+		}
+
+		if track.Path == PathRotation && quaternionLength(keyframe.Value) == 0 {
+			return fmt.Errorf("keyframe %d has a rotation of length 0, which isn't a rotation at all", k)
+		}
+	}
+
+	return nil
+}
+
+// appends the supplied values to floats, as float32s.
+func appendFloat32s(floats []float32, values []float64) []float32 {
+	for _, v := range values {
+		floats = append(floats, float32(v))
+	}
+
+	return floats
+}
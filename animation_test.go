@@ -0,0 +1,85 @@
+package gltf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAddAnimationTranslation(t *testing.T) {
+	gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{testTriangle(Material{Opacity: 1})}}, PipelineOptions{Options: Options{VertexColors: true}})
+	track := AnimationTrack{
+		Node: 0,
+		Path: PathTranslation,
+		Keyframes: []Keyframe{
+			{Time: 0, Value: []float64{0, 0, 0}},
+			{Time: 1, Value: []float64{1, 2, 3}},
+			{Time: 2.5, Value: []float64{0, 4, 0}},
+		},
+	}
+
+	index, err := gltfDoc.AddAnimation("walk", []AnimationTrack{track})
+
+	if err != nil {
+		t.Fatalf("AddAnimation: %v", err)
+	}
+
+	animation := gltfDoc.Animations[index]
+
+	if len(animation.Channels) != 1 || len(animation.Samplers) != 1 {
+		t.Fatalf("got %d channels and %d samplers, want 1 of each", len(animation.Channels), len(animation.Samplers))
+	}
+
+	if target := animation.Channels[0].Target; target.Node == nil || *target.Node != 0 || target.Path != PathTranslation {
+		t.Errorf("channel targets %+v, want node 0's translation", target)
+	}
+
+	sampler := animation.Samplers[animation.Channels[0].Sampler]
+	times := gltfDoc.Accessors[sampler.Input]
+
+	if times.Count != 3 || times.Type != Scalar {
+		t.Errorf("time accessor is %d of %s, want 3 of %s", times.Count, times.Type, Scalar)
+	}
+
+	if !reflect.DeepEqual(times.Min, []float32{0}) || !reflect.DeepEqual(times.Max, []float32{2.5}) {
+		t.Errorf("time accessor runs from %v to %v, want [0] to [2.5]", times.Min, times.Max)
+	}
+
+	values, ok := gltfDoc.readVector3Floats(gltfDoc.Accessors[sampler.Output])
+
+	if !ok {
+		t.Fatal("the output accessor couldn't be read as VEC3 floats")
+	}
+
+	if want := []Vector3{{}, {X: 1, Y: 2, Z: 3}, {Y: 4}}; !reflect.DeepEqual(values, want) {
+		t.Errorf("output values are %v, want %v", values, want)
+	}
+
+	if err := gltfDoc.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func TestAddAnimationRejectsBadTracks(t *testing.T) {
+	gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{testTriangle(Material{Opacity: 1})}}, PipelineOptions{Options: Options{VertexColors: true}})
+	keyframes := []Keyframe{{Time: 0, Value: []float64{0, 0, 0}}, {Time: 1, Value: []float64{1, 0, 0}}}
+
+	tests := []struct {
+		name  string
+		track AnimationTrack
+	}{
+		{"missing node", AnimationTrack{Node: 5, Path: PathTranslation, Keyframes: keyframes}},
+		{"unknown path", AnimationTrack{Node: 0, Path: "colour", Keyframes: keyframes}},
+		{"times out of order", AnimationTrack{Node: 0, Path: PathTranslation, Keyframes: []Keyframe{keyframes[1], keyframes[0]}}},
+		{"wrong value size", AnimationTrack{Node: 0, Path: PathRotation, Keyframes: keyframes}},
+	}
+
+	for _, test := range tests {
+		if _, err := gltfDoc.AddAnimation(test.name, []AnimationTrack{test.track}); err == nil {
+			t.Errorf("%s was accepted", test.name)
+		}
+	}
+
+	if len(gltfDoc.Animations) != 0 {
+		t.Errorf("%d animations were added by rejected tracks", len(gltfDoc.Animations))
+	}
+}
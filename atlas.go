@@ -0,0 +1,261 @@
+package gltf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"sort"
+
+	// registers the JPEG decoder with image.Decode; PNG is registered by image/png above.
+	_ "image/jpeg"
+)
+
+// atlasGutter is the number of pixels each tile's edge is extended by on every side, so that filtering near the edge of
+// a tile doesn't pick up its neighbor's pixels.
+const atlasGutter = 1
+
+// one rectangle of the packed atlas.  x and y are the top left corner of the image itself, inside the gutter.
+type atlasTile struct {
+	img  image.Image
+	x, y int
+}
+
+// optimizeModelTextured is the texture atlas strategy for Models whose materials reference texture files on disk.
+// Each distinct Material.TexturePath is loaded, decoded (PNG and JPEG are supported) and packed into the atlas once,
+// and every vertex's UV is remapped from the 0-1 range of its own texture into that texture's rectangle in the atlas.
+// Materials without a TexturePath get a single pixel of their diffuse color and opacity, as with optimizeModel, and
+// Geometry is merged per unique material in the same way.  With textureTransforms set, the remapping is left to each
+// material's AtlasTransform instead, and keepGeometry keeps every Geometry apart, both as optimizeModel describes.
+//
+// UVs are clamped to 0-1, because a packed texture can't repeat; models that rely on wrapping need separate textures.
+// An error naming the mesh and path is returned if any texture can't be loaded.
+func optimizeModelTextured(meshes Model, textureTransforms bool, keepGeometry bool) (Model, bytes.Buffer, error) {
+	imageData := bytes.Buffer{}
+	groups := groupByMaterial(meshes, keepGeometry)
+	tiles := []*atlasTile{}
+	tilesByPath := make(map[string]*atlasTile)
+	groupTiles := make([]*atlasTile, len(groups))
+
+	for i, group := range groups {
+		path := group.material.TexturePath
+
+		if path == "" {
+			groupTiles[i] = &atlasTile{img: image.NewUniform(solidColor(group.material))}
+			tiles = append(tiles, groupTiles[i])
+
+			continue
+		}
+
+		if tile, ok := tilesByPath[path]; ok {
+			groupTiles[i] = tile
+			continue
+		}
+
+		img, err := loadTextureFile(path)
+
+		if err != nil {
+			return meshes, imageData, fmt.Errorf("material of mesh %d: texture %q: %w", group.firstMesh, path, err)
+		}
+
+		groupTiles[i] = &atlasTile{img: img}
+		tilesByPath[path] = groupTiles[i]
+		tiles = append(tiles, groupTiles[i])
+	}
+
+	width, height := packAtlasTiles(tiles)
+	atlas := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for _, tile := range tiles {
+		drawAtlasTile(atlas, tile)
+	}
+
+	merged := Model{Meshes: make([]Geometry, 0, len(groups)), Scenes: meshes.Scenes, DefaultScene: meshes.DefaultScene}
+
+	for i, group := range groups {
+		tile := groupTiles[i]
+		w, h := tileSize(tile)
+		textured := group.material.TexturePath != ""
+
+		geo := mergeGeometry(group, func(vertex Vertex) Vertex {
+			u, v := float32(0.5), float32(0.5)
+
+			if textured {
+				u, v = clamp01(vertex.UV.U), clamp01(vertex.UV.V)
+			}
+
+			// the transform does the remapping, so the UVs only need to stay inside the tile.
+			if textureTransforms {
+				vertex.UV = Vector2{U: u, V: v}
+				return vertex
+			}
+
+			vertex.UV = Vector2{
+				U: (float32(tile.x) + u*float32(w)) / float32(width),
+				V: (float32(tile.y) + v*float32(h)) / float32(height),
+			}
+
+			return vertex
+		})
+
+		if textureTransforms {
+			geo.Material.AtlasTransform = atlasTileTransform(tile.x, tile.y, w, h, width, height)
+		}
+
+		merged.Meshes = append(merged.Meshes, geo)
+	}
+
+	if err := png.Encode(&imageData, atlas); err != nil {
+		return meshes, imageData, err
+	}
+
+	return merged, imageData, nil
+}
+
+// reports whether any of the Model's materials reference a texture file.
+func hasTexturePaths(meshes Model) bool {
+	for _, mesh := range meshes.Meshes {
+		if mesh.Material.TexturePath != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func loadTextureFile(path string) (image.Image, error) {
+	f, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+
+	return img, err
+}
+
+// returns the atlas pixel color for a material without a texture.
+func solidColor(material Material) color.RGBA {
+	//* color correction: scale all colors from 0-1 to 0.04-0.85 because gltf uses Physically Based Rendering.
+	//* https://seblagarde.wordpress.com/2011/08/17/feeding-a-physical-based-lighting-mode/
+	// TODO: scale all colors by the same amount; just enough to bring the brightest and darkest colors into range.
+	return color.RGBA{
+		R: uint8(mapRange(float64(material.DiffuseColor[0]), 0.0, 1.0, 0.04, 0.85) * 255),
+		G: uint8(mapRange(float64(material.DiffuseColor[1]), 0.0, 1.0, 0.04, 0.85) * 255),
+		B: uint8(mapRange(float64(material.DiffuseColor[2]), 0.0, 1.0, 0.04, 0.85) * 255),
+		// the opacity goes in the glTF material's base color factor instead, or it would be applied twice.
+		A: 255,
+	}
+}
+
+// returns the size of a tile's image, not counting its gutter.  Solid colors are a single pixel.
+func tileSize(tile *atlasTile) (w, h int) {
+	if _, ok := tile.img.(*image.Uniform); ok {
+		return 1, 1
+	}
+
+	return tile.img.Bounds().Dx(), tile.img.Bounds().Dy()
+}
+
+// places the tiles in rows, tallest first, and returns the atlas size needed to hold them.  Both dimensions are
+// powers of two so that viewers can mipmap the atlas.
+func packAtlasTiles(tiles []*atlasTile) (width, height int) {
+	sorted := append([]*atlasTile{}, tiles...)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		_, hi := tileSize(sorted[i])
+		_, hj := tileSize(sorted[j])
+
+		return hi > hj
+	})
+
+	area, widest := 0, 0
+
+	for _, tile := range sorted {
+		w, h := tileSize(tile)
+		area += (w + 2*atlasGutter) * (h + 2*atlasGutter)
+
+		if w+2*atlasGutter > widest {
+			widest = w + 2*atlasGutter
+		}
+	}
+
+	width = nextPowerOfTwo(widest)
+
+	for width*width < area {
+		width *= 2
+	}
+
+	x, y, rowHeight := 0, 0, 0
+
+	for _, tile := range sorted {
+		w, h := tileSize(tile)
+
+		if x+w+2*atlasGutter > width {
+			x, y, rowHeight = 0, y+rowHeight, 0
+		}
+
+		tile.x, tile.y = x+atlasGutter, y+atlasGutter
+		x += w + 2*atlasGutter
+
+		if h+2*atlasGutter > rowHeight {
+			rowHeight = h + 2*atlasGutter
+		}
+	}
+
+	return width, nextPowerOfTwo(y + rowHeight)
+}
+
+// draws a tile's image into the atlas, then fills its gutter by repeating the image's edge pixels.
+func drawAtlasTile(atlas *image.RGBA, tile *atlasTile) {
+	w, h := tileSize(tile)
+	src := tile.img.Bounds().Min
+
+	for y := -atlasGutter; y < h+atlasGutter; y++ {
+		for x := -atlasGutter; x < w+atlasGutter; x++ {
+			sx := src.X + clampInt(x, 0, w-1)
+			sy := src.Y + clampInt(y, 0, h-1)
+
+			atlas.Set(tile.x+x, tile.y+y, tile.img.At(sx, sy))
+		}
+	}
+}
+
+func nextPowerOfTwo(n int) int {
+	p := 1
+
+	for p < n {
+		p *= 2
+	}
+
+	return p
+}
+
+func clampInt(n, min, max int) int {
+	if n < min {
+		return min
+	}
+
+	if n > max {
+		return max
+	}
+
+	return n
+}
+
+func clamp01(f float32) float32 {
+	if f < 0 {
+		return 0
+	}
+
+	if f > 1 {
+		return 1
+	}
+
+	return f
+}
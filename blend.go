@@ -0,0 +1,98 @@
+package gltf
+
+import "sort"
+
+// SortForBlending reorders a Model so that simple viewers, which draw triangles in the order they're stored and don't
+// sort transparent geometry themselves, blend it correctly from one viewpoint.  Geometry whose glTF material won't be
+// BLEND, as Material.AlphaMode and Opacity decide, comes first, unchanged.  Blended Geometry follows, ordered
+// back-to-front along viewDirection, and the triangles within each are sorted back-to-front too.  Each blended
+// Geometry's extras note that its order is view dependent.  The Geometry of a hierarchical Model keep their order, and
+// only their triangles are sorted.
+//
+// viewDirection is the direction the camera looks in; the zero vector means -Z, the default glTF camera direction.
+// Only triangle order changes, so indices remain valid.  Use the result with ToGltfDoc directly: optimizeModel merges
+// Geometry by material, which loses the order between transparent Geometry with different materials.
+func SortForBlending(model Model, viewDirection Vector3) Model {
+	if viewDirection == (Vector3{}) {
+		viewDirection = Vector3{X: 0, Y: 0, Z: -1}
+	}
+
+	viewDirection = normalize(viewDirection)
+
+	opaque := []Geometry{}
+	transparent := []Geometry{}
+	sorted := Model{Meshes: make([]Geometry, 0, len(model.Meshes)), Scenes: model.Scenes, DefaultScene: model.DefaultScene}
+
+	// Children and scene roots are indices into Meshes, so a hierarchy's Geometry can't be reordered, only its
+	// triangles can.  Each Geometry is its own node there, so viewers order them by node anyway.
+	hierarchical := model.hasHierarchy()
+
+	for _, geo := range model.Meshes {
+		// only blended materials depend on draw order; masked ones are drawn as opaque, cut out or not.
+		if mode, _ := geo.Material.alpha(); mode != string(AlphaBlend) {
+			opaque = append(opaque, geo)
+			sorted.Meshes = append(sorted.Meshes, geo)
+
+			continue
+		}
+
+		geo.Faces = append([]Triangle{}, geo.Faces...)
+
+		// farther along the view direction is farther from the camera, so it's drawn first.
+		depths := make([]float32, len(geo.Faces))
+
+		for i, f := range geo.Faces {
+			depths[i] = dot(triangleCentroid(geo, f), viewDirection)
+		}
+
+		order := make([]int, len(geo.Faces))
+
+		for i := range order {
+			order[i] = i
+		}
+
+		sort.SliceStable(order, func(i, j int) bool { return depths[order[i]] > depths[order[j]] })
+
+		faces := make([]Triangle, len(geo.Faces))
+
+		for i, o := range order {
+			faces[i] = geo.Faces[o]
+		}
+
+		geo.Faces = faces
+		geo.Extras = map[string]interface{}{
+			"viewDependentSort": true,
+			"viewDirection":     []float32{viewDirection.X, viewDirection.Y, viewDirection.Z},
+		}
+
+		transparent = append(transparent, geo)
+		sorted.Meshes = append(sorted.Meshes, geo)
+	}
+
+	if hierarchical {
+		return sorted
+	}
+
+	sort.SliceStable(transparent, func(i, j int) bool {
+		return dot(geometryCenter(transparent[i]), viewDirection) > dot(geometryCenter(transparent[j]), viewDirection)
+	})
+
+	sorted.Meshes = append(opaque, transparent...)
+
+	return sorted
+}
+
+func triangleCentroid(geo Geometry, f Triangle) Vector3 {
+	a := geo.Vertices[f.TriangleIndices[0]].Position
+	b := geo.Vertices[f.TriangleIndices[1]].Position
+	c := geo.Vertices[f.TriangleIndices[2]].Position
+
+	return Vector3{X: (a.X + b.X + c.X) / 3, Y: (a.Y + b.Y + c.Y) / 3, Z: (a.Z + b.Z + c.Z) / 3}
+}
+
+// returns the center of the geometry's bounding box.
+func geometryCenter(geo Geometry) Vector3 {
+	min, max := geometryBounds(geo)
+
+	return lerp3(min, max, 0.5)
+}
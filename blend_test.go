@@ -0,0 +1,70 @@
+package gltf
+
+import "testing"
+
+// returns testTriangle moved along Z, so that it's at depth z along the default view direction.
+func triangleAt(z float32, material Material) Geometry {
+	geo := testTriangle(material)
+
+	for i := range geo.Vertices {
+		geo.Vertices[i].Position.Z = z
+	}
+
+	return geo
+}
+
+func TestSortForBlending(t *testing.T) {
+	blended := Material{Opacity: 1, AlphaMode: AlphaBlend}
+	translucent := Material{Opacity: 0.5}
+	// neither of these depends on draw order, whatever their opacity.
+	masked := Material{Opacity: 0.5, AlphaMode: AlphaMask}
+	opaque := Material{Opacity: 0.5, AlphaMode: AlphaOpaque}
+
+	model := Model{Meshes: []Geometry{
+		triangleAt(1, blended),
+		triangleAt(-5, translucent),
+		triangleAt(2, masked),
+		triangleAt(3, opaque),
+	}}
+
+	sorted := SortForBlending(model, Vector3{})
+	want := []struct {
+		z      float32
+		sorted bool
+	}{
+		// the unblended ones first, in their own order, then the blended ones farthest first: -Z is away from the camera.
+		{2, false},
+		{3, false},
+		{-5, true},
+		{1, true},
+	}
+
+	if len(sorted.Meshes) != len(want) {
+		t.Fatalf("got %d Geometry, want %d", len(sorted.Meshes), len(want))
+	}
+
+	for i, w := range want {
+		geo := sorted.Meshes[i]
+		_, marked := geo.Extras.(map[string]interface{})
+
+		if geo.Vertices[0].Position.Z != w.z || marked != w.sorted {
+			t.Errorf("Meshes[%d] is at z %g, marked %t; want z %g, marked %t", i, geo.Vertices[0].Position.Z, marked, w.z, w.sorted)
+		}
+	}
+}
+
+func TestSortForBlendingTriangles(t *testing.T) {
+	near, far := triangleAt(1, Material{}), triangleAt(-1, Material{})
+	geo := Geometry{Vertices: append(near.Vertices, far.Vertices...), Material: Material{Opacity: 0.5}}
+	geo.Faces = []Triangle{{TriangleIndices: [3]int32{0, 1, 2}}, {TriangleIndices: [3]int32{3, 4, 5}}}
+
+	sorted := SortForBlending(Model{Meshes: []Geometry{geo}}, Vector3{})
+
+	if first := sorted.Meshes[0].Faces[0].TriangleIndices; first != [3]int32{3, 4, 5} {
+		t.Errorf("the first triangle is %v, want the far one, [3 4 5]", first)
+	}
+
+	if geo.Faces[0].TriangleIndices != [3]int32{0, 1, 2} {
+		t.Error("sorting changed the Model passed in")
+	}
+}
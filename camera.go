@@ -0,0 +1,105 @@
+package gltf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Camera is a glTF camera.  Type is "perspective" or "orthographic", and exactly the matching one of Perspective and
+// Orthographic is set.  A camera looks down its node's -Z axis, with +Y up.
+type Camera struct {
+	Extensions   interface{}         `json:"extensions,omitempty"`
+	Extras       interface{}         `json:"extras,omitempty"`
+	Name         string              `json:"name,omitempty"`
+	Orthographic *CameraOrthographic `json:"orthographic,omitempty"`
+	Perspective  *CameraPerspective  `json:"perspective,omitempty"`
+	Type         string              `json:"type"`
+}
+
+// CameraPerspective holds a perspective projection.  YFov is the vertical field of view in radians.  An AspectRatio of
+// 0 leaves it up to the viewer, which uses its viewport's, and a ZFar of 0 gives an infinite projection.
+type CameraPerspective struct {
+	AspectRatio float64 `json:"aspectRatio,omitempty" validator:"gte=0"`
+	YFov        float64 `json:"yfov" validator:"gte=0"`
+	ZFar        float64 `json:"zfar,omitempty" validator:"gte=0"`
+	ZNear       float64 `json:"znear" validator:"gte=0"`
+}
+
+// CameraOrthographic holds an orthographic projection.  XMag and YMag are half the width and height of the view.
+type CameraOrthographic struct {
+	XMag  float64 `json:"xmag"`
+	YMag  float64 `json:"ymag"`
+	ZFar  float64 `json:"zfar" validator:"gte=0"`
+	ZNear float64 `json:"znear" validator:"gte=0"`
+}
+
+// PerspectiveCamera returns a perspective Camera.  See CameraPerspective for what the values mean.
+func PerspectiveCamera(yfov, aspectRatio, znear, zfar float64) Camera {
+	return Camera{
+		Type:        "perspective",
+		Perspective: &CameraPerspective{AspectRatio: aspectRatio, YFov: yfov, ZFar: zfar, ZNear: znear},
+	}
+}
+
+// OrthographicCamera returns an orthographic Camera.  See CameraOrthographic for what the values mean.
+func OrthographicCamera(xmag, ymag, znear, zfar float64) Camera {
+	return Camera{
+		Type:         "orthographic",
+		Orthographic: &CameraOrthographic{XMag: xmag, YMag: ymag, ZFar: zfar, ZNear: znear},
+	}
+}
+
+// AddCamera checks the supplied Camera, adds it to the document, and attaches it to the node at nodeIndex, replacing
+// any camera the node already had.  The new camera's index is returned.
+func (gltfDoc *GlTF) AddCamera(camera Camera, nodeIndex int) (cameraIndex int, err error) {
+	if nodeIndex < 0 || nodeIndex >= len(gltfDoc.Nodes) {
+		return -1, fmt.Errorf("node %d does not exist", nodeIndex)
+	}
+
+	if err := camera.check(); err != nil {
+		return -1, err
+	}
+
+	gltfDoc.Cameras = append(gltfDoc.Cameras, camera)
+	gltfDoc.Nodes[nodeIndex].Camera = len(gltfDoc.Cameras) - 1
+
+	return len(gltfDoc.Cameras) - 1, nil
+}
+
+// makes sure the camera's type agrees with the projection it has, and that the projection is one a viewer could use.
+func (camera Camera) check() error {
+	switch camera.Type {
+	case "perspective":
+		p := camera.Perspective
+
+		switch {
+		case p == nil || camera.Orthographic != nil:
+			return errors.New("a perspective camera needs a perspective projection and no orthographic one")
+		case p.YFov <= 0:
+			return fmt.Errorf("yfov is %g; it has to be more than 0", p.YFov)
+		case p.ZNear <= 0:
+			return fmt.Errorf("znear is %g; it has to be more than 0", p.ZNear)
+		case p.ZFar != 0 && p.ZFar <= p.ZNear:
+			return fmt.Errorf("zfar is %g; it has to be more than znear, %g, or 0 for an infinite projection", p.ZFar, p.ZNear)
+		case p.AspectRatio < 0:
+			return fmt.Errorf("aspectRatio is %g; it has to be more than 0, or 0 to use the viewport's", p.AspectRatio)
+		}
+	case "orthographic":
+		o := camera.Orthographic
+
+		switch {
+		case o == nil || camera.Perspective != nil:
+			return errors.New("an orthographic camera needs an orthographic projection and no perspective one")
+		case o.XMag == 0 || o.YMag == 0:
+			return fmt.Errorf("xmag and ymag are %g and %g; neither can be 0", o.XMag, o.YMag)
+		case o.ZNear < 0:
+			return fmt.Errorf("znear is %g; it can't be negative", o.ZNear)
+		case o.ZFar <= o.ZNear:
+			return fmt.Errorf("zfar is %g; it has to be more than znear, %g", o.ZFar, o.ZNear)
+		}
+	default:
+		return fmt.Errorf("camera type %q is neither \"perspective\" nor \"orthographic\"", camera.Type)
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package gltf
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCameraMarshalling(t *testing.T) {
+	tests := []struct {
+		name   string
+		camera Camera
+		want   string
+	}{
+		{"perspective", PerspectiveCamera(0.8, 1.5, 0.1, 100), `{"perspective":{"aspectRatio":1.5,"yfov":0.8,"zfar":100,"znear":0.1},"type":"perspective"}`},
+		// an infinite projection that uses the viewport's aspect ratio leaves both out.
+		{"infinite perspective", PerspectiveCamera(0.8, 0, 0.1, 0), `{"perspective":{"yfov":0.8,"znear":0.1},"type":"perspective"}`},
+		{"orthographic", OrthographicCamera(2, 1, 0, 50), `{"orthographic":{"xmag":2,"ymag":1,"zfar":50,"znear":0},"type":"orthographic"}`},
+	}
+
+	for _, test := range tests {
+		gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{testTriangle(Material{Opacity: 1})}}, PipelineOptions{Options: Options{VertexColors: true}})
+		cameraIndex, err := gltfDoc.AddCamera(test.camera, 0)
+
+		if err != nil {
+			t.Errorf("%s: AddCamera: %v", test.name, err)
+			continue
+		}
+
+		data, err := json.Marshal(gltfDoc.Cameras[cameraIndex])
+
+		if err != nil {
+			t.Errorf("%s: json.Marshal: %v", test.name, err)
+			continue
+		}
+
+		if string(data) != test.want {
+			t.Errorf("%s: camera marshals to %s, want %s", test.name, data, test.want)
+		}
+
+		node, err := json.Marshal(gltfDoc.Nodes[0])
+
+		if err != nil {
+			t.Errorf("%s: json.Marshal: %v", test.name, err)
+			continue
+		}
+
+		var decoded struct {
+			Camera *int `json:"camera"`
+		}
+
+		if err := json.Unmarshal(node, &decoded); err != nil || decoded.Camera == nil || *decoded.Camera != cameraIndex {
+			t.Errorf("%s: node marshals to %s, want it referencing camera %d", test.name, node, cameraIndex)
+		}
+	}
+}
+
+func TestCameraCheck(t *testing.T) {
+	for _, camera := range []Camera{
+		PerspectiveCamera(0, 1, 0.1, 100),
+		PerspectiveCamera(0.8, 1, 0.1, 0.05),
+		OrthographicCamera(0, 1, 0, 50),
+		{Type: "perspective", Orthographic: &CameraOrthographic{XMag: 1, YMag: 1, ZFar: 1}},
+		{Type: "fisheye"},
+	} {
+		if err := camera.check(); err == nil {
+			t.Errorf("camera %+v was accepted", camera)
+		}
+	}
+}
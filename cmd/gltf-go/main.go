@@ -0,0 +1,164 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/naikrovek/gltf-go"
+)
+
+var (
+	// vertexColors should be true if the Model you pass in has vertex colors set AND you want the glTF model to use vertex
+	// colors. if vertexColors is false, a bitmapped texture atlas will be created from the Materials used in the Model,
+	// and the UV coordinates for each vertex will be set to the appropriate pixel on the texture atlas.
+	vertexColors = flag.Bool("vc", false, "use vertex colors for mesh colors")
+
+	// if true, a self-contained embedded .gltf file will be generated instead of a self-contained binary .glb.
+	embeddedGltf = flag.Bool("e", false, "create embedded .gltf rather than binary .glb model")
+
+	// if true, a .gltf file referring to a separate .bin file holding the vertex data is generated instead.
+	separateBin = flag.Bool("sb", false, "create .gltf with a separate .bin rather than binary .glb model")
+
+	// if true, the document is written even if it breaks the constraints that (*GlTF).Validate checks.
+	skipValidation = flag.Bool("novalidate", false, "write the model without validating it first")
+
+	// if true, all materials are baked into vertex colors and a custom _MATERIAL_INDEX attribute on a single primitive.
+	// this needs a custom shader to make use of the material indices, so it isn't useful in ordinary viewers.
+	materialIndexed = flag.Bool("mi", false, "bake materials into vertex colors and a _MATERIAL_INDEX attribute (needs a custom shader)")
+
+	// if true, the model is mirrored into a left-handed coordinate system for engines that expect one.  glTF is
+	// right-handed, so the output will look mirrored in every standard viewer.
+	leftHanded = flag.Bool("lh", false, "convert to left-handed coordinates (non-standard; mirrored in glTF viewers)")
+
+	// vertices that match to within this are welded into one after the Geometry is merged by material.
+	weldEpsilon = flag.Float64("weld", 1e-6, "distance within which vertex attributes are considered equal when welding")
+
+	// if true, vertices are never welded, and the output keeps exactly the vertices the Model had.
+	noWeld = flag.Bool("noweld", false, "don't weld duplicate vertices")
+
+	// if true, atlas materials pick out their part of the atlas with KHR_texture_transform instead of remapped UVs.
+	// viewers without the extension can't show the result.
+	textureTransforms = flag.Bool("tt", false, "use KHR_texture_transform for the texture atlas rather than remapping UVs")
+
+	// if true, vertex data is written as normalized integers with KHR_mesh_quantization, which viewers have to support.
+	quantize = flag.Bool("q", false, "quantize positions, normals and tangents with KHR_mesh_quantization")
+
+	// if true, each mesh's vertex attributes share one interleaved buffer view rather than having one each.
+	interleave = flag.Bool("il", false, "interleave vertex attributes into a single buffer view")
+
+	// if true, every Geometry becomes a primitive of its own rather than being merged with others of the same material.
+	keepGeometry = flag.Bool("kg", false, "keep one primitive per geometry rather than merging them by material")
+)
+
+func main() {
+	flag.Parse()
+
+	// set up the single material we'll use.
+	plainMaterial := gltf.Material{
+		DiffuseColor: [3]float32{1.0, 0.0, 0.0},
+		Opacity:      1.0,
+		DoubleSided:  true,
+	}
+
+	// set up a vertex color in case vertex colors are chosen.
+	redColor := gltf.Vector4{
+		R: 1.0,
+		G: 0.0,
+		B: 0.0,
+		A: 1.0,
+	}
+
+	// set up the geometry we're going to render:
+	vert1 := gltf.Vector3{
+		X: 0.0,
+		Y: 0.0,
+		Z: 0.0,
+	}
+
+	vert2 := gltf.Vector3{
+		X: 1.0,
+		Y: 0.0,
+		Z: 0.0,
+	}
+
+	vert3 := gltf.Vector3{
+		X: 0.0,
+		Y: 1.0,
+		Z: 0.0,
+	}
+
+	normal := gltf.Vector3{
+		X: 0.0,
+		Y: 0.0,
+		Z: 1.0,
+	}
+
+	// create a mesh using the geometry we just specified
+	meshes := gltf.Model{
+		Meshes: []gltf.Geometry{
+			gltf.Geometry{
+				Vertices: []gltf.Vertex{
+					gltf.Vertex{
+						Position: vert1,
+						Normal:   normal,
+						Color:    redColor,
+					},
+					gltf.Vertex{
+						Position: vert2,
+						Normal:   normal,
+						Color:    redColor,
+					},
+					gltf.Vertex{
+						Position: vert3,
+						Normal:   normal,
+						Color:    redColor,
+					},
+				},
+				Faces: []gltf.Triangle{
+					gltf.Triangle{
+						TriangleIndices: [3]int32{0, 1, 2},
+					},
+				},
+				Material: plainMaterial,
+			},
+		},
+	}
+
+	format := gltf.OutputGlb
+
+	switch {
+	case *separateBin:
+		format = gltf.OutputSeparateBin
+	case *embeddedGltf:
+		format = gltf.OutputEmbedded
+	}
+
+	if *leftHanded {
+		meshes = gltf.ConvertHandedness(meshes)
+	}
+
+	opts := gltf.PipelineOptions{
+		Options:           gltf.Options{VertexColors: *vertexColors, Interleave: *interleave},
+		MaterialIndexed:   *materialIndexed,
+		WeldEpsilon:       *weldEpsilon,
+		TextureTransforms: *textureTransforms,
+		Quantize:          *quantize,
+		KeepGeometry:      *keepGeometry,
+	}
+
+	if *noWeld {
+		opts.WeldEpsilon = -1
+	}
+
+	gltfDoc, _, err := gltf.OptimizeModel(meshes, opts)
+	failIf(err != nil, err)
+
+	err = gltf.WriteGltf(gltfDoc, "sample", format, *skipValidation)
+	failIf(err != nil, err)
+}
+
+func failIf(condition bool, message ...interface{}) {
+	if condition {
+		log.Fatal(message...)
+	}
+}
@@ -0,0 +1,36 @@
+package gltf
+
+// The KHR_materials_emissive_strength extension multiplies a material's emissiveFactor, which the core spec limits to
+// [0,1], so it can glow brighter than white for HDR rendering and bloom.  Viewers without the extension clamp the
+// emission to the plain factor.
+// See https://github.com/KhronosGroup/glTF/tree/main/extensions/2.0/Khronos/KHR_materials_emissive_strength
+
+const emissiveStrengthExtensionName = "KHR_materials_emissive_strength"
+
+type emissiveStrengthExtension struct {
+	EmissiveStrength float64 `json:"emissiveStrength"`
+}
+
+// returns the glTF emissiveFactor for the Material's EmissiveFactor, or nil if it's black, which is the spec default.
+func (material Material) emissiveFactor() []float64 {
+	c := material.EmissiveFactor
+
+	if c == [3]float32{} {
+		return nil
+	}
+
+	return []float64{float64(c[0]), float64(c[1]), float64(c[2])}
+}
+
+// reports whether a material carries the KHR_materials_emissive_strength extension.
+func (material GltfMaterial) hasEmissiveStrength() bool {
+	extensions, ok := material.Extensions.(map[string]interface{})
+
+	if !ok {
+		return false
+	}
+
+	_, found := extensions[emissiveStrengthExtensionName]
+
+	return found
+}
@@ -0,0 +1,67 @@
+package gltf
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlainEmissive(t *testing.T) {
+	glowing := Material{Opacity: 1, EmissiveFactor: [3]float32{1, 0.5, 0}, DoubleSided: true}
+	dark := Material{Opacity: 1, DiffuseColor: [3]float32{1, 0, 0}}
+	gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{testTriangle(glowing), testTriangle(dark)}}, PipelineOptions{Options: Options{VertexColors: true}})
+
+	if got, want := gltfDoc.Materials[0].EmissiveFactor, []float64{1, 0.5, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("emissiveFactor is %v, want %v", got, want)
+	}
+
+	if !gltfDoc.Materials[0].DoubleSided || gltfDoc.Materials[1].DoubleSided {
+		t.Errorf("doubleSided is %t and %t, want true and false", gltfDoc.Materials[0].DoubleSided, gltfDoc.Materials[1].DoubleSided)
+	}
+
+	// a factor of at most 1 needs no extension.
+	if len(gltfDoc.ExtensionsUsed) != 0 {
+		t.Errorf("extensionsUsed is %v, want it empty", gltfDoc.ExtensionsUsed)
+	}
+
+	data, err := json.Marshal(gltfDoc.Materials[1])
+
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	if strings.Contains(string(data), "emissive") || strings.Contains(string(data), "doubleSided") {
+		t.Errorf("a black, single sided material marshals to %s, want no emissive or doubleSided", data)
+	}
+}
+
+func TestEmissiveStrength(t *testing.T) {
+	bright := Material{Opacity: 1, EmissiveFactor: [3]float32{1, 1, 1}, EmissiveStrength: 4}
+	gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{testTriangle(bright)}}, PipelineOptions{Options: Options{VertexColors: true}})
+
+	if used := strings.Join(gltfDoc.ExtensionsUsed, ","); used != emissiveStrengthExtensionName {
+		t.Errorf("extensionsUsed is %q, want %s", used, emissiveStrengthExtensionName)
+	}
+
+	// viewers without it still show the plain factor, so nothing needs it.
+	if len(gltfDoc.ExtensionsRequired) != 0 {
+		t.Errorf("extensionsRequired is %v, want it empty", gltfDoc.ExtensionsRequired)
+	}
+
+	data, err := json.Marshal(gltfDoc.Materials[0])
+
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	if want := `"extensions":{"KHR_materials_emissive_strength":{"emissiveStrength":4}}`; !strings.Contains(string(data), want) {
+		t.Errorf("material marshals to %s, want it to contain %s", data, want)
+	}
+
+	for _, bad := range []Material{{Opacity: 1, EmissiveStrength: -1}, {Opacity: 1, EmissiveFactor: [3]float32{2, 0, 0}}} {
+		if _, _, err := OptimizeModel(Model{Meshes: []Geometry{testTriangle(bad)}}, PipelineOptions{}); err == nil {
+			t.Errorf("emissive factor %v with strength %g was accepted", bad.EmissiveFactor, bad.EmissiveStrength)
+		}
+	}
+}
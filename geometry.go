@@ -0,0 +1,445 @@
+package gltf
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// DoubleSidedBake returns a copy of the supplied Geometry where every triangle has a back-facing twin: the same three
+// corners wound the other way, using duplicated vertices with negated normals.  Both sides then shade correctly even
+// in viewers that cull or mis-light back faces.  The duplicated vertices keep their UVs and colors, so textures line up
+// on the back just as they do on the front.  This doubles both the vertex and triangle counts.  Everything else about
+// the Geometry, its place in the hierarchy and its transform included, is kept as it is.
+//
+// Only ModeTriangles has triangles to twin, so Geometry with any other mode is returned as it is.
+func DoubleSidedBake(geo Geometry) Geometry {
+	if geo.Mode != ModeTriangles {
+		return geo
+	}
+
+	vertexCount := int32(len(geo.Vertices))
+
+	baked := geo
+	baked.Vertices = make([]Vertex, 0, len(geo.Vertices)*2)
+	baked.Faces = make([]Triangle, 0, len(geo.Faces)*2)
+
+	baked.Vertices = append(baked.Vertices, geo.Vertices...)
+
+	for _, v := range geo.Vertices {
+		v.Normal = Vector3{X: -v.Normal.X, Y: -v.Normal.Y, Z: -v.Normal.Z}
+		// the tangent still follows U, but with the normal flipped the bitangent only stays put if its sign flips too.
+		v.Tangent.A = 0 - v.Tangent.A
+
+		// the back's normals have to move the opposite way to the front's.
+		if len(v.Morphs) > 0 {
+			morphs := make([]MorphDelta, len(v.Morphs))
+
+			for t, delta := range v.Morphs {
+				delta.Normal = Vector3{X: -delta.Normal.X, Y: -delta.Normal.Y, Z: -delta.Normal.Z}
+				morphs[t] = delta
+			}
+
+			v.Morphs = morphs
+		}
+
+		baked.Vertices = append(baked.Vertices, v)
+	}
+
+	baked.Faces = append(baked.Faces, geo.Faces...)
+
+	// the back faces use the flipped copies of the vertices, and swap two corners to reverse the winding.
+	for _, f := range geo.Faces {
+		baked.Faces = append(baked.Faces, Triangle{
+			TriangleIndices: [3]int32{
+				f.TriangleIndices[0] + vertexCount,
+				f.TriangleIndices[2] + vertexCount,
+				f.TriangleIndices[1] + vertexCount,
+			},
+		})
+	}
+
+	return baked
+}
+
+// WeldVertices returns a copy of the supplied Geometry where vertices that are the same to within epsilon share one
+// index, so a mesh stored as a triangle soup gets its shared corners back.  Every attribute has to match, not just the
+// position: corners on either side of a hard edge or a UV seam stay separate, as they must.  The first of each set of
+// matching vertices is the one kept.  An epsilon of 0 only welds vertices that are exactly the same.
+//
+// Strips, loops, fans and points are drawn in vertex order, so Geometry with those modes is returned as it is.
+func WeldVertices(geo Geometry, epsilon float64) Geometry {
+	if geo.Mode.isOrdered() {
+		return geo
+	}
+
+	welded := geo
+	welded.Vertices = make([]Vertex, 0, len(geo.Vertices))
+	welded.Faces = make([]Triangle, len(geo.Faces))
+	welded.Edges = nil
+
+	// remap[i] is the index in welded.Vertices of the vertex that geo.Vertices[i] became.
+	remap := make([]int32, len(geo.Vertices))
+	seen := make(map[string]int32)
+
+	for i, v := range geo.Vertices {
+		key := weldKey(v, epsilon)
+
+		index, ok := seen[key]
+
+		if !ok {
+			index = int32(len(welded.Vertices))
+			seen[key] = index
+			welded.Vertices = append(welded.Vertices, v)
+		}
+
+		remap[i] = index
+	}
+
+	for i, f := range geo.Faces {
+		welded.Faces[i] = Triangle{TriangleIndices: [3]int32{
+			remap[f.TriangleIndices[0]],
+			remap[f.TriangleIndices[1]],
+			remap[f.TriangleIndices[2]],
+		}}
+	}
+
+	for _, e := range geo.Edges {
+		welded.Edges = append(welded.Edges, [2]int32{remap[e[0]], remap[e[1]]})
+	}
+
+	return welded
+}
+
+// returns a map key that's the same for vertices whose attributes, morph target offsets included, all round to the
+// same multiple of epsilon, or, with an epsilon of 0, are exactly the same.
+func weldKey(v Vertex, epsilon float64) string {
+	values := []float32{
+		v.Position.X, v.Position.Y, v.Position.Z,
+		v.Normal.X, v.Normal.Y, v.Normal.Z,
+		v.UV.U, v.UV.V,
+		v.UV2.U, v.UV2.V,
+		v.Color.R, v.Color.G, v.Color.B, v.Color.A,
+		v.Tangent.R, v.Tangent.G, v.Tangent.B, v.Tangent.A,
+		v.Velocity.X, v.Velocity.Y, v.Velocity.Z,
+		v.Weights[0], v.Weights[1], v.Weights[2], v.Weights[3],
+	}
+
+	for _, delta := range v.Morphs {
+		values = append(values,
+			delta.Position.X, delta.Position.Y, delta.Position.Z,
+			delta.Normal.X, delta.Normal.Y, delta.Normal.Z,
+			delta.Tangent.X, delta.Tangent.Y, delta.Tangent.Z,
+		)
+	}
+
+	key := make([]int64, len(values), len(values)+5)
+
+	for i, value := range values {
+		// adding 0 turns -0 into 0, which would otherwise have different bits.
+		if epsilon == 0 {
+			key[i] = int64(math.Float32bits(value + 0))
+		} else {
+			key[i] = int64(math.Round(float64(value) / epsilon))
+		}
+	}
+
+	key = append(key, int64(v.MaterialIndex))
+
+	for _, joint := range v.Joints {
+		key = append(key, int64(joint))
+	}
+
+	b := make([]byte, 8*len(key))
+
+	for i, k := range key {
+		binary.LittleEndian.PutUint64(b[8*i:], uint64(k))
+	}
+
+	return string(b)
+}
+
+// ConvertHandedness returns a copy of the supplied Model mirrored through the XY plane, for engines that use a
+// left-handed coordinate system: the Z component of every position, normal, tangent, velocity and morph target offset
+// is negated, as is each tangent's handedness, and every triangle's winding is reversed so that front faces stay front
+// faces.  The transforms of a hierarchical Model, and its skins' inverse bind matrices, are mirrored to match.
+// Converting twice returns the original Model, so the same function converts left-handed input back to right-handed.
+//
+// glTF is always right-handed.  The output of this is not valid glTF in anything but name, and standard viewers will
+// show it mirrored; only use it for pipelines whose target engine expects left-handed data.
+func ConvertHandedness(model Model) Model {
+	converted := Model{Meshes: make([]Geometry, 0, len(model.Meshes)), Scenes: model.Scenes, DefaultScene: model.DefaultScene}
+
+	for _, geo := range model.Meshes {
+		vertices := make([]Vertex, len(geo.Vertices))
+		faces := make([]Triangle, len(geo.Faces))
+
+		for i, v := range geo.Vertices {
+			// subtracting from zero rather than negating keeps 0 from becoming -0, which the JSON output shows as "-0".
+			v.Position.Z = 0 - v.Position.Z
+			v.Normal.Z = 0 - v.Normal.Z
+			v.Velocity.Z = 0 - v.Velocity.Z
+			// mirroring reverses the handedness of the tangent frame, so the bitangent sign flips as well.
+			v.Tangent.B = 0 - v.Tangent.B
+			v.Tangent.A = 0 - v.Tangent.A
+
+			// the morph target offsets are mirrored like the attributes they move.
+			if len(v.Morphs) > 0 {
+				morphs := make([]MorphDelta, len(v.Morphs))
+
+				for t, delta := range v.Morphs {
+					delta.Position.Z = 0 - delta.Position.Z
+					delta.Normal.Z = 0 - delta.Normal.Z
+					delta.Tangent.Z = 0 - delta.Tangent.Z
+					morphs[t] = delta
+				}
+
+				v.Morphs = morphs
+			}
+
+			vertices[i] = v
+		}
+
+		for i, f := range geo.Faces {
+			faces[i] = Triangle{TriangleIndices: [3]int32{f.TriangleIndices[0], f.TriangleIndices[2], f.TriangleIndices[1]}}
+		}
+
+		geo.Vertices = vertices
+		geo.Faces = faces
+
+		// a mirrored rotation turns the other way about the X and Y axes but the same way about Z.
+		if len(geo.Translation) == 3 {
+			geo.Translation = []float64{geo.Translation[0], geo.Translation[1], 0 - geo.Translation[2]}
+		}
+
+		if len(geo.Rotation) == 4 {
+			geo.Rotation = []float64{0 - geo.Rotation[0], 0 - geo.Rotation[1], geo.Rotation[2], geo.Rotation[3]}
+		}
+
+		// mirroring a matrix on both sides negates the elements that mix Z with X, Y or W.
+		if len(geo.Matrix) == 16 {
+			matrix := make([]float64, 16)
+
+			for i, m := range geo.Matrix {
+				if (i/4 == 2) != (i%4 == 2) {
+					m = 0 - m
+				}
+
+				matrix[i] = m
+			}
+
+			geo.Matrix = matrix
+		}
+
+		// the inverse bind matrices are mirrored just like the joints' transforms.
+		if geo.Skin != nil {
+			skin := *geo.Skin
+			skin.InverseBindMatrices = make([][16]float32, len(geo.Skin.InverseBindMatrices))
+
+			for m, matrix := range geo.Skin.InverseBindMatrices {
+				for i := range matrix {
+					if (i/4 == 2) != (i%4 == 2) {
+						matrix[i] = 0 - matrix[i]
+					}
+				}
+
+				skin.InverseBindMatrices[m] = matrix
+			}
+
+			geo.Skin = &skin
+		}
+
+		converted.Meshes = append(converted.Meshes, geo)
+	}
+
+	return converted
+}
+
+// Axis constants for GenerateUVsPlanar.
+const (
+	AxisX = 0
+	AxisY = 1
+	AxisZ = 2
+)
+
+// GenerateUVsPlanar sets the UV of every vertex by projecting its position along the supplied axis (AxisX, AxisY or
+// AxisZ; anything else is treated as AxisZ) onto the plane formed by the other two.  The UVs are scaled to the
+// geometry's bounding box so they fall in [0,1].
+func GenerateUVsPlanar(geo *Geometry, axis int) {
+	min, max := geometryBounds(*geo)
+
+	for i := range geo.Vertices {
+		geo.Vertices[i].UV = projectUV(geo.Vertices[i].Position, axis, min, max)
+	}
+}
+
+// GenerateUVsBox sets the UV of every vertex by projecting each triangle along whichever axis its face normal points
+// most strongly along, like wrapping the geometry in a box and projecting each side of the box onto it.  Vertices that
+// are shared by triangles that project along different axes are duplicated so that each triangle gets the right UVs.
+// The UVs are scaled to the geometry's bounding box so they fall in [0,1].
+func GenerateUVsBox(geo *Geometry) {
+	min, max := geometryBounds(*geo)
+
+	// the axis each vertex has been projected along so far, or -1 if it hasn't been used yet.
+	vertexAxes := make([]int, len(geo.Vertices))
+
+	for i := range vertexAxes {
+		vertexAxes[i] = -1
+	}
+
+	// the copies made of shared vertices, keyed by the original vertex index and the axis of the copy.
+	duplicates := make(map[[2]int]int32)
+
+	for f := range geo.Faces {
+		axis := dominantAxis(faceNormal(*geo, geo.Faces[f]))
+
+		for c, index := range geo.Faces[f].TriangleIndices {
+			switch vertexAxes[index] {
+			case -1:
+				vertexAxes[index] = axis
+				geo.Vertices[index].UV = projectUV(geo.Vertices[index].Position, axis, min, max)
+			case axis:
+				// already projected the same way, nothing to do.
+			default:
+				key := [2]int{int(index), axis}
+
+				duplicate, ok := duplicates[key]
+
+				if !ok {
+					v := geo.Vertices[index]
+					v.UV = projectUV(v.Position, axis, min, max)
+
+					geo.Vertices = append(geo.Vertices, v)
+					vertexAxes = append(vertexAxes, axis)
+
+					duplicate = int32(len(geo.Vertices) - 1)
+					duplicates[key] = duplicate
+				}
+
+				geo.Faces[f].TriangleIndices[c] = duplicate
+			}
+		}
+	}
+}
+
+// projects a position along the supplied axis, and normalizes the result to the supplied bounds.
+func projectUV(position Vector3, axis int, min, max Vector3) Vector2 {
+	uAxis, vAxis := AxisX, AxisY
+
+	switch axis {
+	case AxisX:
+		uAxis, vAxis = AxisZ, AxisY
+	case AxisY:
+		uAxis, vAxis = AxisX, AxisZ
+	}
+
+	return Vector2{
+		U: normalizeToRange(component(position, uAxis), component(min, uAxis), component(max, uAxis)),
+		V: normalizeToRange(component(position, vAxis), component(min, vAxis), component(max, vAxis)),
+	}
+}
+
+// maps x from [min,max] to [0,1].  A flat range maps everything to 0.
+func normalizeToRange(x, min, max float32) float32 {
+	if max == min {
+		return 0
+	}
+
+	return (x - min) / (max - min)
+}
+
+// returns the index of the component with the largest magnitude.
+func dominantAxis(v Vector3) int {
+	x, y, z := math.Abs(float64(v.X)), math.Abs(float64(v.Y)), math.Abs(float64(v.Z))
+
+	if x >= y && x >= z {
+		return AxisX
+	}
+
+	if y >= z {
+		return AxisY
+	}
+
+	return AxisZ
+}
+
+func component(v Vector3, axis int) float32 {
+	switch axis {
+	case AxisX:
+		return v.X
+	case AxisY:
+		return v.Y
+	default:
+		return v.Z
+	}
+}
+
+// returns the (unnormalized) normal of the supplied triangle, from its winding.
+func faceNormal(geo Geometry, f Triangle) Vector3 {
+	a := geo.Vertices[f.TriangleIndices[0]].Position
+	b := geo.Vertices[f.TriangleIndices[1]].Position
+	c := geo.Vertices[f.TriangleIndices[2]].Position
+
+	return cross(sub(b, a), sub(c, a))
+}
+
+// returns the minimum and maximum of each component of the geometry's vertex positions.
+func geometryBounds(geo Geometry) (min, max Vector3) {
+	return bounds(getVertices(geo))
+}
+
+// returns the minimum and maximum of each component of the supplied vectors.
+func bounds(vectors []Vector3) (min, max Vector3) {
+	if len(vectors) == 0 {
+		return min, max
+	}
+
+	min = vectors[0]
+	max = vectors[0]
+
+	for _, p := range vectors {
+		min.X = float32(math.Min(float64(min.X), float64(p.X)))
+		min.Y = float32(math.Min(float64(min.Y), float64(p.Y)))
+		min.Z = float32(math.Min(float64(min.Z), float64(p.Z)))
+
+		max.X = float32(math.Max(float64(max.X), float64(p.X)))
+		max.Y = float32(math.Max(float64(max.Y), float64(p.Y)))
+		max.Z = float32(math.Max(float64(max.Z), float64(p.Z)))
+	}
+
+	return min, max
+}
+
+func add(a, b Vector3) Vector3 {
+	return Vector3{X: a.X + b.X, Y: a.Y + b.Y, Z: a.Z + b.Z}
+}
+
+func sub(a, b Vector3) Vector3 {
+	return Vector3{X: a.X - b.X, Y: a.Y - b.Y, Z: a.Z - b.Z}
+}
+
+func cross(a, b Vector3) Vector3 {
+	return Vector3{
+		X: a.Y*b.Z - a.Z*b.Y,
+		Y: a.Z*b.X - a.X*b.Z,
+		Z: a.X*b.Y - a.Y*b.X,
+	}
+}
+
+func mul(v Vector3, s float32) Vector3 {
+	return Vector3{X: v.X * s, Y: v.Y * s, Z: v.Z * s}
+}
+
+func dot(a, b Vector3) float32 {
+	return a.X*b.X + a.Y*b.Y + a.Z*b.Z
+}
+
+// returns v scaled to unit length, or v unchanged if it has no length.
+func normalize(v Vector3) Vector3 {
+	length := float32(math.Sqrt(float64(dot(v, v))))
+
+	if length == 0 {
+		return v
+	}
+
+	return Vector3{X: v.X / length, Y: v.Y / length, Z: v.Z / length}
+}
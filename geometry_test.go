@@ -0,0 +1,106 @@
+package gltf
+
+import (
+	"reflect"
+	"testing"
+)
+
+// returns testQuad as a triangle soup: each of its two triangles with three vertices of its own.
+func testQuadSoup(material Material) Geometry {
+	quad := testQuad(material)
+	soup := Geometry{Material: material}
+
+	for _, face := range quad.Faces {
+		first := int32(len(soup.Vertices))
+
+		for _, index := range face.TriangleIndices {
+			soup.Vertices = append(soup.Vertices, quad.Vertices[index])
+		}
+
+		soup.Faces = append(soup.Faces, Triangle{TriangleIndices: [3]int32{first, first + 1, first + 2}})
+	}
+
+	return soup
+}
+
+func TestWeldQuad(t *testing.T) {
+	tests := []struct {
+		name        string
+		weldEpsilon float64
+		vertices    int
+	}{
+		{"welded", 0, 4},
+		// a negative epsilon turns welding off, for callers that need every face's own data.
+		{"not welded", -1, 6},
+	}
+
+	for _, test := range tests {
+		opts := PipelineOptions{Options: Options{VertexColors: true}, WeldEpsilon: test.weldEpsilon}
+		gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{testQuadSoup(Material{Opacity: 1})}}, opts)
+		primitive := gltfDoc.Meshes[0].Primitives[0]
+
+		if count := gltfDoc.Accessors[primitive.Attributes["POSITION"]].Count; count != test.vertices {
+			t.Errorf("%s: POSITION has %d vertices, want %d", test.name, count, test.vertices)
+		}
+
+		if count := gltfDoc.Accessors[*primitive.Indices].Count; count != 6 {
+			t.Errorf("%s: there are %d indices, want 6", test.name, count)
+		}
+	}
+}
+
+func TestWeldVerticesEpsilon(t *testing.T) {
+	soup := testQuadSoup(Material{})
+	// the second triangle's copy of the first corner is a little off.
+	soup.Vertices[3].Position.X += 1e-5
+
+	if welded := WeldVertices(soup, 0); len(welded.Vertices) != 5 {
+		t.Errorf("with an epsilon of 0, %d vertices are left, want 5", len(welded.Vertices))
+	}
+
+	welded := WeldVertices(soup, 1e-3)
+
+	if len(welded.Vertices) != 4 {
+		t.Fatalf("with an epsilon of 0.001, %d vertices are left, want 4", len(welded.Vertices))
+	}
+
+	// the faces still draw the same corners.
+	for i, face := range welded.Faces {
+		for j, index := range face.TriangleIndices {
+			if got, want := welded.Vertices[index].UV, soup.Vertices[soup.Faces[i].TriangleIndices[j]].UV; got != want {
+				t.Errorf("face %d corner %d has UV %v, want %v", i, j, got, want)
+			}
+		}
+	}
+}
+
+func TestDoubleSidedBake(t *testing.T) {
+	geo := testTriangle(Material{Opacity: 1})
+	geo.Translation = []float64{1, 2, 3}
+	geo.Children = []int{1}
+	geo.Extras = map[string]interface{}{"name": "card"}
+
+	baked := DoubleSidedBake(geo)
+
+	if len(baked.Vertices) != 6 || len(baked.Faces) != 2 {
+		t.Fatalf("got %d vertices and %d triangles, want 6 and 2", len(baked.Vertices), len(baked.Faces))
+	}
+
+	if back := baked.Vertices[3].Normal; back != (Vector3{Z: -1}) {
+		t.Errorf("the back's normal is %v, want -Z", back)
+	}
+
+	if want := [3]int32{3, 5, 4}; baked.Faces[1].TriangleIndices != want {
+		t.Errorf("the back face is %v, want %v, wound the other way", baked.Faces[1].TriangleIndices, want)
+	}
+
+	if !reflect.DeepEqual(baked.Translation, geo.Translation) || !reflect.DeepEqual(baked.Children, geo.Children) || !reflect.DeepEqual(baked.Extras, geo.Extras) {
+		t.Errorf("baking lost the translation, children or extras: %+v", baked)
+	}
+
+	lines := Geometry{Vertices: geo.Vertices, Edges: [][2]int32{{0, 1}, {1, 2}}, Mode: ModeLines}
+
+	if baked := DoubleSidedBake(lines); !reflect.DeepEqual(baked, lines) {
+		t.Errorf("baking line geometry changed it to %+v", baked)
+	}
+}
@@ -1,18 +1,27 @@
-package main
+// Package gltf builds glTF 2.0 documents from Models, merging their Geometry by material into a texture atlas or vertex
+// colors, and writes them as .glb, embedded .gltf or .gltf with a separate .bin.  Start with OptimizeModel and
+// WriteGltf.
+package gltf
 
 import (
+	"archive/zip"
 	"bufio"
 	"bytes"
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"image"
-	"image/color"
 	"image/png"
+	"io"
 	"log"
 	"math"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -22,18 +31,127 @@ import (
 
 // Accessor ...
 type Accessor struct {
-	BufferView    int         `json:"bufferView" validator:"gte=0"`
-	ByteOffset    int         `json:"byteOffset" validator:"gte=0"`
-	ComponentType interface{} `json:"componentType,omitempty"`
-	Count         int         `json:"count" validator:"gte=1"`
-	Type          interface{} `json:"type,omitempty"`
-	Extensions    interface{} `json:"extensions,omitempty"`
-	Extras        interface{} `json:"extras,omitempty"`
-	Max           []float32   `json:"max,omitempty"`
-	Min           []float32   `json:"min,omitempty"`
-	Name          interface{} `json:"name,omitempty"`
-	Normalized    bool        `json:"normalized,omitempty"`
-	Sparse        interface{} `json:"sparse,omitempty"`
+	BufferView    *int          `json:"bufferView,omitempty" validator:"gte=0"` // nil is all zeros, unless Sparse says otherwise.
+	ByteOffset    int           `json:"byteOffset,omitempty" validator:"gte=0"` // left out when 0, since it mustn't be given without a BufferView.
+	ComponentType ComponentType `json:"componentType,omitempty"`
+	Count         int           `json:"count" validator:"gte=1"`
+	Type          AccessorType  `json:"type,omitempty"`
+	Extensions    interface{}   `json:"extensions,omitempty"`
+	Extras        interface{}   `json:"extras,omitempty"`
+	Max           []float32     `json:"max,omitempty"`
+	Min           []float32     `json:"min,omitempty"`
+	Name          interface{}   `json:"name,omitempty"`
+	Normalized    bool          `json:"normalized,omitempty"`
+	Sparse        *Sparse       `json:"sparse,omitempty"`
+}
+
+// returns the index of the accessor's buffer view, or -1 if it has none.
+func (accessor Accessor) bufferViewIndex() int {
+	if accessor.BufferView == nil {
+		return -1
+	}
+
+	return *accessor.BufferView
+}
+
+// returns the index of the last of the buffer views, for the Accessor of the data that's just been given it.
+func lastBufferView(gltfBufferViews []BufferView) *int {
+	index := len(gltfBufferViews) - 1
+	return &index
+}
+
+// ComponentType is the data type of the components of an accessor's elements.
+type ComponentType uint32
+
+// The component types glTF allows.
+const (
+	Byte          ComponentType = 5120
+	UnsignedByte  ComponentType = 5121
+	Short         ComponentType = 5122
+	UnsignedShort ComponentType = 5123
+	UnsignedInt   ComponentType = 5125
+	Float         ComponentType = 5126
+)
+
+// MarshalJSON writes the component type as the plain integer the spec uses.
+func (componentType ComponentType) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatUint(uint64(componentType), 10)), nil
+}
+
+// UnmarshalJSON reads the integer the spec uses, rejecting anything that isn't one of the allowed component types so
+// that a bad file fails when it's loaded rather than when its data is read.
+func (componentType *ComponentType) UnmarshalJSON(data []byte) error {
+	n, err := strconv.ParseUint(string(data), 10, 32)
+
+	if err != nil || ComponentType(n).Size() == 0 {
+		return fmt.Errorf("invalid accessor componentType %s", data)
+	}
+
+	*componentType = ComponentType(n)
+
+	return nil
+}
+
+// Size returns the size in bytes of one component of this type, or 0 if it isn't a valid component type.
+func (componentType ComponentType) Size() int {
+	switch componentType {
+	case Byte, UnsignedByte:
+		return 1
+	case Short, UnsignedShort:
+		return 2
+	case UnsignedInt, Float:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// AccessorType is the shape of each of an accessor's elements.  It marshals as the uppercase strings the spec uses.
+type AccessorType string
+
+// The accessor types glTF allows.
+const (
+	Scalar AccessorType = "SCALAR"
+	Vec2   AccessorType = "VEC2"
+	Vec3   AccessorType = "VEC3"
+	Vec4   AccessorType = "VEC4"
+	Mat2   AccessorType = "MAT2"
+	Mat3   AccessorType = "MAT3"
+	Mat4   AccessorType = "MAT4"
+)
+
+// UnmarshalJSON rejects anything that isn't one of the allowed accessor types, so a misspelled type is caught when a
+// file is loaded.
+func (accessorType *AccessorType) UnmarshalJSON(data []byte) error {
+	var name string
+
+	if err := json.Unmarshal(data, &name); err != nil || componentCount(AccessorType(name)) == 0 {
+		return fmt.Errorf("invalid accessor type %s", data)
+	}
+
+	*accessorType = AccessorType(name)
+
+	return nil
+}
+
+// returns the number of components in each element of an accessor of the supplied type, or 0 if it isn't valid.
+func componentCount(accessorType AccessorType) int {
+	switch accessorType {
+	case Scalar:
+		return 1
+	case Vec2:
+		return 2
+	case Vec3:
+		return 3
+	case Vec4, Mat2:
+		return 4
+	case Mat3:
+		return 9
+	case Mat4:
+		return 16
+	default:
+		return 0
+	}
 }
 
 // Asset ...
@@ -51,7 +169,7 @@ type BufferView struct {
 	Buffer     int         `json:"buffer" validator:"gte=0"`
 	ByteLength int         `json:"byteLength" validator:"gte=1"`
 	ByteOffset int         `json:"byteOffset" validator:"gte=0"`
-	ByteStride int         `json:"byteStride,omitempty" validator:"gte=4, lte=252"`
+	ByteStride int         `json:"byteStride,omitempty" validator:"gte=4, lte=252, multiple=4"`
 	Extensions interface{} `json:"extensions,omitempty"`
 	Extras     interface{} `json:"extras,omitempty"`
 	Name       interface{} `json:"name,omitempty"`
@@ -61,9 +179,11 @@ type BufferView struct {
 // GlTF ...
 type GlTF struct {
 	Accessors          []Accessor     `json:"accessors,omitempty"`
+	Animations         []Animation    `json:"animations,omitempty"`
 	Asset              interface{}    `json:"asset,omitempty"`
 	Buffers            []GltfBuffer   `json:"buffers,omitempty"`
 	BufferViews        []BufferView   `json:"bufferViews,omitempty"`
+	Cameras            []Camera       `json:"cameras,omitempty"`
 	Extensions         interface{}    `json:"extensions,omitempty"`
 	ExtensionsRequired []string       `json:"extensionsRequired,omitempty"`
 	ExtensionsUsed     []string       `json:"extensionsUsed,omitempty"`
@@ -71,19 +191,25 @@ type GlTF struct {
 	Materials          []GltfMaterial `json:"materials,omitempty"`
 	Meshes             []Mesh         `json:"meshes,omitempty"`
 	Nodes              []Node         `json:"nodes,omitempty"`
-	Scene              int            `json:"scene"`
+	Samplers           []Sampler      `json:"samplers,omitempty"`
+	Scene              *int           `json:"scene,omitempty"`
 	Scenes             []Scene        `json:"scenes,omitempty"`
+	Skins              []Skin         `json:"skins,omitempty"`
 	Textures           []GltfTexture  `json:"textures,omitempty"`
 }
 
 // GltfTexture ...
 type GltfTexture struct {
-	Source interface{} `json:"source,omitempty"`
+	Sampler *int        `json:"sampler,omitempty"` // nil leaves the filtering to the viewer, with repeat wrapping.
+	Source  interface{} `json:"source,omitempty"`
 }
 
-// GltfImage ...
+// GltfImage is either a URI, which may be a data URI, or a buffer view holding the image, in which case MimeType has
+// to say whether it's "image/png" or "image/jpeg".
 type GltfImage struct {
-	URI string `json:"uri,omitempty"`
+	BufferView *int   `json:"bufferView,omitempty"`
+	MimeType   string `json:"mimeType,omitempty"`
+	URI        string `json:"uri,omitempty"`
 }
 
 // GltfBuffer ...
@@ -105,24 +231,47 @@ type GltfMaterial struct {
 	AlphaMode            interface{}                  `json:"alphaMode,omitempty"`
 	DoubleSided          bool                         `json:"doubleSided,omitempty"`
 	EmissiveFactor       []float64                    `json:"emissiveFactor,omitempty"`
-	EmissiveTexture      interface{}                  `json:"emissiveTexture,omitempty"`
+	EmissiveTexture      *TextureInfo                 `json:"emissiveTexture,omitempty"`
 	Extensions           interface{}                  `json:"extensions,omitempty"`
 	Extras               interface{}                  `json:"extras,omitempty"`
 	Name                 interface{}                  `json:"name,omitempty"`
-	NormalTexture        interface{}                  `json:"normalTexture,omitempty"`
-	OcclusionTexture     interface{}                  `json:"occlusionTexture,omitempty"`
+	NormalTexture        *NormalTextureInfo           `json:"normalTexture,omitempty"`
+	OcclusionTexture     *OcclusionTextureInfo        `json:"occlusionTexture,omitempty"`
 	PbrMetallicRoughness MaterialPbrMetallicRoughness `json:"pbrMetallicRoughness,omitempty"`
 }
 
+// NormalTextureInfo refers a material to the texture holding its tangent space normal map.
+type NormalTextureInfo struct {
+	Extensions interface{} `json:"extensions,omitempty"`
+	Index      int         `json:"index" validator:"gte=0"`
+	TexCoord   int         `json:"texCoord,omitempty" validator:"gte=0"` // which TEXCOORD_n set to sample it with.
+	Scale      float64     `json:"scale,omitempty"`                      // scales the normal map's X and Y; 0 leaves the spec default of 1.
+}
+
+// OcclusionTextureInfo refers a material to the texture holding its ambient occlusion, in the red channel.
+type OcclusionTextureInfo struct {
+	Extensions interface{} `json:"extensions,omitempty"`
+	Index      int         `json:"index" validator:"gte=0"`
+	TexCoord   int         `json:"texCoord,omitempty" validator:"gte=0"`
+	Strength   float64     `json:"strength,omitempty" validator:"gte=0, lte=1"` // 0 leaves the spec default of 1.
+}
+
+// TextureInfo refers a material to a texture, such as its emissive texture, that has no settings of its own.
+type TextureInfo struct {
+	Extensions interface{} `json:"extensions,omitempty"`
+	Index      int         `json:"index" validator:"gte=0"`
+	TexCoord   int         `json:"texCoord,omitempty" validator:"gte=0"`
+}
+
 // MaterialPbrMetallicRoughness ...
 type MaterialPbrMetallicRoughness struct {
-	BaseColorFactor          []float64   `json:"-"`
+	BaseColorFactor          []float64   `json:"baseColorFactor,omitempty"`
 	BaseColorTexture         interface{} `json:"baseColorTexture,omitempty"`
 	Extensions               interface{} `json:"extensions,omitempty"`
 	Extras                   interface{} `json:"extras,omitempty"`
 	MetallicFactor           float64     `json:"metallicFactor" validator:"gte=0, lte=1"`
 	MetallicRoughnessTexture interface{} `json:"metallicRoughnessTexture,omitempty"`
-	RoughnessFactor          float64     `json:"roughnessFactor,omitempty" validator:"gte=0, lte=1"`
+	RoughnessFactor          float64     `json:"roughnessFactor" validator:"gte=0, lte=1"` // always written: left out, it'd be the spec default of 1, not 0.
 }
 
 // Mesh ...
@@ -135,20 +284,141 @@ type Mesh struct {
 }
 
 type meshInfoAssociation struct {
-	MeshIndicesAccessorIndex     int
-	MeshVerticesAccessorIndex    int
-	MeshNormalsAccessorIndex     int
-	MeshMaterialIndex            int
-	MeshUVAccessorIndex          int
-	MeshVertexColorAccessorIndex int
+	MeshIndicesAccessorIndex       int
+	MeshMode                       PrimitiveMode
+	MeshVerticesAccessorIndex      int
+	MeshNormalsAccessorIndex       int
+	MeshTangentsAccessorIndex      int
+	MeshMaterialIndex              int
+	MeshUVAccessorIndex            int
+	MeshUV2AccessorIndex           int
+	MeshVertexColorAccessorIndex   int
+	MeshVelocityAccessorIndex      int
+	MeshMaterialIndexAccessorIndex int
+	MeshJointsAccessorIndex        int
+	MeshWeightsAccessorIndex       int
+	MeshTargets                    []Attributes
+	MeshQuantization               *Quantization
+	MeshExtras                     interface{}
 }
 
 // MeshPrimitive ...
 type MeshPrimitive struct {
-	Attributes map[string]int `json:"attributes,omitempty"`
-	Indices    int            `json:"indices" validator:"gte=0"`
-	Material   int            `json:"material" validator:"gte=0"`
-	Mode       int            `json:"mode,omitempty"`
+	Attributes Attributes   `json:"attributes,omitempty"`
+	Indices    *int         `json:"indices,omitempty" validator:"gte=0"` // nil draws the vertices in order.
+	Material   int          `json:"material" validator:"gte=0"`
+	Mode       *int         `json:"mode,omitempty"`    // nil is the spec default, 4 (TRIANGLES).
+	Targets    []Attributes `json:"targets,omitempty"` // the morph targets, each a map of attribute offsets.
+	Extras     interface{}  `json:"extras,omitempty"`
+}
+
+// PrimitiveMode is the kind of primitive a Geometry is drawn as.  The zero value is triangles, so that Geometry that
+// doesn't mention a mode stays as it always was; the values aren't the glTF ones, which gltfMode returns.
+type PrimitiveMode int
+
+const (
+	// ModeTriangles draws each of the Geometry's Faces.
+	ModeTriangles PrimitiveMode = iota
+	// ModePoints draws each vertex as a point.  No indices are written.
+	ModePoints
+	// ModeLines draws each of the Geometry's Edges, or, if it has none, the edges of its Faces.
+	ModeLines
+	// ModeLineLoop, ModeLineStrip, ModeTriangleStrip and ModeTriangleFan join the vertices up in the order they're
+	// stored, as glTF describes.  No indices are written, and Geometry using them is never merged with any other.
+	ModeLineLoop
+	ModeLineStrip
+	ModeTriangleStrip
+	ModeTriangleFan
+)
+
+// returns the glTF value of the mode, as MeshPrimitive.Mode has it.
+func (mode PrimitiveMode) gltfMode() int {
+	switch mode {
+	case ModePoints:
+		return 0
+	case ModeLines:
+		return 1
+	case ModeLineLoop:
+		return 2
+	case ModeLineStrip:
+		return 3
+	case ModeTriangleStrip:
+		return 5
+	case ModeTriangleFan:
+		return 6
+	default:
+		return 4
+	}
+}
+
+// reports whether the mode draws the vertices in the order they're stored, with no indices.
+func (mode PrimitiveMode) isOrdered() bool {
+	return mode != ModeTriangles && mode != ModeLines
+}
+
+// Attributes maps attribute semantics (POSITION, NORMAL, etc.) to accessor indices.  It marshals in a fixed order,
+// with POSITION first, rather than in encoding/json's alphabetical map order, because some viewers care about that.
+type Attributes map[string]int
+
+// the order attributes are written in.  Any other standard attributes follow these, sorted by name, and then any
+// custom attributes (those starting with an underscore), also sorted by name.
+var attributeOrder = []string{"POSITION", "NORMAL", "TANGENT", "TEXCOORD_0", "TEXCOORD_1", "COLOR_0", "JOINTS_0", "WEIGHTS_0"}
+
+// MarshalJSON writes the attributes in the canonical order described by attributeOrder.
+func (attributes Attributes) MarshalJSON() ([]byte, error) {
+	if attributes == nil {
+		return []byte("null"), nil
+	}
+
+	rank := func(name string) int {
+		for i, a := range attributeOrder {
+			if a == name {
+				return i
+			}
+		}
+
+		if strings.HasPrefix(name, "_") {
+			return len(attributeOrder) + 1
+		}
+
+		return len(attributeOrder)
+	}
+
+	names := make([]string, 0, len(attributes))
+
+	for name := range attributes {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		if rank(names[i]) != rank(names[j]) {
+			return rank(names[i]) < rank(names[j])
+		}
+
+		return names[i] < names[j]
+	})
+
+	out := new(bytes.Buffer)
+	out.WriteByte('{')
+
+	for i, name := range names {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+
+		key, err := json.Marshal(name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		out.Write(key)
+		fmt.Fprintf(out, ":%d", attributes[name])
+	}
+
+	out.WriteByte('}')
+
+	return out.Bytes(), nil
 }
 
 // Node ...
@@ -175,36 +445,378 @@ type Scene struct {
 	Nodes      []int       `json:"nodes,omitempty"`
 }
 
-func writeGltf(model Model, atlas bytes.Buffer, filename string, embeddedGltf bool, vertexColors bool) {
-	gltfDoc := ToGltfDoc(model, atlas, vertexColors)
+// Options controls how Geometry is turned into glTF data.
+type Options struct {
+	// VertexColors has the same meaning as the vertexColors argument to optimizeModel and ToGltfDoc: if true, COLOR_0
+	// is emitted from each Vertex.Color, otherwise TEXCOORD_0 is emitted and the material samples the texture atlas.
+	// Geometry without any vertex colors, or with COLOR_0 left out, has its material's DiffuseColor and Opacity put in
+	// the glTF material's base color factor instead.
+	VertexColors bool
+
+	// Attributes lists the vertex attributes to emit, by their glTF names: NORMAL, TANGENT, TEXCOORD_0, TEXCOORD_1,
+	// COLOR_0, JOINTS_0, WEIGHTS_0, _VELOCITY and _MATERIAL_INDEX.  POSITION is always emitted.  Leaving it nil emits
+	// everything the Geometry has, which is what you want unless you're trimming lower levels of detail down.
+	// Attributes the Geometry doesn't have are skipped, an atlas mesh always needs TEXCOORD_0, and JOINTS_0 and
+	// WEIGHTS_0 only make sense together.
+	Attributes []string
+
+	// Interleave writes POSITION, NORMAL, TEXCOORD_0, TEXCOORD_1 and COLOR_0, whichever of them are emitted, into a
+	// single buffer view, each vertex's attributes one after another, rather than into a tightly packed view each.
+	// Some GPUs fetch interleaved vertices faster.  Quantized Geometry is always tightly packed.
+	Interleave bool
+}
+
+// the vertex attributes Options.Attributes may list.
+var optionalAttributes = []string{"NORMAL", "TANGENT", "TEXCOORD_0", "TEXCOORD_1", "COLOR_0", "JOINTS_0", "WEIGHTS_0", "_VELOCITY", "_MATERIAL_INDEX"}
 
-	gltfDoc.Meshes[0].Name = filename
-	gltfDoc.Nodes[0].Name = filename
+// reports whether the named attribute should be emitted.
+func (opts Options) includes(attribute string) bool {
+	if opts.Attributes == nil || attribute == "POSITION" {
+		return true
+	}
 
-	gltfFileContents := []byte{}
-	gltfOutputFile := ""
+	for _, name := range opts.Attributes {
+		if name == attribute {
+			return true
+		}
+	}
 
-	if embeddedGltf {
-		gltfFileContents = SerializeEmbeddedGlTF(gltfDoc)
-		gltfOutputFile = filename + ".gltf"
+	return false
+}
+
+// makes sure Attributes only names attributes that exist, and doesn't leave out one the material needs.
+func (opts Options) validate() error {
+	for _, name := range opts.Attributes {
+		known := name == "POSITION"
+
+		for _, optional := range optionalAttributes {
+			known = known || name == optional
+		}
+
+		if !known {
+			return fmt.Errorf("unknown vertex attribute %q", name)
+		}
+	}
+
+	if !opts.VertexColors && !opts.includes("TEXCOORD_0") {
+		return errors.New("TEXCOORD_0 left out of a mesh that uses the texture atlas; it would have no way to sample it")
+	}
+
+	if opts.includes("JOINTS_0") != opts.includes("WEIGHTS_0") {
+		return errors.New("JOINTS_0 and WEIGHTS_0 have to be emitted together, or not at all")
+	}
+
+	return nil
+}
+
+// ErrEmptyModel is returned by OptimizeModel when the Model has no meshes, so there is nothing to write.
+var ErrEmptyModel = errors.New("model has no meshes")
+
+// OutputFormat selects the kind of file WriteGltf writes.
+type OutputFormat int
+
+const (
+	// OutputGlb writes a single binary filename.glb.
+	OutputGlb OutputFormat = iota
+
+	// OutputEmbedded writes a single filename.gltf with its buffers embedded as base64 data URIs.
+	OutputEmbedded
+
+	// OutputSeparateBin writes filename.gltf and, alongside it, filename.bin holding the buffer data, which the .gltf
+	// refers to by a relative URI.  This is the classic layout, and the most efficient text format for large models.
+	OutputSeparateBin
+)
+
+// PipelineOptions controls OptimizeModel.  The embedded Options control how the glTF is laid out, and its VertexColors
+// chooses between vertex colors and the texture atlas, as it does for optimizeModel.
+type PipelineOptions struct {
+	Options
+
+	// MaterialIndexed merges the whole Model with optimizeModelMaterialIndexed instead, which always uses vertex
+	// colors.  The welding, texture transform, quantization and KeepGeometry settings don't apply to it.  It makes one
+	// opaque triangle mesh, so Models with a hierarchy, skins, morph targets, other primitive modes or see-through
+	// materials are rejected rather than flattened.
+	MaterialIndexed bool
+
+	// WeldEpsilon is how close vertex attributes have to be for the merged vertices to be welded.  The zero value only
+	// welds vertices that are exactly the same, and a negative one doesn't weld at all.
+	WeldEpsilon float64
+
+	// TextureTransforms, Quantize and KeepGeometry are the optimizeModel settings of the same names.
+	TextureTransforms bool
+	Quantize          bool
+	KeepGeometry      bool
+}
+
+// OptimizeModel runs the Model through the whole pipeline: it checks it, merges its Geometry by material into the
+// texture atlas or vertex colors, and converts the result to glTF, with its rotations normalized.  It returns the
+// document and the PNG texture atlas it samples, which is empty with vertex colors.  The document is ready for
+// WriteGltf or WriteGLB, and the Model is left as it was.
+func OptimizeModel(model Model, opts PipelineOptions) (*GlTF, []byte, error) {
+	if err := opts.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	if err := checkModel(model); err != nil {
+		return nil, nil, err
+	}
+
+	var optimized Model
+	var atlas bytes.Buffer
+
+	if opts.MaterialIndexed {
+		if err := checkMaterialIndexed(model); err != nil {
+			return nil, nil, err
+		}
+
+		// the material colors end up in the vertex colors, so there's no texture atlas.
+		optimized, _ = optimizeModelMaterialIndexed(model)
+		opts.VertexColors = true
 	} else {
-		gltfFileContents = SerializeBinaryGlTF(gltfDoc)
-		gltfOutputFile = filename + ".glb"
+		var err error
+
+		optimized, atlas, err = optimizeModel(model, opts.VertexColors, opts.WeldEpsilon, opts.TextureTransforms, opts.Quantize, opts.KeepGeometry)
+
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	gltfDoc, err := buildGltfDoc(optimized, atlas, opts.Options)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return gltfDoc, atlas.Bytes(), nil
+}
+
+// makes sure a Model can go through optimizeModelMaterialIndexed without losing anything.  Everything is merged into
+// one plain, opaque triangle Geometry, so there's nowhere for a hierarchy, skins, morph targets, other primitive modes
+// or see-through materials to go.
+func checkMaterialIndexed(model Model) error {
+	if model.hasHierarchy() {
+		return errors.New("the material indexed strategy merges everything into one mesh, so it can't keep a hierarchy, transforms, skins or morph targets")
+	}
+
+	for i, mesh := range model.Meshes {
+		if mesh.Mode != ModeTriangles {
+			return fmt.Errorf("mesh %d: the material indexed strategy only merges triangles, not primitive mode %d", i, mesh.Mode)
+		}
+
+		if mode, _ := mesh.Material.alpha(); mode != "" {
+			return fmt.Errorf("mesh %d: the material indexed strategy's merged material is opaque, so it can't keep alpha mode %s", i, mode)
+		}
+	}
+
+	return nil
+}
+
+// makes sure a Model can be turned into valid glTF: it has to have Geometry, a sound hierarchy and valid transforms,
+// and everything that isn't just a group has to pass checkGeometry.
+func checkModel(model Model) error {
+	if len(model.Meshes) == 0 {
+		return ErrEmptyModel
 	}
 
-	gltfOutput, _ := os.Create(gltfOutputFile)
-	gltfWriter := bufio.NewWriter(gltfOutput)
+	if err := model.checkHierarchy(); err != nil {
+		return err
+	}
+
+	for i, mesh := range model.Meshes {
+		if err := checkTransform(mesh.Translation, mesh.Rotation, mesh.Scale, mesh.Matrix); err != nil {
+			return fmt.Errorf("mesh %d: %w", i, err)
+		}
+
+		if mesh.isGroup() {
+			continue
+		}
+
+		if err := checkGeometry(mesh); err != nil {
+			return fmt.Errorf("mesh %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// checks an optimized Model, converts it to glTF with the supplied Options and normalizes its rotations.
+func buildGltfDoc(model Model, atlas bytes.Buffer, opts Options) (*GlTF, error) {
+	if err := checkModel(model); err != nil {
+		return nil, err
+	}
+
+	gltfDoc := ToGltfDocWithOptions(model, atlas, opts)
+
+	if err := validateAtlasMaterials(gltfDoc, opts.VertexColors); err != nil {
+		return nil, fmt.Errorf("invalid materials: %w", err)
+	}
+
+	if err := gltfDoc.NormalizeRotations(DefaultRotationTolerance); err != nil {
+		return nil, fmt.Errorf("invalid node rotations: %w", err)
+	}
+
+	return &gltfDoc, nil
+}
+
+// WriteGltf writes the document in the supplied format, with file names based on filename, which also becomes the
+// name of its first mesh and node.  Its images are embedded or checked as the format needs, its extension lists are
+// tidied by SortExtensions, and the document is checked with Validate and ValidateReferences first, unless
+// skipValidation is true.  The document is changed in place: the separate-bin format points its buffers at the files
+// it writes, for instance.
+//
+// The same document always gives the same bytes, so the output can be used for golden files.  Everything that isn't a
+// list is either a struct, whose fields keep their order, or a map, whose keys encoding/json sorts; Attributes have the
+// fixed order their MarshalJSON describes, and the lists are in the order the document has them, which ToGltfDoc takes
+// from the Model.
+func WriteGltf(gltfDoc *GlTF, filename string, format OutputFormat, skipValidation bool) error {
+	if err := gltfDoc.EmbedImages(format); err != nil {
+		return fmt.Errorf("couldn't embed images: %w", err)
+	}
+
+	gltfDoc.SortExtensions()
+
+	if !skipValidation {
+		if err := gltfDoc.Validate(); err != nil {
+			return err
+		}
+
+		if err := gltfDoc.ValidateReferences(); err != nil {
+			return err
+		}
+	}
+
+	if len(gltfDoc.Meshes) > 0 {
+		gltfDoc.Meshes[0].Name = filename
+	}
+
+	if len(gltfDoc.Nodes) > 0 {
+		gltfDoc.Nodes[0].Name = filename
+	}
+
+	var gltfFileContents []byte
+	var gltfOutputFile string
+	var err error
+
+	switch format {
+	case OutputEmbedded:
+		gltfFileContents, err = SerializeEmbeddedGlTF(*gltfDoc)
+		gltfOutputFile = filename + ".gltf"
+	case OutputSeparateBin:
+		if err := writeSeparateBuffers(*gltfDoc, filename); err != nil {
+			return err
+		}
+
+		gltfFileContents, err = json.MarshalIndent(gltfDoc, "", "    ")
+		gltfOutputFile = filename + ".gltf"
+	default:
+		// the binary format is streamed straight into the file.
+		return createFile(filename+".glb", func(w io.Writer) error {
+			return WriteGLB(w, gltfDoc)
+		})
+	}
+
+	if err != nil {
+		return fmt.Errorf("couldn't serialize %s: %w", gltfOutputFile, err)
+	}
+
+	return writeFile(gltfOutputFile, gltfFileContents)
+}
+
+// writes each of the document's buffers to a .bin file next to filename.gltf, and points the buffer's URI at it.  The
+// first buffer is filename.bin and any others are filename_1.bin and so on.
+func writeSeparateBuffers(gltfDoc GlTF, filename string) error {
+	for i := range gltfDoc.Buffers {
+		binFile := filename + ".bin"
+
+		if i > 0 {
+			binFile = fmt.Sprintf("%s_%d.bin", filename, i)
+		}
+
+		buffer := &gltfDoc.Buffers[i]
+
+		if err := writeFile(binFile, buffer.Bytes); err != nil {
+			return err
+		}
+
+		// the URI is relative to the .gltf, which is in the same directory.
+		buffer.URI = filepath.Base(binFile)
+		buffer.ByteLength = len(buffer.Bytes)
+	}
+
+	return nil
+}
+
+// writes contents to the named file, replacing it if it exists.
+func writeFile(name string, contents []byte) error {
+	return createFile(name, func(w io.Writer) error {
+		_, err := w.Write(contents)
+		return err
+	})
+}
+
+// creates the named file, replacing it if it exists, and passes it to write through a buffered writer.
+func createFile(name string, write func(w io.Writer) error) error {
+	output, err := os.Create(name)
+
+	if err != nil {
+		return fmt.Errorf("couldn't create output file: %w", err)
+	}
+
+	writer := bufio.NewWriter(output)
+
+	if err := write(writer); err != nil {
+		output.Close()
+		return fmt.Errorf("couldn't write %s: %w", name, err)
+	}
+
+	if err := writer.Flush(); err != nil {
+		output.Close()
+		return fmt.Errorf("couldn't write %s: %w", name, err)
+	}
+
+	if err := output.Close(); err != nil {
+		return fmt.Errorf("couldn't write %s: %w", name, err)
+	}
 
-	gltfWriter.Write(gltfFileContents)
-	gltfWriter.Flush()
+	return nil
 }
 
-// SerializeBinaryGlTF renders a GlTF document to a byte slice containing a binary glTF document.
-func SerializeBinaryGlTF(gltfDoc GlTF) []byte {
-	outBuf := bytes.NewBuffer(gltfDoc.Buffers[0].Bytes)
+// SerializeBinaryGlTF renders a GlTF document to a byte slice containing a binary glTF document, as WriteGLB writes
+// it.
+func SerializeBinaryGlTF(gltfDoc GlTF) ([]byte, error) {
+	outData := new(bytes.Buffer)
+
+	if err := WriteGLB(outData, &gltfDoc); err != nil {
+		return nil, err
+	}
+
+	return outData.Bytes(), nil
+}
+
+// WriteGLB streams a GlTF document to w as a binary glTF document: the 12 byte header, the JSON chunk padded with
+// spaces, and the first buffer as the BIN chunk padded with zeros, with every chunk 4 byte aligned.  Nothing is
+// written to the filesystem, so w can be a network connection or an HTTP response.  The first write error is returned.
+func WriteGLB(w io.Writer, gltfDoc *GlTF) error {
+	// a document without buffers, like the one from NewGltf, gets no binary chunk at all.
+	hasBinaryChunk := len(gltfDoc.Buffers) > 0
+	binData := []byte{}
+
+	if hasBinaryChunk {
+		binData = gltfDoc.Buffers[0].Bytes
+	}
 
 	// get the JSON content for the binary file.
-	outJSON, _ := json.Marshal(gltfDoc)
+	outJSON, err := json.Marshal(gltfDoc)
+
+	if err != nil {
+		return fmt.Errorf("couldn't marshal json: %w", err)
+	}
+
+	// every size in the container is a uint32, including the 28 bytes of headers and up to 6 bytes of padding.
+	if uint64(len(outJSON))+uint64(len(binData))+28+6 > math.MaxUint32 {
+		return fmt.Errorf("%d bytes of json and %d bytes of binary data are too large for a GLB file", len(outJSON), len(binData))
+	}
 
 	// get the JSON size, and the number of padding spaces required.
 	outJSONSize := uint32(len(outJSON))
@@ -214,7 +826,7 @@ func SerializeBinaryGlTF(gltfDoc GlTF) []byte {
 	outJSONSize += outJSONPaddingNeeded
 
 	// get the binary mesh data buffer length and the number of padding nulls required.
-	outBufSize := uint32(outBuf.Len())
+	outBufSize := uint32(len(binData))
 
 	// calculate the number of padding null bytes that makes the size of the binary buffer modulus 4 equal to 0.
 	outBufNullsNeeded := (4 - (outBufSize & 3)) & 3
@@ -227,16 +839,19 @@ func SerializeBinaryGlTF(gltfDoc GlTF) []byte {
 	glbSize += 4           // total file size field length +
 	glbSize += 4           // json chunk type header length +
 	glbSize += 4           // json chunk declaration length +
-	glbSize += outJSONSize // json payload length +
-	glbSize += 4           // binary chunk type header length +
-	glbSize += 4           // binary chunk declaration length +
-	glbSize += outBufSize  // binary payload length.
+	glbSize += outJSONSize // json payload length.
 
-	// set up the output byte array
-	outData := new(bytes.Buffer)
+	if hasBinaryChunk {
+		glbSize += 4          // binary chunk type header length +
+		glbSize += 4          // binary chunk declaration length +
+		glbSize += outBufSize // binary payload length.
+	}
+
+	// set up the output, which remembers the first error so it only needs checking at the end.
+	outData := &stickyWriter{w: w}
 
 	// write the magic number
-	outData.WriteString("glTF")
+	io.WriteString(outData, "glTF")
 
 	// write the glTF version
 	binary.Write(outData, binary.LittleEndian, uint32(2))
@@ -248,115 +863,244 @@ func SerializeBinaryGlTF(gltfDoc GlTF) []byte {
 	binary.Write(outData, binary.LittleEndian, outJSONSize)
 
 	// write the JSON chunk header
-	outData.WriteString("JSON")
+	io.WriteString(outData, "JSON")
 
 	// write the JSON chunk itself
-	outData.WriteString(string(outJSON))
+	outData.Write(outJSON)
 
 	// pad the JSON with spaces, if required.
-	outData.WriteString(strings.Repeat(" ", int(outJSONPaddingNeeded)))
+	io.WriteString(outData, strings.Repeat(" ", int(outJSONPaddingNeeded)))
+
+	if !hasBinaryChunk {
+		return outData.err
+	}
 
 	// write the binary chunk length
 	binary.Write(outData, binary.LittleEndian, outBufSize)
 
 	// write the binary chunk type
-	outData.WriteString("BIN\x00")
+	io.WriteString(outData, "BIN\x00")
 
 	// write the binary chunk itself
-	outBuf.WriteTo(outData)
+	outData.Write(binData)
 
 	// pad with nulls if required
-	for outBufNullsNeeded > 0 {
-		outData.WriteByte(byte(0))
-		outBufNullsNeeded--
-	}
+	outData.Write(make([]byte, outBufNullsNeeded))
 
 	// done.
-	return outData.Bytes()
+	return outData.err
+}
+
+// stickyWriter passes writes through to w until one fails, then ignores the rest and keeps the error.
+type stickyWriter struct {
+	w   io.Writer
+	err error
 }
 
-// SerializeEmbeddedGlTF renders a GlTF document to a byte slice containing an embedded glTF document.
-func SerializeEmbeddedGlTF(gltfDoc GlTF) []byte {
-	outBuf := bytes.NewBuffer(gltfDoc.Buffers[0].Bytes)
+func (s *stickyWriter) Write(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	n, err := s.w.Write(p)
+	s.err = err
+
+	return n, err
+}
 
+// SerializeEmbeddedGlTF renders a GlTF document to a byte slice containing an embedded glTF document, with its first
+// buffer as a base64 data URI, as WriteGltf writes it for OutputEmbedded.
+func SerializeEmbeddedGlTF(gltfDoc GlTF) ([]byte, error) {
 	// ASCII glTF is easier for the developer of this application.
-	gltfDoc.Buffers[0].URI = "data:application/gltf-buffer;base64," + base64.StdEncoding.EncodeToString(outBuf.Bytes())
+	if len(gltfDoc.Buffers) > 0 {
+		gltfDoc.Buffers[0].URI = "data:application/gltf-buffer;base64," + base64.StdEncoding.EncodeToString(gltfDoc.Buffers[0].Bytes)
+	}
 
 	outData, err := json.MarshalIndent(gltfDoc, "", "    ")
 
-	printIf(err != nil, "couldn't marshal json.")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't marshal json: %w", err)
+	}
 
-	return outData
+	return outData, nil
 }
 
-// Appends the supplied triangle indices to the supplied bytes.Buffer.  It is up to the calling function to observe the
-// length of the buffer before and after this function is called. Returns the minimum and maximum values observed in the
-// supplied indices so they can be defined in the glTF file that uses the appended data.
-func addTriangleArrayToBuffer(outBuf *bytes.Buffer, indices []Triangle) (min, max uint32) {
-	min = uint32(100)
-	max = uint32(0)
+// WriteGltfZip writes a zip archive containing the document as a .gltf file, each of its buffers as a .bin, and the
+// texture atlas as a .png, all side by side at the root of the archive.  The image files the other images refer to,
+// such as the Materials' normal maps, are read and packed in too, as image_1.png and so on, by their index.  The URIs
+// in the .gltf are set to the names of the entries, so the archive can be extracted and the .gltf loaded directly.
+// The supplied document is not modified.  An image file that can't be read, or isn't a PNG or JPEG, is an error.
+func WriteGltfZip(model GlTF, atlas *bytes.Buffer, w io.Writer) error {
+	// copy the slices we're going to change the URIs in, so the caller's document is left alone.
+	model.Buffers = append([]GltfBuffer{}, model.Buffers...)
+	model.Images = append([]GltfImage{}, model.Images...)
 
-	for _, i := range indices {
-		binary.Write(outBuf, binary.LittleEndian, i.TriangleIndices[0])
-		binary.Write(outBuf, binary.LittleEndian, i.TriangleIndices[1])
-		binary.Write(outBuf, binary.LittleEndian, i.TriangleIndices[2])
+	zipWriter := zip.NewWriter(w)
 
-		max = uint32(math.Max(float64(max), float64(i.TriangleIndices[0])))
-		max = uint32(math.Max(float64(max), float64(i.TriangleIndices[1])))
-		max = uint32(math.Max(float64(max), float64(i.TriangleIndices[2])))
+	for i := range model.Buffers {
+		model.Buffers[i].URI = "model.bin"
 
-		min = uint32(math.Min(float64(min), float64(i.TriangleIndices[0])))
-		min = uint32(math.Min(float64(min), float64(i.TriangleIndices[1])))
-		min = uint32(math.Min(float64(min), float64(i.TriangleIndices[2])))
+		if i > 0 {
+			model.Buffers[i].URI = fmt.Sprintf("model_%d.bin", i)
+		}
+
+		if err := writeZipEntry(zipWriter, model.Buffers[i].URI, model.Buffers[i].Bytes); err != nil {
+			return err
+		}
 	}
 
-	return min, max
-}
+	// the atlas is always image 0.
+	hasAtlas := atlas != nil && atlas.Len() > 0 && len(model.Images) > 0
 
-// see the Vector3 version.
-func addVector2ArrayToBuffer(outBuf *bytes.Buffer, data *[]Vector2) (min, max Vector2) {
-	highestU := float32(-100)
-	highestV := float32(-100)
+	if hasAtlas {
+		model.Images[0].URI = "atlas.png"
 
-	lowestU := float32(100)
-	lowestV := float32(100)
+		if err := writeZipEntry(zipWriter, model.Images[0].URI, atlas.Bytes()); err != nil {
+			return err
+		}
+	}
 
-	for _, v := range *data {
-		binary.Write(outBuf, binary.LittleEndian, v.U)
-		binary.Write(outBuf, binary.LittleEndian, v.V)
+	// data URIs and buffer views are already in the .gltf or a .bin; only files need packing.
+	for i := range model.Images {
+		if (i == 0 && hasAtlas) || !model.Images[i].isFile() {
+			continue
+		}
 
-		highestU = float32(math.Max(float64(highestU), float64(v.U)))
-		highestV = float32(math.Max(float64(highestV), float64(v.V)))
+		data, mimeType, err := model.Images[i].readFile()
 
-		lowestU = float32(math.Min(float64(lowestU), float64(v.U)))
-		lowestV = float32(math.Min(float64(lowestV), float64(v.V)))
-	}
+		if err != nil {
+			return fmt.Errorf("images[%d]: %w", i, err)
+		}
 
-	min = Vector2{U: lowestU, V: lowestV}
-	max = Vector2{U: highestU, V: highestV}
+		extension := ".png"
 
-	return min, max
-}
+		if mimeType == "image/jpeg" {
+			extension = ".jpg"
+		}
 
-// Appends the supplied Vector3 slice to the supplied bytes.Buffer.  It is up to the calling function to observe the
-// length of the buffer before and after modification so that the appropriate BufferView and Accessor can be created.
-// Returns new Vector3s containing the minimum and maximum observed values for each component in the supplied Vector3
-// slice so that they can be used in the glTF file that uses the appended data.
-func addVector3ArrayToBuffer(outBuf *bytes.Buffer, data *[]Vector3) (min, max Vector3) {
-	highestX := float32(-100)
-	highestY := float32(-100)
-	highestZ := float32(-100)
+		model.Images[i].URI = fmt.Sprintf("image_%d%s", i, extension)
 
-	lowestX := float32(100)
-	lowestY := float32(100)
-	lowestZ := float32(100)
+		if err := writeZipEntry(zipWriter, model.Images[i].URI, data); err != nil {
+			return err
+		}
+	}
 
-	for _, v := range *data {
-		binary.Write(outBuf, binary.LittleEndian, v.X)
-		binary.Write(outBuf, binary.LittleEndian, v.Y)
-		binary.Write(outBuf, binary.LittleEndian, v.Z)
+	outJSON, err := json.MarshalIndent(model, "", "    ")
 
-		highestX = float32(math.Max(float64(highestX), float64(v.X)))
+	if err != nil {
+		return fmt.Errorf("couldn't marshal json: %w", err)
+	}
+
+	if err := writeZipEntry(zipWriter, "model.gltf", outJSON); err != nil {
+		return err
+	}
+
+	return zipWriter.Close()
+}
+
+func writeZipEntry(zipWriter *zip.Writer, name string, contents []byte) error {
+	entry, err := zipWriter.Create(name)
+
+	if err != nil {
+		return fmt.Errorf("couldn't create %s in zip archive: %w", name, err)
+	}
+
+	if _, err := entry.Write(contents); err != nil {
+		return fmt.Errorf("couldn't write %s to zip archive: %w", name, err)
+	}
+
+	return nil
+}
+
+// Appends the supplied triangle indices to the supplied bytes.Buffer, each one written as the supplied component type,
+// which must be UnsignedShort or UnsignedInt.  It is up to the calling function to observe the length of the buffer
+// before and after this function is called.
+func addTriangleArrayToBuffer(outBuf *bytes.Buffer, indices []Triangle, componentType ComponentType) {
+	for _, i := range indices {
+		for _, index := range i.TriangleIndices {
+			if componentType == UnsignedShort {
+				binary.Write(outBuf, binary.LittleEndian, uint16(index))
+			} else {
+				binary.Write(outBuf, binary.LittleEndian, uint32(index))
+			}
+		}
+	}
+}
+
+// returns the minimum and maximum values in the supplied indices so they can be defined in the glTF file that uses
+// them.
+func triangleIndexRange(indices []Triangle) (min, max uint32) {
+	if len(indices) == 0 {
+		return 0, 0
+	}
+
+	min = uint32(math.MaxUint32)
+	max = uint32(0)
+
+	for _, i := range indices {
+		for _, index := range i.TriangleIndices {
+			max = uint32(math.Max(float64(max), float64(index)))
+			min = uint32(math.Min(float64(min), float64(index)))
+		}
+	}
+
+	return min, max
+}
+
+// returns the smallest index component type that can hold every index up to max.  The largest value of each type is
+// reserved by the spec for primitive restart, so it can't be used as an index.
+func indexComponentType(max uint32) ComponentType {
+	if max < math.MaxUint16 {
+		return UnsignedShort
+	}
+
+	return UnsignedInt
+}
+
+// see the Vector3 version.
+func addVector2ArrayToBuffer(outBuf *bytes.Buffer, data *[]Vector2) (min, max Vector2) {
+	highestU := float32(-math.MaxFloat32)
+	highestV := float32(-math.MaxFloat32)
+
+	lowestU := float32(math.MaxFloat32)
+	lowestV := float32(math.MaxFloat32)
+
+	for _, v := range *data {
+		binary.Write(outBuf, binary.LittleEndian, v.U)
+		binary.Write(outBuf, binary.LittleEndian, v.V)
+
+		highestU = float32(math.Max(float64(highestU), float64(v.U)))
+		highestV = float32(math.Max(float64(highestV), float64(v.V)))
+
+		lowestU = float32(math.Min(float64(lowestU), float64(v.U)))
+		lowestV = float32(math.Min(float64(lowestV), float64(v.V)))
+	}
+
+	min = Vector2{U: lowestU, V: lowestV}
+	max = Vector2{U: highestU, V: highestV}
+
+	return min, max
+}
+
+// Appends the supplied Vector3 slice to the supplied bytes.Buffer.  It is up to the calling function to observe the
+// length of the buffer before and after modification so that the appropriate BufferView and Accessor can be created.
+// Returns new Vector3s containing the minimum and maximum observed values for each component in the supplied Vector3
+// slice so that they can be used in the glTF file that uses the appended data.
+func addVector3ArrayToBuffer(outBuf *bytes.Buffer, data *[]Vector3) (min, max Vector3) {
+	highestX := float32(-math.MaxFloat32)
+	highestY := float32(-math.MaxFloat32)
+	highestZ := float32(-math.MaxFloat32)
+
+	lowestX := float32(math.MaxFloat32)
+	lowestY := float32(math.MaxFloat32)
+	lowestZ := float32(math.MaxFloat32)
+
+	for _, v := range *data {
+		binary.Write(outBuf, binary.LittleEndian, v.X)
+		binary.Write(outBuf, binary.LittleEndian, v.Y)
+		binary.Write(outBuf, binary.LittleEndian, v.Z)
+
+		highestX = float32(math.Max(float64(highestX), float64(v.X)))
 		highestY = float32(math.Max(float64(highestY), float64(v.Y)))
 		highestZ = float32(math.Max(float64(highestZ), float64(v.Z)))
 
@@ -372,15 +1116,15 @@ func addVector3ArrayToBuffer(outBuf *bytes.Buffer, data *[]Vector3) (min, max Ve
 }
 
 func addVector4ArrayToBuffer(outBuf *bytes.Buffer, data *[]Vector4) (min, max Vector4) {
-	highestR := float32(-100)
-	highestG := float32(-100)
-	highestB := float32(-100)
-	highestA := float32(-100)
+	highestR := float32(-math.MaxFloat32)
+	highestG := float32(-math.MaxFloat32)
+	highestB := float32(-math.MaxFloat32)
+	highestA := float32(-math.MaxFloat32)
 
-	lowestR := float32(100)
-	lowestG := float32(100)
-	lowestB := float32(100)
-	lowestA := float32(100)
+	lowestR := float32(math.MaxFloat32)
+	lowestG := float32(math.MaxFloat32)
+	lowestB := float32(math.MaxFloat32)
+	lowestA := float32(math.MaxFloat32)
 
 	for _, v := range *data {
 		binary.Write(outBuf, binary.LittleEndian, v.R)
@@ -406,6 +1150,7 @@ func addVector4ArrayToBuffer(outBuf *bytes.Buffer, data *[]Vector4) (min, max Ve
 }
 
 func getAccessorIndexFromVector2(outBuf *bytes.Buffer, vectors []Vector2, gltfBufferViews *[]BufferView, gltfAccessors *[]Accessor) (accessorIndex int) {
+	padToAlignment(outBuf)
 	byteOffset := outBuf.Len()
 	min, max := addVector2ArrayToBuffer(outBuf, &vectors)
 	byteLength := outBuf.Len() - byteOffset
@@ -414,18 +1159,17 @@ func getAccessorIndexFromVector2(outBuf *bytes.Buffer, vectors []Vector2, gltfBu
 		Buffer:     0,
 		ByteOffset: byteOffset,
 		ByteLength: byteLength,
-		ByteStride: 8,
 		Target:     34962,
 	}
 
 	*gltfBufferViews = append(*gltfBufferViews, verticesBufferView)
 
 	verticesAccessor := Accessor{
-		BufferView:    len(*gltfBufferViews) - 1,
+		BufferView:    lastBufferView(*gltfBufferViews),
 		ByteOffset:    0,
-		ComponentType: 5126,
+		ComponentType: Float,
 		Count:         len(vectors),
-		Type:          "VEC2",
+		Type:          Vec2,
 		Max:           []float32{max.U, max.V},
 		Min:           []float32{min.U, min.V},
 	}
@@ -438,6 +1182,7 @@ func getAccessorIndexFromVector2(outBuf *bytes.Buffer, vectors []Vector2, gltfBu
 // Appends an array of Vector3 to a bytes.Buffer, then generates and adds the appropriate glTF BufferView and glTF
 // accessor to the supplied slices, then returns those new, modified slices.
 func getAccessorIndexFromVector3(outBuf *bytes.Buffer, vectors []Vector3, gltfBufferViews *[]BufferView, gltfAccessors *[]Accessor) (accessorIndex int) {
+	padToAlignment(outBuf)
 	byteOffset := outBuf.Len()
 	min, max := addVector3ArrayToBuffer(outBuf, &vectors)
 	byteLength := outBuf.Len() - byteOffset
@@ -446,18 +1191,17 @@ func getAccessorIndexFromVector3(outBuf *bytes.Buffer, vectors []Vector3, gltfBu
 		Buffer:     0,
 		ByteOffset: byteOffset,
 		ByteLength: byteLength,
-		ByteStride: 12,
 		Target:     34962,
 	}
 
 	*gltfBufferViews = append(*gltfBufferViews, verticesBufferView)
 
 	verticesAccessor := Accessor{
-		BufferView:    len(*gltfBufferViews) - 1,
+		BufferView:    lastBufferView(*gltfBufferViews),
 		ByteOffset:    0,
-		ComponentType: 5126,
+		ComponentType: Float,
 		Count:         len(vectors),
-		Type:          "VEC3",
+		Type:          Vec3,
 		Max:           []float32{max.X, max.Y, max.Z},
 		Min:           []float32{min.X, min.Y, min.Z},
 	}
@@ -468,6 +1212,7 @@ func getAccessorIndexFromVector3(outBuf *bytes.Buffer, vectors []Vector3, gltfBu
 }
 
 func getAccessorIndexFromVector4(outBuf *bytes.Buffer, vectors []Vector4, gltfBufferViews *[]BufferView, gltfAccessors *[]Accessor) (accessorIndex int) {
+	padToAlignment(outBuf)
 	byteOffset := outBuf.Len()
 	min, max := addVector4ArrayToBuffer(outBuf, &vectors)
 	byteLength := outBuf.Len() - byteOffset
@@ -476,18 +1221,17 @@ func getAccessorIndexFromVector4(outBuf *bytes.Buffer, vectors []Vector4, gltfBu
 		Buffer:     0,
 		ByteOffset: byteOffset,
 		ByteLength: byteLength,
-		ByteStride: 16,
 		Target:     34962,
 	}
 
 	*gltfBufferViews = append(*gltfBufferViews, verticesBufferView)
 
 	verticesAccessor := Accessor{
-		BufferView:    len(*gltfBufferViews) - 1,
+		BufferView:    lastBufferView(*gltfBufferViews),
 		ByteOffset:    0,
-		ComponentType: 5126,
+		ComponentType: Float,
 		Count:         len(vectors),
-		Type:          "VEC4",
+		Type:          Vec4,
 		Max:           []float32{max.R, max.G, max.B, max.A},
 		Min:           []float32{min.R, min.G, min.B, min.A},
 	}
@@ -497,11 +1241,114 @@ func getAccessorIndexFromVector4(outBuf *bytes.Buffer, vectors []Vector4, gltfBu
 	return len(*gltfAccessors) - 1
 }
 
+// one vertex attribute to be interleaved with others: the number of components each vertex has, and all of them, one
+// vertex after another.
+type interleavedAttribute struct {
+	accessorType AccessorType
+	components   int
+	data         []float32
+}
+
+// Appends the supplied attributes to the supplied bytes.Buffer interleaved, each vertex's components of every attribute
+// in turn, then adds a single BufferView for them all and an Accessor for each, and returns the accessor indices in
+// the same order.  This is the only kind of view that's given a ByteStride; tightly packed views leave it out, as the
+// spec asks.  Every component is a float, so the stride is always a multiple of 4.
+func getAccessorIndicesFromInterleaved(outBuf *bytes.Buffer, attributes []interleavedAttribute, gltfBufferViews *[]BufferView, gltfAccessors *[]Accessor) (accessorIndices []int) {
+	padToAlignment(outBuf)
+	byteOffset := outBuf.Len()
+
+	stride := 0
+	count := len(attributes[0].data) / attributes[0].components
+
+	for _, a := range attributes {
+		stride += 4 * a.components
+	}
+
+	for i := 0; i < count; i++ {
+		for _, a := range attributes {
+			binary.Write(outBuf, binary.LittleEndian, a.data[i*a.components:(i+1)*a.components])
+		}
+	}
+
+	*gltfBufferViews = append(*gltfBufferViews, BufferView{
+		Buffer:     0,
+		ByteOffset: byteOffset,
+		ByteLength: outBuf.Len() - byteOffset,
+		ByteStride: stride,
+		Target:     34962,
+	})
+
+	offset := 0
+
+	for _, a := range attributes {
+		min := append([]float32(nil), a.data[:a.components]...)
+		max := append([]float32(nil), a.data[:a.components]...)
+
+		for i, value := range a.data {
+			c := i % a.components
+			min[c] = float32(math.Min(float64(min[c]), float64(value)))
+			max[c] = float32(math.Max(float64(max[c]), float64(value)))
+		}
+
+		*gltfAccessors = append(*gltfAccessors, Accessor{
+			BufferView:    lastBufferView(*gltfBufferViews),
+			ByteOffset:    offset,
+			ComponentType: Float,
+			Count:         count,
+			Type:          a.accessorType,
+			Max:           max,
+			Min:           min,
+		})
+
+		accessorIndices = append(accessorIndices, len(*gltfAccessors)-1)
+		offset += 4 * a.components
+	}
+
+	return accessorIndices
+}
+
+// the components of the supplied vectors, one vector after another, for interleaving.
+func vector2Floats(vectors []Vector2) []float32 {
+	floats := make([]float32, 0, 2*len(vectors))
+
+	for _, v := range vectors {
+		floats = append(floats, v.U, v.V)
+	}
+
+	return floats
+}
+
+func vector3Floats(vectors []Vector3) []float32 {
+	floats := make([]float32, 0, 3*len(vectors))
+
+	for _, v := range vectors {
+		floats = append(floats, v.X, v.Y, v.Z)
+	}
+
+	return floats
+}
+
+func vector4Floats(vectors []Vector4) []float32 {
+	floats := make([]float32, 0, 4*len(vectors))
+
+	for _, v := range vectors {
+		floats = append(floats, v.R, v.G, v.B, v.A)
+	}
+
+	return floats
+}
+
 // Appends an array of triangle indices to the supplied bytes.Buffer, then generates and adds the appropriate glTF
-// BufferView and glTF Accessor to the supplied slices, then returns the new, modified slices.
+// BufferView and glTF Accessor to the supplied slices, then returns the new, modified slices.  Indices are written as
+// 16 bit values when they all fit, which halves their size for any mesh of fewer than 65535 vertices, and 32 bit values
+// otherwise.
 func getAccessorIndexFromIndices(outBuf *bytes.Buffer, indices []Triangle, gltfBufferViews *[]BufferView, gltfAccessors *[]Accessor) (accessorIndex int) {
+	min, max := triangleIndexRange(indices)
+	componentType := indexComponentType(max)
+
+	padToAlignment(outBuf)
 	byteOffset := outBuf.Len()
-	min, max := addTriangleArrayToBuffer(outBuf, indices)
+	addTriangleArrayToBuffer(outBuf, indices, componentType)
 	byteLength := outBuf.Len() - byteOffset
 
 	indicesBufferView := BufferView{
@@ -514,11 +1361,11 @@ func getAccessorIndexFromIndices(outBuf *bytes.Buffer, indices []Triangle, gltfB
 	*gltfBufferViews = append(*gltfBufferViews, indicesBufferView)
 
 	indicesAccessor := Accessor{
-		BufferView:    len(*gltfBufferViews) - 1,
+		BufferView:    lastBufferView(*gltfBufferViews),
 		ByteOffset:    0,
-		ComponentType: 5125,
+		ComponentType: componentType,
 		Count:         len(indices) * 3,
-		Type:          "SCALAR",
+		Type:          Scalar,
 		Max:           []float32{float32(max)},
 		Min:           []float32{float32(min)},
 	}
@@ -545,304 +1392,1253 @@ func addMaterial(material GltfMaterial, gltfMaterials []GltfMaterial) (materialI
 	return len(newMaterials) - 1, newMaterials
 }
 
+// returns the index of the texture showing the image at the supplied URI with the supplied sampler, which may be nil,
+// adding the image and texture if there isn't one already.  An image used with two samplers is only added once.
+func addImageTexture(uri string, sampler *int, gltfImages *[]GltfImage, gltfTextures *[]GltfTexture) (textureIndex int) {
+	imageIndex := -1
+
+	for i, img := range *gltfImages {
+		if img.URI == uri {
+			imageIndex = i
+			break
+		}
+	}
+
+	if imageIndex < 0 {
+		*gltfImages = append(*gltfImages, GltfImage{URI: uri})
+		imageIndex = len(*gltfImages) - 1
+	}
+
+	for i, texture := range *gltfTextures {
+		if jsonNumber(texture.Source) == imageIndex && reflect.DeepEqual(texture.Sampler, sampler) {
+			return i
+		}
+	}
+
+	*gltfTextures = append(*gltfTextures, GltfTexture{Sampler: sampler, Source: imageIndex})
+
+	return len(*gltfTextures) - 1
+}
+
 // compare materials for equality.  This should probably instead return -1, 0, or 1 so they can be sorted by hue, then
 // opacity.  I don't know if I would ever need to sort materials, though.
 func areMaterialsEqual(a GltfMaterial, b GltfMaterial) bool {
-	sameR := a.PbrMetallicRoughness.BaseColorFactor[0] == b.PbrMetallicRoughness.BaseColorFactor[0]
-	sameG := a.PbrMetallicRoughness.BaseColorFactor[1] == b.PbrMetallicRoughness.BaseColorFactor[1]
-	sameB := a.PbrMetallicRoughness.BaseColorFactor[2] == b.PbrMetallicRoughness.BaseColorFactor[2]
-	sameA := a.PbrMetallicRoughness.BaseColorFactor[3] == b.PbrMetallicRoughness.BaseColorFactor[3]
+	// compared whole, since a factor left out for the default is nil.
+	sameFactor := reflect.DeepEqual(a.PbrMetallicRoughness.BaseColorFactor, b.PbrMetallicRoughness.BaseColorFactor)
 
 	sameMe := a.PbrMetallicRoughness.MetallicFactor == b.PbrMetallicRoughness.MetallicFactor
 	sameRo := a.PbrMetallicRoughness.RoughnessFactor == b.PbrMetallicRoughness.RoughnessFactor
 
-	return sameR && sameG && sameB && sameA && sameMe && sameRo
+	// a transparent material can't share with an opaque one, even when the factors match.
+	sameMode := a.AlphaMode == b.AlphaMode && a.AlphaCutoff == b.AlphaCutoff
+
+	// the texture infos are pointers, so they have to be compared by what they point at.
+	sameTextures := reflect.DeepEqual(a.NormalTexture, b.NormalTexture) &&
+		reflect.DeepEqual(a.OcclusionTexture, b.OcclusionTexture) &&
+		reflect.DeepEqual(a.EmissiveTexture, b.EmissiveTexture) &&
+		reflect.DeepEqual(a.EmissiveFactor, b.EmissiveFactor)
+
+	sameExtensions := reflect.DeepEqual(a.Extensions, b.Extensions) &&
+		reflect.DeepEqual(a.PbrMetallicRoughness.BaseColorTexture, b.PbrMetallicRoughness.BaseColorTexture)
+
+	return sameFactor && sameMe && sameRo && sameMode && a.DoubleSided == b.DoubleSided && sameTextures && sameExtensions
 }
 
 // TODO: support more material and appearance features, despite their apparent lack of use by our models.
 // This creates a new gltfMaterial based on the properties in the supplied Material.
 func gltfMaterial(material Material) GltfMaterial {
 	outMaterial := GltfMaterial{
-		DoubleSided: true,
+		DoubleSided:    material.DoubleSided,
+		EmissiveFactor: material.emissiveFactor(),
 		PbrMetallicRoughness: MaterialPbrMetallicRoughness{
-			BaseColorFactor: []float64{
-				float64(material.DiffuseColor[0]),
-				float64(material.DiffuseColor[1]),
-				float64(material.DiffuseColor[2]),
-				float64(material.Opacity),
-			},
-			MetallicFactor:  0.0,
-			RoughnessFactor: 1.0 - (float64(material.SpecularPower) / 128.0),
+			BaseColorFactor: baseColorFactor(material.DiffuseColor, material.Opacity),
+			MetallicFactor:  float64(material.MetallicFactor),
+			RoughnessFactor: material.roughness(),
 		},
 	}
 
-	if material.Opacity < 1 {
-		outMaterial.AlphaMode = "BLEND"
+	if mode, cutoff := material.alpha(); mode != "" {
+		outMaterial.AlphaMode = mode
+		outMaterial.AlphaCutoff = cutoff
+	}
+
+	extensions := map[string]interface{}{}
+
+	if material.Unlit {
+		extensions[unlitExtensionName] = unlitExtension{}
+	}
+
+	// a strength of 1 is the default, so it needs no extension.
+	if material.EmissiveStrength != 0 && material.EmissiveStrength != 1 {
+		extensions[emissiveStrengthExtensionName] = emissiveStrengthExtension{EmissiveStrength: float64(material.EmissiveStrength)}
+	}
+
+	if len(extensions) > 0 {
+		outMaterial.Extensions = extensions
 	}
 
 	return outMaterial
 }
 
+// defaultRoughness is the roughness of a Material that sets neither RoughnessFactor nor SpecularPower: halfway, so
+// untextured meshes look like painted plastic rather than a mirror or chalk.
+const defaultRoughness = 0.5
+
+// returns the Material's RoughnessFactor, or one worked out from its SpecularPower if it has no RoughnessFactor, or
+// defaultRoughness if it has neither.
+func (material Material) roughness() float64 {
+	switch {
+	case material.RoughnessFactor != 0:
+		return float64(material.RoughnessFactor)
+	case material.SpecularPower != 0:
+		// clamped, because a SpecularPower above 128 would otherwise make it negative.
+		return math.Max(0, math.Min(1, 1.0-(float64(material.SpecularPower)/128.0)))
+	default:
+		return defaultRoughness
+	}
+}
+
+// returns the base color factor for the supplied color and alpha, or nil if it's the spec default of all ones, which
+// doesn't need writing.
+func baseColorFactor(color [3]float32, alpha float32) []float64 {
+	factor := []float64{float64(color[0]), float64(color[1]), float64(color[2]), float64(alpha)}
+
+	for _, c := range factor {
+		if c != 1 {
+			return factor
+		}
+	}
+
+	return nil
+}
+
+// atlasSize is the width and height, in pixels, of the generated texture atlas.  Each material gets one pixel, so this
+// allows for atlasSize * atlasSize materials.  Keep it a power of two so viewers can mipmap the atlas properly.
+const atlasSize = 32
+
 // TODO: rename this to 'applyMaterialStrategy' probably since that's what it does.
-func optimizeModel(meshes Model, vertexColors bool) (Model, bytes.Buffer) {
-	// set up the fully merged Geometry structure.
-	finalVertices := []Vertex{}
-	finalFaces := []Triangle{}
+//
+// optimizeModel merges all the Geometry that shares a material into one Geometry per unique material, so each becomes
+// a single primitive with its own glTF material.  Materials are the same if they have the same DiffuseColor, Opacity
+// and TexturePath; the first Geometry with a material supplies the rest of its properties.  The colors themselves go
+// into the texture atlas (one pixel per unique material) or the vertex colors, so the glTF materials only differ in
+// what's left over, such as opacity and roughness, and identical ones are shared by ToGltfDoc.
+//
+// With textureTransforms set, an atlas material samples its part of the atlas through a KHR_texture_transform, stored
+// in its AtlasTransform, and the vertex UVs are left in [0,1] rather than remapped.  Solid colors have no UVs of their
+// own worth keeping, so they're all set to the middle of the material's pixel.  Viewers without the extension can't
+// show the result, so it's listed as required; the remapped UVs are the default for that reason.
+//
+// The merged Geometry then has its vertices welded by WeldVertices, to weldEpsilon.  A negative weldEpsilon leaves
+// every vertex as it was, for callers that need exactly the per-face data they supplied.
+//
+// With quantize set, the merged Geometry is given a Quantization, so ToGltfDoc writes it with KHR_mesh_quantization and
+// puts the transform that undoes it on the mesh's node.  That's listed as required as well.  Skinned Geometry is left
+// alone, since its node's transform doesn't apply to it.
+//
+// Geometry whose normals are all zero, because whatever built it had none, is given smooth ones by GenerateNormals
+// first, and Geometry with a normal map but no tangents is given them as GenerateTangents does, from whichever UVs the
+// normal map is sampled with.
+//
+// With keepGeometry set, nothing is merged: each Geometry stays a primitive of its own, in order, though those with
+// the same material still share a glTF material.  That costs a draw call per Geometry, but keeps them apart for
+// callers that need to find them again.
+func optimizeModel(meshes Model, vertexColors bool, weldEpsilon float64, textureTransforms bool, quantize bool, keepGeometry bool) (Model, bytes.Buffer, error) {
 	imageData := new(bytes.Buffer)
 
 	if !vertexColors {
-		// the texture atlas case.
+		if err := checkAtlasMapUVs(meshes); err != nil {
+			return Model{}, bytes.Buffer{}, err
+		}
+	}
 
-		// set up the texture atlas and populate it as you go through the Geometry objects.
-		img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	meshes = generateMissingTangents(generateMissingNormals(meshes), !vertexColors)
 
-		for i, mesh := range meshes.Meshes {
-			vertexOffset := int32(len(finalVertices))
+	if !vertexColors && hasTexturePaths(meshes) {
+		// texture files need a packed atlas rather than one pixel per material.
+		model, atlas, err := optimizeModelTextured(meshes, textureTransforms, keepGeometry)
 
-			//* color correction: scale all colors from 0-1 to 0.04-0.85 because gltf uses Physically Based Rendering.
-			//* https://seblagarde.wordpress.com/2011/08/17/feeding-a-physical-based-lighting-mode/
-			// TODO: scale all colors by the same amount; just enough to bring the brightest and darkest colors into range.
-			dR := mapRange(float64(mesh.Material.DiffuseColor[0]), 0.0, 1.0, 0.04, 0.85)
-			dG := mapRange(float64(mesh.Material.DiffuseColor[1]), 0.0, 1.0, 0.04, 0.85)
-			dB := mapRange(float64(mesh.Material.DiffuseColor[2]), 0.0, 1.0, 0.04, 0.85)
+		if err != nil {
+			return Model{}, bytes.Buffer{}, err
+		}
+
+		return quantizeModel(weldModel(model, weldEpsilon), quantize), atlas, nil
+	}
+
+	groups := groupByMaterial(meshes, keepGeometry)
+	// a hierarchy keeps a group per Geometry, in order, so the scenes' indices still hold.
+	merged := Model{Meshes: make([]Geometry, 0, len(groups)), Scenes: meshes.Scenes, DefaultScene: meshes.DefaultScene}
 
-			r := uint8(dR * 255)
-			g := uint8(dG * 255)
-			b := uint8(dB * 255)
-			a := uint8(mesh.Material.Opacity * 255)
+	if !vertexColors {
+		// the texture atlas case.
 
-			x := i % 32
-			y := i / 32
+		// set up the texture atlas and populate it as you go through the materials.
+		img := image.NewRGBA(image.Rect(0, 0, atlasSize, atlasSize))
+
+		for i, group := range groups {
+			x := i % atlasSize
+			y := i / atlasSize
 
 			// set the pixel on the texture atlas
-			color := color.RGBA{r, g, b, a}
-			img.Set(x, y, color)
+			img.Set(x, y, solidColor(group.material))
 
 			// add a reference to this pixel for all the vertices that use this color.
-			for _, vertex := range mesh.Vertices {
-				vertex.UV = Vector2{
-					U: (float32(x) / 32.0) + (0.5 / 32.0),
-					V: (float32(y) / 32.0) + (0.5 / 32.0),
-				}
+			uv := Vector2{
+				U: (float32(x) / atlasSize) + (0.5 / atlasSize),
+				V: (float32(y) / atlasSize) + (0.5 / atlasSize),
+			}
 
-				finalVertices = append(finalVertices, vertex)
+			// with a transform, the middle of [0,1] lands on the middle of the pixel instead.
+			if textureTransforms {
+				uv = Vector2{U: 0.5, V: 0.5}
 			}
 
-			// add the triangles to the new monolithic mesh, using the new indices.
-			for _, triangle := range mesh.Faces {
-				f := Triangle{
-					TriangleIndices: [3]int32{
-						triangle.TriangleIndices[0] + vertexOffset,
-						triangle.TriangleIndices[1] + vertexOffset,
-						triangle.TriangleIndices[2] + vertexOffset,
-					},
-				}
-
-				finalFaces = append(finalFaces, f)
+			geo := mergeGeometry(group, func(vertex Vertex) Vertex {
+				vertex.UV = uv
+				return vertex
+			})
+
+			if textureTransforms {
+				geo.Material.AtlasTransform = atlasTileTransform(x, y, 1, 1, atlasSize, atlasSize)
 			}
+
+			merged.Meshes = append(merged.Meshes, geo)
 		}
 
+		// PNG only stores a single level, so viewers build the mip chain themselves.  That works poorly (or not at all
+		// on older WebGL) when the dimensions aren't powers of two, so let the user know.
+		logIf(!isPowerOfTwo(img.Bounds().Dx()) || !isPowerOfTwo(img.Bounds().Dy()),
+			"texture atlas dimensions are not a power of two; mipmapping will be suboptimal.")
+
 		// finally, save out the texture atlas.  we're saving to a bytes.Buffer in this case, not a file.
 		png.Encode(imageData, img)
 	} else {
 		// The vertex color case.
-		for _, mesh := range meshes.Meshes {
-			vertexOffset := int32(len(finalVertices))
-
-			for _, vertex := range mesh.Vertices {
+		for _, group := range groups {
+			remap := func(vertex Vertex) Vertex {
 				vertex.Color.R = float32(mapRange(float64(vertex.Color.R), 0.0, 1.0, 0.04, 0.85))
 				vertex.Color.G = float32(mapRange(float64(vertex.Color.G), 0.0, 1.0, 0.04, 0.85))
 				vertex.Color.B = float32(mapRange(float64(vertex.Color.B), 0.0, 1.0, 0.04, 0.85))
 
-				finalVertices = append(finalVertices, vertex)
+				return vertex
 			}
 
-			for _, triangle := range mesh.Faces {
-				f := Triangle{
-					TriangleIndices: [3]int32{
-						triangle.TriangleIndices[0] + vertexOffset,
-						triangle.TriangleIndices[1] + vertexOffset,
-						triangle.TriangleIndices[2] + vertexOffset,
-					},
-				}
-
-				finalFaces = append(finalFaces, f)
+			// remapping would lift colors that are all zero off zero, so ToGltfDoc couldn't tell the Geometry has none
+			// and give its material's color a base color factor instead.
+			if !group.hasVertexColors() {
+				remap = func(vertex Vertex) Vertex { return vertex }
 			}
-		}
-	}
 
-	// create a new Model using everything we just created above.
-	meshes = Model{
-		Meshes: []Geometry{
-			Geometry{
-				Vertices: finalVertices,
-				Faces:    finalFaces,
-				Material: Material{
-					AmbientColor:  [3]float32{1.0, 1.0, 1.0},
-					DiffuseColor:  [3]float32{1.0, 1.0, 1.0},
-					SpecularColor: [3]float32{1.0, 1.0, 1.0},
-					SpecularPower: 128,
-					EmissiveColor: [3]float32{1.0, 1.0, 1.0},
-					Opacity:       1.0,
-				},
-			},
-		},
+			merged.Meshes = append(merged.Meshes, mergeGeometry(group, remap))
+		}
 	}
 
 	// return it.
-	return meshes, *imageData
+	return quantizeModel(weldModel(merged, weldEpsilon), quantize), *imageData, nil
 }
 
-// ToGltfDoc converts a model to a GlTF object, ready for serialization.
-func ToGltfDoc(model Model, atlas bytes.Buffer, vertexColors bool) GlTF {
-	gltfBufferViews := []BufferView{}
-	gltfAccessors := []Accessor{}
-	gltfBuffers := []GltfBuffer{}
-	gltfMeshes := []Mesh{}
-	gltfNodes := []Node{}
-	gltfScenes := []Scene{}
-	gltfMaterials := []GltfMaterial{}
-
-	outBuf := new(bytes.Buffer)
+// makes sure every Geometry with a normal, occlusion or emissive map has the second UV set to sample it with, since the
+// texture atlas remaps the first.
+func checkAtlasMapUVs(model Model) error {
+	for i, geo := range model.Meshes {
+		if !geo.isGroup() && geo.Material.hasMaps() && !hasUV2Coords(geo) {
+			return fmt.Errorf("mesh %d has a normal, occlusion or emissive map but no Vertex.UV2; with the texture atlas, "+
+				"Vertex.UV is remapped into the atlas, so the maps can only be sampled with UV2", i)
+		}
+	}
 
-	associations := []meshInfoAssociation{}
+	return nil
+}
 
-	for _, mesh := range model.Meshes {
-		thisMaterial := gltfMaterial(mesh.Material)
+// reports whether the Material has a normal, occlusion or emissive map, which sample the mesh's own UVs.
+func (material Material) hasMaps() bool {
+	return material.NormalMapPath != "" || material.OcclusionMapPath != "" || material.EmissiveMapPath != ""
+}
 
-		uvAccessorIndex := -1
-		vertexColorAccessorIndex := -1
+// welds the vertices of each of the Model's Geometry in place, unless epsilon is negative.  It's done after merging,
+// once the atlas UVs and vertex colors are final, so only vertices that really end up the same are welded.
+func weldModel(model Model, epsilon float64) Model {
+	if epsilon < 0 {
+		return model
+	}
 
-		meshIndicesAccessorIndex := getAccessorIndexFromIndices(outBuf, mesh.Faces, &gltfBufferViews, &gltfAccessors)
-		meshVertexAccessorIndex := getAccessorIndexFromVector3(outBuf, getVertices(mesh), &gltfBufferViews, &gltfAccessors)
-		meshNormalAccessorIndex := getAccessorIndexFromVector3(outBuf, getNormals(mesh), &gltfBufferViews, &gltfAccessors)
+	for i, geo := range model.Meshes {
+		model.Meshes[i] = WeldVertices(geo, epsilon)
+	}
 
-		if !vertexColors {
-			uvAccessorIndex = getAccessorIndexFromVector2(outBuf, getUVCoords(mesh), &gltfBufferViews, &gltfAccessors)
-			baseColorTexture := make(map[string]int)
-			baseColorTexture["index"] = 0
+	return model
+}
 
-			thisMaterial.PbrMetallicRoughness.BaseColorTexture = baseColorTexture
-		} else {
-			vertexColorAccessorIndex = getAccessorIndexFromVector4(outBuf, getVertexColors(mesh), &gltfBufferViews, &gltfAccessors)
+// the Geometry that uses one unique material.
+type materialGroup struct {
+	material  Material
+	meshes    []Geometry
+	firstMesh int // the index of the first Geometry in the Model with this material, for error messages.
+}
 
-			thisMaterial.PbrMetallicRoughness.BaseColorTexture = nil
+// reports whether any of the group's Geometry has vertex colors.
+func (group materialGroup) hasVertexColors() bool {
+	for _, geo := range group.meshes {
+		if hasVertexColors(geo) {
+			return true
 		}
+	}
+
+	return false
+}
 
-		thisMaterial.PbrMetallicRoughness.BaseColorFactor = []float64{1.0, 1.0, 1.0, 1.0}
+// groups the Model's Geometry by material, in the order each material first appears, or, with keepGeometry set, puts
+// each Geometry in a group of its own.
+func groupByMaterial(meshes Model, keepGeometry bool) []materialGroup {
+	type materialKey struct {
+		diffuse       [3]float32
+		opacity       float32
+		texturePath   string
+		normalMap     string
+		occlusion     string
+		emissive      string
+		unlit         bool
+		sampler       Sampler
+		metallic      float32
+		roughness     float32
+		emission      [3]float32
+		emissiveColor [3]float32 // EmissiveColor, which an emissive map falls back to when there's no EmissiveFactor.
+		specular      float32    // SpecularPower, which roughness falls back to.
+		strength      float32
+		doubleSided   bool
+		alphaMode     AlphaMode
+		alphaCutoff   float32
+		mode          PrimitiveMode
+		mesh          int
+	}
 
-		materialIndex, newGltfMaterials := addMaterial(thisMaterial, gltfMaterials)
+	groups := []materialGroup{}
+	groupIndex := make(map[materialKey]int)
 
-		gltfMaterials = newGltfMaterials
+	// Geometry in a hierarchy can't be merged, because each has its own node, so every one is a group of its own.
+	hierarchical := meshes.hasHierarchy()
 
-		accessorAssociation := meshInfoAssociation{
-			MeshIndicesAccessorIndex:  meshIndicesAccessorIndex,
-			MeshMaterialIndex:         materialIndex,
-			MeshNormalsAccessorIndex:  meshNormalAccessorIndex,
-			MeshVerticesAccessorIndex: meshVertexAccessorIndex,
-		}
+	for m, mesh := range meshes.Meshes {
+		sampler, _ := mesh.Material.sampler()
+		key := materialKey{mesh.Material.DiffuseColor, mesh.Material.Opacity, mesh.Material.TexturePath, mesh.Material.NormalMapPath, mesh.Material.OcclusionMapPath, mesh.Material.EmissiveMapPath, mesh.Material.Unlit, sampler, mesh.Material.MetallicFactor, mesh.Material.RoughnessFactor, mesh.Material.EmissiveFactor, mesh.Material.EmissiveColor, mesh.Material.SpecularPower, mesh.Material.EmissiveStrength, mesh.Material.DoubleSided, mesh.Material.AlphaMode, mesh.Material.AlphaCutoff, mesh.Mode, -1}
 
-		if !vertexColors {
-			accessorAssociation.MeshUVAccessorIndex = uvAccessorIndex
-		} else {
-			accessorAssociation.MeshVertexColorAccessorIndex = vertexColorAccessorIndex
+		// strips, loops and fans can't be joined end to end, so they're never merged either.
+		if hierarchical || keepGeometry || mesh.Mode.isOrdered() {
+			key.mesh = m
 		}
 
-		associations = append(associations, accessorAssociation)
+		i, ok := groupIndex[key]
 
-		// make sure we're aligned properly.
-		if outBuf.Len()%4 != 0 {
-			fmt.Println(" this should't happen. ")
+		if !ok {
+			i = len(groups)
+			groupIndex[key] = i
+			groups = append(groups, materialGroup{material: mesh.Material, firstMesh: m})
 		}
+
+		groups[i].meshes = append(groups[i].meshes, mesh)
 	}
 
-	nodeList := []int{}
+	return groups
+}
 
-	meshPrimitives := []MeshPrimitive{}
+// merges a group's Geometry into one, passing every vertex through the supplied function on the way.
+func mergeGeometry(group materialGroup, vertexFunc func(Vertex) Vertex) Geometry {
+	merged := Geometry{Material: group.material, Mode: group.meshes[0].Mode}
+
+	// a group of one may be part of a hierarchy, which has to survive the merge.
+	if len(group.meshes) == 1 {
+		merged.Children = group.meshes[0].Children
+		merged.Translation = group.meshes[0].Translation
+		merged.Rotation = group.meshes[0].Rotation
+		merged.Scale = group.meshes[0].Scale
+		merged.Matrix = group.meshes[0].Matrix
+		merged.Skin = group.meshes[0].Skin
+		merged.MorphWeights = group.meshes[0].MorphWeights
+	}
 
-	for _, assoc := range associations {
-		meshPrimitiveAttributes := make(map[string]int)
-		meshPrimitiveAttributes["POSITION"] = assoc.MeshVerticesAccessorIndex
-		meshPrimitiveAttributes["NORMAL"] = assoc.MeshNormalsAccessorIndex
+	for _, mesh := range group.meshes {
+		vertexOffset := int32(len(merged.Vertices))
 
-		if !vertexColors {
-			meshPrimitiveAttributes["TEXCOORD_0"] = assoc.MeshUVAccessorIndex
-		} else {
-			meshPrimitiveAttributes["COLOR_0"] = assoc.MeshVertexColorAccessorIndex
+		for _, vertex := range mesh.Vertices {
+			merged.Vertices = append(merged.Vertices, vertexFunc(vertex))
 		}
 
-		mp := MeshPrimitive{
-			Attributes: meshPrimitiveAttributes,
-			Indices:    assoc.MeshIndicesAccessorIndex,
-			Material:   assoc.MeshMaterialIndex,
+		// add the triangles to the merged mesh, using the new indices.
+		for _, triangle := range mesh.Faces {
+			merged.Faces = append(merged.Faces, Triangle{
+				TriangleIndices: [3]int32{
+					triangle.TriangleIndices[0] + vertexOffset,
+					triangle.TriangleIndices[1] + vertexOffset,
+					triangle.TriangleIndices[2] + vertexOffset,
+				},
+			})
 		}
 
-		meshPrimitives = append(meshPrimitives, mp)
-	}
-
-	gltfMeshes = append(gltfMeshes, Mesh{Primitives: meshPrimitives})
-	gltfNodes = append(gltfNodes, Node{Mesh: len(gltfMeshes) - 1})
-	nodeList = append(nodeList, len(gltfNodes)-1)
-	scene := Scene{Nodes: nodeList}
-	gltfScenes = append(gltfScenes, scene)
-	rootSceneIndex := len(gltfScenes) - 1
-
-	gltfBuffer := GltfBuffer{ByteLength: outBuf.Len()}
-	gltfBuffer.Bytes = outBuf.Bytes()
-
-	gltfBuffers = append(gltfBuffers, gltfBuffer)
+		// a line Geometry that draws the edges of its faces has to keep doing so once it's been merged.
+		edges := mesh.Edges
 
-	gltfDoc := GlTF{
-		Accessors: gltfAccessors,
-		Asset: Asset{
-			Version:   "2.0",
-			Generator: "gltf-go, https://github.com/naikrovek/gltf-go/", // feel free to change this; i don't mind.
-		},
-		Buffers:     gltfBuffers,
-		BufferViews: gltfBufferViews,
-		Materials:   gltfMaterials,
-		Meshes:      gltfMeshes,
-		Nodes:       gltfNodes,
-		Scene:       rootSceneIndex,
-		Scenes:      gltfScenes,
-	}
+		if mesh.Mode == ModeLines && len(edges) == 0 {
+			edges = faceEdges(mesh.Faces)
+		}
 
-	if !vertexColors {
-		gltfDoc.Images = []GltfImage{GltfImage{URI: "data:image/png;base64," + base64.StdEncoding.EncodeToString(atlas.Bytes())}}
-		gltfDoc.Textures = []GltfTexture{GltfTexture{Source: 0}}
+		for _, edge := range edges {
+			merged.Edges = append(merged.Edges, [2]int32{edge[0] + vertexOffset, edge[1] + vertexOffset})
+		}
 	}
 
-	return gltfDoc
+	return merged
 }
 
-func getVertices(mesh Geometry) []Vector3 {
-	results := []Vector3{}
+// returns the vertex index pairs a ModeLines Geometry draws.
+func meshLines(mesh Geometry) [][2]uint32 {
+	edges := mesh.Edges
 
-	for _, m := range mesh.Vertices {
-		results = append(results, m.Position)
+	if len(edges) == 0 {
+		edges = faceEdges(mesh.Faces)
 	}
 
-	return results
-}
-
-func getNormals(mesh Geometry) []Vector3 {
-	results := []Vector3{}
+	lines := make([][2]uint32, len(edges))
 
-	for _, m := range mesh.Vertices {
-		results = append(results, m.Normal)
+	for i, edge := range edges {
+		lines[i] = [2]uint32{uint32(edge[0]), uint32(edge[1])}
 	}
 
-	return results
+	return lines
 }
 
-func getUVCoords(mesh Geometry) []Vector2 {
-	results := []Vector2{}
+// returns each edge of the supplied triangles once, in the order they're first found.
+func faceEdges(faces []Triangle) [][2]int32 {
+	seen := make(map[[2]int32]bool)
+	edges := [][2]int32{}
 
-	for _, m := range mesh.Vertices {
-		results = append(results, m.UV)
+	for _, f := range faces {
+		for c := 0; c < 3; c++ {
+			a, b := f.TriangleIndices[c], f.TriangleIndices[(c+1)%3]
+
+			if a > b {
+				a, b = b, a
+			}
+
+			if !seen[[2]int32{a, b}] {
+				seen[[2]int32{a, b}] = true
+				edges = append(edges, [2]int32{a, b})
+			}
+		}
 	}
 
-	return results
+	return edges
+}
+
+// optimizeModelMaterialIndexed is an alternative to both the texture atlas and the plain vertex color strategies for
+// models with very many materials.  Like optimizeModel, it merges every Geometry into one, so the whole model is a
+// single draw call.  Each vertex gets its material's diffuse color and opacity as its vertex color, and the index of its
+// material in the returned palette as its MaterialIndex, which is exported as the custom _MATERIAL_INDEX attribute.
+//
+// Use ToGltfDoc with vertexColors set to true on the result.  Standard viewers will show the base colors, but making
+// use of anything else about the materials (the _MATERIAL_INDEX attribute and the palette) requires a custom shader.
+func optimizeModelMaterialIndexed(meshes Model) (Model, []Material) {
+	meshes = generateMissingTangents(generateMissingNormals(meshes), false)
+	finalVertices := []Vertex{}
+	finalFaces := []Triangle{}
+	palette := []Material{}
+
+	for _, mesh := range meshes.Meshes {
+		vertexOffset := int32(len(finalVertices))
+
+		// identical materials share a palette entry.
+		materialIndex := -1
+
+		for i, m := range palette {
+			if m == mesh.Material {
+				materialIndex = i
+				break
+			}
+		}
+
+		if materialIndex == -1 {
+			palette = append(palette, mesh.Material)
+			materialIndex = len(palette) - 1
+		}
+
+		// same color correction as the other strategies.
+		color := Vector4{
+			R: float32(mapRange(float64(mesh.Material.DiffuseColor[0]), 0.0, 1.0, 0.04, 0.85)),
+			G: float32(mapRange(float64(mesh.Material.DiffuseColor[1]), 0.0, 1.0, 0.04, 0.85)),
+			B: float32(mapRange(float64(mesh.Material.DiffuseColor[2]), 0.0, 1.0, 0.04, 0.85)),
+			A: mesh.Material.Opacity,
+		}
+
+		for _, vertex := range mesh.Vertices {
+			vertex.Color = color
+			vertex.MaterialIndex = uint16(materialIndex)
+
+			finalVertices = append(finalVertices, vertex)
+		}
+
+		for _, triangle := range mesh.Faces {
+			f := Triangle{
+				TriangleIndices: [3]int32{
+					triangle.TriangleIndices[0] + vertexOffset,
+					triangle.TriangleIndices[1] + vertexOffset,
+					triangle.TriangleIndices[2] + vertexOffset,
+				},
+			}
+
+			finalFaces = append(finalFaces, f)
+		}
+	}
+
+	meshes = Model{
+		Meshes: []Geometry{
+			Geometry{
+				Vertices: finalVertices,
+				Faces:    finalFaces,
+				Material: Material{
+					AmbientColor:  [3]float32{1.0, 1.0, 1.0},
+					DiffuseColor:  [3]float32{1.0, 1.0, 1.0},
+					SpecularColor: [3]float32{1.0, 1.0, 1.0},
+					SpecularPower: 128,
+					EmissiveColor: [3]float32{1.0, 1.0, 1.0},
+					Opacity:       1.0,
+					DoubleSided:   true,
+				},
+			},
+		},
+	}
+
+	return meshes, palette
+}
+
+// NewGltf returns the smallest valid glTF document: an asset with version 2.0 and a single empty scene, which is the
+// default.  It's the starting point for building a document piece by piece with AddGeometry, AddInstances and the
+// like, which add their nodes to the default scene.
+func NewGltf() *GlTF {
+	return &GlTF{
+		Asset: Asset{
+			Version:   "2.0",
+			Generator: "gltf-go, https://github.com/naikrovek/gltf-go/",
+		},
+		Scene:  new(int),
+		Scenes: []Scene{Scene{}},
+	}
+}
+
+// defaultScene returns the index of the document's default scene, and whether it has one that exists.
+func (gltfDoc GlTF) defaultScene() (sceneIndex int, ok bool) {
+	if gltfDoc.Scene == nil || *gltfDoc.Scene < 0 || *gltfDoc.Scene >= len(gltfDoc.Scenes) {
+		return -1, false
+	}
+
+	return *gltfDoc.Scene, true
+}
+
+// ToGltfDoc converts a model to a GlTF object, ready for serialization.
+func ToGltfDoc(model Model, atlas bytes.Buffer, vertexColors bool) GlTF {
+	return ToGltfDocWithOptions(model, atlas, Options{VertexColors: vertexColors})
+}
+
+// ToGltfDocWithOptions converts a model to a GlTF object like ToGltfDoc, with the layout and attributes of every mesh
+// controlled by opts, which has to be valid.
+func ToGltfDocWithOptions(model Model, atlas bytes.Buffer, opts Options) GlTF {
+	gltfBufferViews := []BufferView{}
+	gltfAccessors := []Accessor{}
+	gltfBuffers := []GltfBuffer{}
+	gltfMeshes := []Mesh{}
+	gltfNodes := []Node{}
+	gltfScenes := []Scene{}
+	gltfMaterials := []GltfMaterial{}
+	gltfImages := []GltfImage{}
+	gltfTextures := []GltfTexture{}
+	gltfSamplers := []Sampler{}
+	gltfSkins := []Skin{}
+
+	// the atlas has to be texture 0, ahead of any normal maps, because that's the one the atlas materials sample.
+	if !opts.VertexColors {
+		gltfImages = append(gltfImages, GltfImage{URI: "data:image/png;base64," + base64.StdEncoding.EncodeToString(atlas.Bytes())})
+		gltfTextures = append(gltfTextures, GltfTexture{Source: 0})
+	}
+
+	outBuf := new(bytes.Buffer)
+
+	associations := []meshInfoAssociation{}
+
+	for _, mesh := range model.Meshes {
+		// a group has nothing to draw, so it gets no accessors; the entry just keeps associations lined up with Meshes.
+		if mesh.isGroup() {
+			associations = append(associations, meshInfoAssociation{})
+			continue
+		}
+
+		accessorAssociation := addMeshInfo(outBuf, mesh, opts, &gltfBufferViews, &gltfAccessors, &gltfMaterials, &gltfImages, &gltfTextures, &gltfSamplers)
+
+		associations = append(associations, accessorAssociation)
+	}
+
+	nodeList := []int{}
+
+	if model.hasHierarchy() {
+		// the nodes are at the same indices as the Geometry they came from, so the roots are too.
+		gltfMeshes, gltfNodes = hierarchyNodes(model, associations)
+		gltfSkins = hierarchySkins(model, gltfNodes, outBuf, &gltfBufferViews, &gltfAccessors)
+		nodeList = model.roots()
+	} else if len(associations) > 0 {
+		meshPrimitives := []MeshPrimitive{}
+
+		for _, assoc := range associations {
+			meshPrimitives = append(meshPrimitives, meshPrimitive(assoc))
+		}
+
+		gltfMeshes = append(gltfMeshes, Mesh{Primitives: meshPrimitives})
+		node := Node{}
+
+		// the Geometry of a flat Model share one quantization frame, since they share this node.
+		for _, assoc := range associations {
+			if assoc.MeshQuantization != nil {
+				node = assoc.MeshQuantization.node()
+				break
+			}
+		}
+
+		node.Mesh = len(gltfMeshes) - 1
+		gltfNodes = append(gltfNodes, node)
+		nodeList = append(nodeList, len(gltfNodes)-1)
+	}
+
+	var rootSceneIndex *int
+
+	switch {
+	case len(model.Scenes) > 0:
+		for _, modelScene := range model.Scenes {
+			scene := Scene{Nodes: append([]int{}, modelScene.Roots...)}
+
+			if modelScene.Name != "" {
+				scene.Name = modelScene.Name
+			}
+
+			gltfScenes = append(gltfScenes, scene)
+		}
+
+		defaultScene := model.DefaultScene
+		rootSceneIndex = &defaultScene
+	case len(gltfNodes) > 0:
+		gltfScenes = append(gltfScenes, Scene{Nodes: nodeList})
+		rootSceneIndex = new(int)
+	}
+
+	// a buffer has to hold at least one byte, so a model with nothing in it gets none.
+	if outBuf.Len() > 0 {
+		gltfBuffers = append(gltfBuffers, GltfBuffer{ByteLength: outBuf.Len(), Bytes: outBuf.Bytes()})
+	}
+
+	gltfDoc := GlTF{
+		Accessors: gltfAccessors,
+		Asset: Asset{
+			Version:   "2.0",
+			Generator: "gltf-go, https://github.com/naikrovek/gltf-go/", // feel free to change this; i don't mind.
+		},
+		Buffers:     gltfBuffers,
+		BufferViews: gltfBufferViews,
+		Materials:   gltfMaterials,
+		Meshes:      gltfMeshes,
+		Nodes:       gltfNodes,
+		Scene:       rootSceneIndex,
+		Scenes:      gltfScenes,
+	}
+
+	if len(gltfTextures) > 0 {
+		gltfDoc.Images = gltfImages
+		gltfDoc.Textures = gltfTextures
+	}
+
+	if len(gltfSamplers) > 0 {
+		gltfDoc.Samplers = gltfSamplers
+	}
+
+	if len(gltfSkins) > 0 {
+		gltfDoc.Skins = gltfSkins
+	}
+
+	gltfDoc.useMaterialExtensions()
+
+	for _, assoc := range associations {
+		if assoc.MeshQuantization != nil {
+			gltfDoc.requireExtension(meshQuantizationExtensionName)
+		}
+	}
+
+	return gltfDoc
+}
+
+// validateAtlasMaterials makes sure that a document using the texture atlas doesn't have any primitives that ignore it.
+// This happens when optimizeModel and ToGltfDoc are called with different vertexColors settings, and the result is a
+// file where only some of the geometry is colored.
+func validateAtlasMaterials(gltfDoc GlTF, vertexColors bool) error {
+	// a vertex color document can still have textures for its normal maps, but it has no atlas, so there's nothing to
+	// check.
+	if vertexColors || len(gltfDoc.Textures) == 0 {
+		return nil
+	}
+
+	for m, mesh := range gltfDoc.Meshes {
+		for p, primitive := range mesh.Primitives {
+			if primitive.Material < 0 || primitive.Material >= len(gltfDoc.Materials) {
+				return fmt.Errorf("mesh %d primitive %d references material %d, which does not exist", m, p, primitive.Material)
+			}
+
+			if gltfDoc.Materials[primitive.Material].PbrMetallicRoughness.BaseColorTexture == nil {
+				return fmt.Errorf("mesh %d primitive %d references material %d, which does not use the texture atlas; "+
+					"call optimizeModel and ToGltfDoc with the same vertexColors setting", m, p, primitive.Material)
+			}
+
+			if _, ok := primitive.Attributes["TEXCOORD_0"]; !ok {
+				return fmt.Errorf("mesh %d primitive %d has no TEXCOORD_0 attribute, so it cannot sample the texture atlas; "+
+					"call optimizeModel and ToGltfDoc with the same vertexColors setting", m, p)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Appends the accessors for the supplied Geometry to the supplied bytes.Buffer, BufferViews and Accessors, adds its
+// material to the supplied materials if it's new, and returns the indices needed to build a MeshPrimitive from it.
+// Accessors for attributes that opts leaves out are not written at all, and their indices are -1.
+func addMeshInfo(outBuf *bytes.Buffer, mesh Geometry, opts Options, gltfBufferViews *[]BufferView, gltfAccessors *[]Accessor, gltfMaterials *[]GltfMaterial, gltfImages *[]GltfImage, gltfTextures *[]GltfTexture, gltfSamplers *[]Sampler) meshInfoAssociation {
+	thisMaterial := gltfMaterial(mesh.Material)
+
+	// the maps all share the material's sampler, which is only added once one of them needs it.
+	var samplerIndex *int
+
+	if sampler, ok := mesh.Material.sampler(); ok && mesh.Material.hasMaps() {
+		index := addSampler(sampler, gltfSamplers)
+		samplerIndex = &index
+	}
+
+	// the atlas samples TEXCOORD_0, and with vertex colors the maps are all that do.
+	writeUV := opts.includes("TEXCOORD_0") && (!opts.VertexColors || mesh.Material.hasMaps())
+	writeUV2 := hasUV2Coords(mesh) && opts.includes("TEXCOORD_1")
+
+	// the maps sample the mesh's own UVs.  TEXCOORD_0 points into the atlas by now, so there only TEXCOORD_1 has them.
+	// A map whose UVs aren't written is left out, rather than pointing at a set that doesn't exist; optimizeModel
+	// rejects atlas Geometry with maps but no UV2.
+	texCoord, mapUVs := 0, writeUV
+
+	if !opts.VertexColors {
+		texCoord, mapUVs = 1, writeUV2
+	}
+
+	if mesh.Material.NormalMapPath != "" && mapUVs {
+		thisMaterial.NormalTexture = &NormalTextureInfo{
+			Index:    addImageTexture(filepath.ToSlash(mesh.Material.NormalMapPath), samplerIndex, gltfImages, gltfTextures),
+			TexCoord: texCoord,
+		}
+	}
+
+	if mesh.Material.OcclusionMapPath != "" && (mapUVs || writeUV2) {
+		thisMaterial.OcclusionTexture = &OcclusionTextureInfo{
+			Index:    addImageTexture(filepath.ToSlash(mesh.Material.OcclusionMapPath), samplerIndex, gltfImages, gltfTextures),
+			TexCoord: texCoord,
+		}
+
+		if writeUV2 {
+			thisMaterial.OcclusionTexture.TexCoord = 1
+		}
+	}
+
+	if mesh.Material.EmissiveMapPath != "" && mapUVs {
+		thisMaterial.EmissiveTexture = &TextureInfo{
+			Index:    addImageTexture(filepath.ToSlash(mesh.Material.EmissiveMapPath), samplerIndex, gltfImages, gltfTextures),
+			TexCoord: texCoord,
+		}
+
+		// the spec's default emissiveFactor is black, which would hide the texture entirely.
+		if thisMaterial.EmissiveFactor == nil {
+			thisMaterial.EmissiveFactor = []float64{1.0, 1.0, 1.0}
+
+			if c := mesh.Material.EmissiveColor; c != [3]float32{} {
+				thisMaterial.EmissiveFactor = []float64{float64(c[0]), float64(c[1]), float64(c[2])}
+			}
+		}
+	}
+
+	uvAccessorIndex := -1
+	vertexColorAccessorIndex := -1
+	meshNormalAccessorIndex := -1
+
+	meshIndicesAccessorIndex := -1
+
+	switch {
+	case mesh.Mode == ModeLines:
+		meshIndicesAccessorIndex = getAccessorIndexFromLines(outBuf, meshLines(mesh), gltfBufferViews, gltfAccessors)
+	case !mesh.Mode.isOrdered():
+		meshIndicesAccessorIndex = getAccessorIndexFromIndices(outBuf, mesh.Faces, gltfBufferViews, gltfAccessors)
+	}
+	meshVertexAccessorIndex := -1
+	uv2AccessorIndex := -1
+
+	// quantized attributes are padded to keep each vertex aligned, so they aren't interleaved.
+	interleave := opts.Interleave && mesh.Quantization == nil
+
+	// without vertex colors to write, the material's base color factor has its color instead.
+	vertexColors := opts.VertexColors && opts.includes("COLOR_0") && hasVertexColors(mesh)
+
+	switch {
+	case mesh.Quantization != nil:
+		meshVertexAccessorIndex = getAccessorIndexFromQuantizedPositions(outBuf, getVertices(mesh), *mesh.Quantization, gltfBufferViews, gltfAccessors)
+
+		if opts.includes("NORMAL") {
+			meshNormalAccessorIndex = getAccessorIndexFromQuantizedNormals(outBuf, getNormals(mesh), gltfBufferViews, gltfAccessors)
+		}
+	case interleave:
+		// the accessor indices come back in the order the attributes were added, so each goes with where it's kept.
+		attributes := []interleavedAttribute{{Vec3, 3, vector3Floats(getVertices(mesh))}}
+		indices := []*int{&meshVertexAccessorIndex}
+
+		if opts.includes("NORMAL") {
+			attributes = append(attributes, interleavedAttribute{Vec3, 3, vector3Floats(getNormals(mesh))})
+			indices = append(indices, &meshNormalAccessorIndex)
+		}
+
+		if writeUV {
+			attributes = append(attributes, interleavedAttribute{Vec2, 2, vector2Floats(getUVCoords(mesh))})
+			indices = append(indices, &uvAccessorIndex)
+		}
+
+		if writeUV2 {
+			attributes = append(attributes, interleavedAttribute{Vec2, 2, vector2Floats(getUV2Coords(mesh))})
+			indices = append(indices, &uv2AccessorIndex)
+		}
+
+		if vertexColors {
+			attributes = append(attributes, interleavedAttribute{Vec4, 4, vector4Floats(getVertexColors(mesh))})
+			indices = append(indices, &vertexColorAccessorIndex)
+		}
+
+		for i, accessorIndex := range getAccessorIndicesFromInterleaved(outBuf, attributes, gltfBufferViews, gltfAccessors) {
+			*indices[i] = accessorIndex
+		}
+	default:
+		meshVertexAccessorIndex = getAccessorIndexFromVector3(outBuf, getVertices(mesh), gltfBufferViews, gltfAccessors)
+
+		if opts.includes("NORMAL") {
+			meshNormalAccessorIndex = getAccessorIndexFromVector3(outBuf, getNormals(mesh), gltfBufferViews, gltfAccessors)
+		}
+	}
+
+	if writeUV && !interleave {
+		uvAccessorIndex = getAccessorIndexFromVector2(outBuf, getUVCoords(mesh), gltfBufferViews, gltfAccessors)
+	}
+
+	if !opts.VertexColors {
+		thisMaterial.PbrMetallicRoughness.BaseColorTexture = atlasTextureInfo(mesh.Material.AtlasTransform)
+	} else {
+		if vertexColors && !interleave {
+			vertexColorAccessorIndex = getAccessorIndexFromVector4(outBuf, getVertexColors(mesh), gltfBufferViews, gltfAccessors)
+		}
+
+		thisMaterial.PbrMetallicRoughness.BaseColorTexture = nil
+	}
+
+	// the factor multiplies the atlas or the vertex colors, which have the colors themselves, so it's only there for
+	// the atlas' alpha; vertex colors have an alpha of their own.  A mesh with neither has the material's color in it.
+	switch {
+	case !opts.VertexColors:
+		thisMaterial.PbrMetallicRoughness.BaseColorFactor = baseColorFactor([3]float32{1, 1, 1}, mesh.Material.Opacity)
+	case vertexColors:
+		thisMaterial.PbrMetallicRoughness.BaseColorFactor = nil
+	default:
+		thisMaterial.PbrMetallicRoughness.BaseColorFactor = baseColorFactor(mesh.Material.DiffuseColor, mesh.Material.Opacity)
+	}
+
+	materialIndex, newGltfMaterials := addMaterial(thisMaterial, *gltfMaterials)
+
+	*gltfMaterials = newGltfMaterials
+
+	accessorAssociation := meshInfoAssociation{
+		MeshIndicesAccessorIndex:       meshIndicesAccessorIndex,
+		MeshMode:                       mesh.Mode,
+		MeshMaterialIndex:              materialIndex,
+		MeshNormalsAccessorIndex:       meshNormalAccessorIndex,
+		MeshTangentsAccessorIndex:      -1,
+		MeshVerticesAccessorIndex:      meshVertexAccessorIndex,
+		MeshUVAccessorIndex:            uvAccessorIndex,
+		MeshUV2AccessorIndex:           uv2AccessorIndex,
+		MeshVertexColorAccessorIndex:   vertexColorAccessorIndex,
+		MeshVelocityAccessorIndex:      -1,
+		MeshMaterialIndexAccessorIndex: -1,
+		MeshJointsAccessorIndex:        -1,
+		MeshWeightsAccessorIndex:       -1,
+		MeshQuantization:               mesh.Quantization,
+		MeshExtras:                     mesh.Extras,
+	}
+
+	// the second UV set is independent of the atlas, which only remaps the first, so it's emitted in both modes.
+	if writeUV2 && !interleave {
+		accessorAssociation.MeshUV2AccessorIndex = getAccessorIndexFromVector2(outBuf, getUV2Coords(mesh), gltfBufferViews, gltfAccessors)
+	}
+
+	// tangents are only any use with a normal map, so they're only emitted for geometry that has them.
+	if hasTangents(mesh) && opts.includes("TANGENT") {
+		if mesh.Quantization != nil {
+			accessorAssociation.MeshTangentsAccessorIndex = getAccessorIndexFromQuantizedTangents(outBuf, getTangents(mesh), gltfBufferViews, gltfAccessors)
+		} else {
+			accessorAssociation.MeshTangentsAccessorIndex = getAccessorIndexFromVector4(outBuf, getTangents(mesh), gltfBufferViews, gltfAccessors)
+		}
+	}
+
+	// velocities are optional, so only emit them for geometry that actually has some.
+	if hasVelocities(mesh) && opts.includes("_VELOCITY") {
+		accessorAssociation.MeshVelocityAccessorIndex = getAccessorIndexFromVector3(outBuf, getVelocities(mesh), gltfBufferViews, gltfAccessors)
+	}
+
+	// material indices only exist for geometry from optimizeModelMaterialIndexed.  They're written as floats, which
+	// represent every uint16 exactly and are the easiest thing for a shader to consume.
+	if hasMaterialIndices(mesh) && opts.includes("_MATERIAL_INDEX") {
+		accessorAssociation.MeshMaterialIndexAccessorIndex = getAccessorIndexFromFloats(outBuf, getMaterialIndices(mesh), Scalar, gltfBufferViews, gltfAccessors)
+		(*gltfBufferViews)[len(*gltfBufferViews)-1].Target = 34962
+	}
+
+	// joints and weights are only any use together, for geometry that's skinned.
+	if hasWeights(mesh) && opts.includes("JOINTS_0") && opts.includes("WEIGHTS_0") {
+		accessorAssociation.MeshJointsAccessorIndex = getAccessorIndexFromJoints(outBuf, getJoints(mesh), gltfBufferViews, gltfAccessors)
+		accessorAssociation.MeshWeightsAccessorIndex = getAccessorIndexFromFloats(outBuf, getWeights(mesh), Vec4, gltfBufferViews, gltfAccessors)
+		(*gltfBufferViews)[len(*gltfBufferViews)-1].Target = 34962
+	}
+
+	// a target can only move the normals and tangents the primitive has.
+	if hasMorphs(mesh) {
+		morphMesh := mesh
+
+		// the offsets stay floats, which the extension allows, but they're in the quantized positions' frame.
+		if mesh.Quantization != nil {
+			morphMesh = mesh.Quantization.scaleMorphs(mesh)
+		}
+
+		accessorAssociation.MeshTargets = getMorphTargets(outBuf, morphMesh, meshNormalAccessorIndex >= 0, accessorAssociation.MeshTangentsAccessorIndex >= 0, gltfBufferViews, gltfAccessors)
+	}
+
+	return accessorAssociation
+}
+
+// builds the MeshPrimitive described by the supplied meshInfoAssociation.  Attributes whose accessor index is -1
+// weren't written, so they're left out.
+func meshPrimitive(assoc meshInfoAssociation) MeshPrimitive {
+	meshPrimitiveAttributes := make(map[string]int)
+	meshPrimitiveAttributes["POSITION"] = assoc.MeshVerticesAccessorIndex
+
+	if assoc.MeshNormalsAccessorIndex >= 0 {
+		meshPrimitiveAttributes["NORMAL"] = assoc.MeshNormalsAccessorIndex
+	}
+
+	if assoc.MeshTangentsAccessorIndex >= 0 {
+		meshPrimitiveAttributes["TANGENT"] = assoc.MeshTangentsAccessorIndex
+	}
+
+	if assoc.MeshUVAccessorIndex >= 0 {
+		meshPrimitiveAttributes["TEXCOORD_0"] = assoc.MeshUVAccessorIndex
+	}
+
+	if assoc.MeshUV2AccessorIndex >= 0 {
+		meshPrimitiveAttributes["TEXCOORD_1"] = assoc.MeshUV2AccessorIndex
+	}
+
+	if assoc.MeshVertexColorAccessorIndex >= 0 {
+		meshPrimitiveAttributes["COLOR_0"] = assoc.MeshVertexColorAccessorIndex
+	}
+
+	if assoc.MeshJointsAccessorIndex >= 0 {
+		meshPrimitiveAttributes["JOINTS_0"] = assoc.MeshJointsAccessorIndex
+	}
+
+	if assoc.MeshWeightsAccessorIndex >= 0 {
+		meshPrimitiveAttributes["WEIGHTS_0"] = assoc.MeshWeightsAccessorIndex
+	}
+
+	if assoc.MeshVelocityAccessorIndex >= 0 {
+		meshPrimitiveAttributes["_VELOCITY"] = assoc.MeshVelocityAccessorIndex
+	}
+
+	if assoc.MeshMaterialIndexAccessorIndex >= 0 {
+		meshPrimitiveAttributes["_MATERIAL_INDEX"] = assoc.MeshMaterialIndexAccessorIndex
+	}
+
+	primitive := MeshPrimitive{
+		Attributes: meshPrimitiveAttributes,
+		Material:   assoc.MeshMaterialIndex,
+		Targets:    assoc.MeshTargets,
+		Extras:     assoc.MeshExtras,
+	}
+
+	if assoc.MeshIndicesAccessorIndex >= 0 {
+		indices := assoc.MeshIndicesAccessorIndex
+		primitive.Indices = &indices
+	}
+
+	if assoc.MeshMode != ModeTriangles {
+		mode := assoc.MeshMode.gltfMode()
+		primitive.Mode = &mode
+	}
+
+	return primitive
+}
+
+// AddGeometry appends the supplied Geometry to an existing document as a new mesh and returns the new mesh's index so
+// it can be attached to a node.  The new data is appended to the end of the document's first buffer; the bytes already
+// in that buffer are left untouched, so existing accessors and buffer views remain valid.
+func (gltfDoc *GlTF) AddGeometry(geo Geometry, opts Options) (meshIndex int, err error) {
+	if err := checkGeometry(geo); err != nil {
+		return -1, err
+	}
+
+	if err := opts.validate(); err != nil {
+		return -1, err
+	}
+
+	// the atlas material samples texture 0, so that texture had better be there.
+	if !opts.VertexColors && len(gltfDoc.Textures) == 0 {
+		return -1, errors.New("document has no texture atlas; use vertex colors or build the document with ToGltfDoc")
+	}
+
+	// the caller attaches the mesh to a node of its own, so there'd be nowhere to put the dequantization transform.
+	geo.Quantization = nil
+
+	outBuf := gltfDoc.beginAppend()
+
+	assoc := addMeshInfo(outBuf, geo, opts, &gltfDoc.BufferViews, &gltfDoc.Accessors, &gltfDoc.Materials, &gltfDoc.Images, &gltfDoc.Textures, &gltfDoc.Samplers)
+
+	gltfDoc.Meshes = append(gltfDoc.Meshes, Mesh{Primitives: []MeshPrimitive{meshPrimitive(assoc)}, Weights: append([]float64(nil), geo.MorphWeights...)})
+	gltfDoc.useMaterialExtensions()
+
+	gltfDoc.endAppend(outBuf)
+
+	return len(gltfDoc.Meshes) - 1, nil
+}
+
+// checkGeometry makes sure a Geometry can be turned into valid glTF: it needs vertices and faces, every face has to
+// refer to vertices that exist, and every position has to be finite, or the POSITION accessor's min and max would be
+// meaningless.
+func checkGeometry(geo Geometry) error {
+	switch {
+	case len(geo.Vertices) == 0:
+		return errors.New("geometry has no vertices")
+	case geo.Mode == ModeTriangles && len(geo.Faces) == 0:
+		return errors.New("geometry has no faces")
+	case geo.Mode == ModeLines && len(geo.Faces) == 0 && len(geo.Edges) == 0:
+		return errors.New("line geometry has no edges or faces")
+	case geo.Mode < ModeTriangles || geo.Mode > ModeTriangleFan:
+		return fmt.Errorf("geometry has unknown primitive mode %d", geo.Mode)
+	}
+
+	for i, e := range geo.Edges {
+		for _, index := range e {
+			if index < 0 || int(index) >= len(geo.Vertices) {
+				return fmt.Errorf("edge %d references vertex %d, but the geometry has %d vertices", i, index, len(geo.Vertices))
+			}
+		}
+	}
+
+	for i, f := range geo.Faces {
+		for _, index := range f.TriangleIndices {
+			if index < 0 || int(index) >= len(geo.Vertices) {
+				return fmt.Errorf("face %d references vertex %d, but the geometry has %d vertices", i, index, len(geo.Vertices))
+			}
+		}
+	}
+
+	for i, v := range geo.Vertices {
+		for _, c := range []float32{v.Position.X, v.Position.Y, v.Position.Z} {
+			if math.IsNaN(float64(c)) || math.IsInf(float64(c), 0) {
+				return fmt.Errorf("vertex %d has a position of %v, which isn't finite", i, v.Position)
+			}
+		}
+	}
+
+	if err := geo.Material.check(); err != nil {
+		return err
+	}
+
+	if err := checkMorphs(geo); err != nil {
+		return err
+	}
+
+	return checkWeights(geo, geo.Skin)
+}
+
+// makes sure the Material's factors are in the ranges glTF allows, and its alpha settings are ones it has.
+func (material Material) check() error {
+	switch {
+	case material.MetallicFactor < 0 || material.MetallicFactor > 1:
+		return fmt.Errorf("metallic factor is %g; it has to be from 0 to 1", material.MetallicFactor)
+	case material.RoughnessFactor < 0 || material.RoughnessFactor > 1:
+		return fmt.Errorf("roughness factor is %g; it has to be from 0 to 1", material.RoughnessFactor)
+	case material.EmissiveStrength < 0:
+		return fmt.Errorf("emissive strength is %g; it can't be less than 0", material.EmissiveStrength)
+	}
+
+	for _, c := range material.EmissiveFactor {
+		if c < 0 || c > 1 {
+			return fmt.Errorf("emissive factor is %v; each component has to be from 0 to 1", material.EmissiveFactor)
+		}
+	}
+
+	return material.checkAlpha()
+}
+
+// beginAppend returns a bytes.Buffer holding a copy of the document's first buffer, padded to a 4 byte boundary, ready
+// for new buffer views to be appended to it.  Pass it to endAppend when done.  The existing bytes are copied rather than
+// wrapped, so that appending can never write into memory that belongs to a slice the caller still holds.
+func (gltfDoc *GlTF) beginAppend() *bytes.Buffer {
+	if len(gltfDoc.Buffers) == 0 {
+		gltfDoc.Buffers = append(gltfDoc.Buffers, GltfBuffer{})
+	}
+
+	outBuf := new(bytes.Buffer)
+	outBuf.Write(gltfDoc.Buffers[0].Bytes)
+
+	padToAlignment(outBuf)
+
+	return outBuf
+}
+
+// pads the supplied bytes.Buffer with zeros to a 4 byte boundary.  Every helper that adds a buffer view calls this
+// first, so each view starts aligned no matter how long the data before it was, and accessors of any component type
+// are properly aligned.
+func padToAlignment(outBuf *bytes.Buffer) {
+	for outBuf.Len()%4 != 0 {
+		outBuf.WriteByte(0)
+	}
+}
+
+// endAppend stores the contents of a bytes.Buffer from beginAppend as the document's first buffer.
+func (gltfDoc *GlTF) endAppend(outBuf *bytes.Buffer) {
+	gltfDoc.Buffers[0].Bytes = outBuf.Bytes()
+	gltfDoc.Buffers[0].ByteLength = outBuf.Len()
+}
+
+func getVertices(mesh Geometry) []Vector3 {
+	results := []Vector3{}
+
+	for _, m := range mesh.Vertices {
+		results = append(results, m.Position)
+	}
+
+	return results
+}
+
+func getNormals(mesh Geometry) []Vector3 {
+	results := []Vector3{}
+
+	for _, m := range mesh.Vertices {
+		results = append(results, m.Normal)
+	}
+
+	return results
+}
+
+func getTangents(mesh Geometry) []Vector4 {
+	results := []Vector4{}
+
+	for _, m := range mesh.Vertices {
+		results = append(results, m.Tangent)
+	}
+
+	return results
+}
+
+// reports whether any vertex in the mesh has a tangent.
+func hasTangents(mesh Geometry) bool {
+	for _, m := range mesh.Vertices {
+		if m.Tangent != (Vector4{}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func getVelocities(mesh Geometry) []Vector3 {
+	results := []Vector3{}
+
+	for _, m := range mesh.Vertices {
+		results = append(results, m.Velocity)
+	}
+
+	return results
+}
+
+// reports whether any vertex in the mesh is moving.
+func hasVelocities(mesh Geometry) bool {
+	for _, m := range mesh.Vertices {
+		if m.Velocity != (Vector3{}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func getMaterialIndices(mesh Geometry) []float32 {
+	results := []float32{}
+
+	for _, m := range mesh.Vertices {
+		results = append(results, float32(m.MaterialIndex))
+	}
+
+	return results
+}
+
+// reports whether any vertex in the mesh uses a material other than the first in its palette.
+func hasMaterialIndices(mesh Geometry) bool {
+	for _, m := range mesh.Vertices {
+		if m.MaterialIndex != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func getUVCoords(mesh Geometry) []Vector2 {
+	results := []Vector2{}
+
+	for _, m := range mesh.Vertices {
+		results = append(results, m.UV)
+	}
+
+	return results
+}
+
+func getUV2Coords(mesh Geometry) []Vector2 {
+	results := []Vector2{}
+
+	for _, m := range mesh.Vertices {
+		results = append(results, m.UV2)
+	}
+
+	return results
+}
+
+// reports whether any vertex in the mesh has a second UV.
+func hasUV2Coords(mesh Geometry) bool {
+	for _, m := range mesh.Vertices {
+		if m.UV2 != (Vector2{}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reports whether any vertex in the mesh has a color.  Geometry built without any has them all zero, which would be
+// transparent black.
+func hasVertexColors(mesh Geometry) bool {
+	for _, m := range mesh.Vertices {
+		if m.Color != (Vector4{}) {
+			return true
+		}
+	}
+
+	return false
 }
 
 func getVertexColors(mesh Geometry) []Vector4 {
@@ -859,13 +2655,56 @@ func getVertexColors(mesh Geometry) []Vector4 {
 type Model struct {
 	//Materials []Material `json:"materials,omitempty"`
 	Meshes []Geometry `json:"meshes,omitempty"`
+
+	// Scenes lists the scenes to write, each naming the Geometry at its roots; defining any makes the Model a
+	// hierarchy.  DefaultScene is the index of the one viewers show first.  With no Scenes, every root Geometry goes
+	// into a single scene.
+	Scenes       []ModelScene `json:"scenes,omitempty"`
+	DefaultScene int          `json:"defaultScene,omitempty"`
+}
+
+// ModelScene is one scene of a Model.  Roots are indices into Model.Meshes, and have to be Geometry that aren't anyone's
+// child.  The same Geometry may be a root of more than one scene.
+type ModelScene struct {
+	Name  string `json:"name,omitempty"`
+	Roots []int  `json:"roots"`
 }
 
 // Geometry ...
 type Geometry struct {
-	Vertices []Vertex   `json:"vertices,omitempty"`
-	Faces    []Triangle `json:"faces,omitempty"`
-	Material Material   `json:"material"`
+	Vertices []Vertex    `json:"vertices,omitempty"`
+	Faces    []Triangle  `json:"faces,omitempty"`
+	Material Material    `json:"material"`
+	Extras   interface{} `json:"extras,omitempty"` // copied to the extras of the MeshPrimitive made from this Geometry.
+
+	// Mode is the kind of primitive to draw; see PrimitiveMode.  Edges are the vertex index pairs of a ModeLines
+	// Geometry, for lines that aren't the edges of its Faces.
+	Mode  PrimitiveMode `json:"mode,omitempty"`
+	Edges [][2]int32    `json:"edges,omitempty"`
+
+	// Children and the transform make the Model a hierarchy; see hierarchy.go.  Children are indices into Model.Meshes,
+	// and the transform is relative to the parent: a translation, a unit quaternion (x, y, z, w) and a scale, which
+	// become the node's translation, rotation and scale.  Matrix is a column-major 4x4 alternative to those three,
+	// which can't be combined with it.  Leave them nil for no transform.  A Geometry with children or a transform but
+	// no vertices or faces is just a node, grouping its children or serving as a skin's joint.
+	Children    []int     `json:"children,omitempty"`
+	Translation []float64 `json:"translation,omitempty"`
+	Rotation    []float64 `json:"rotation,omitempty"`
+	Scale       []float64 `json:"scale,omitempty"`
+	Matrix      []float64 `json:"matrix,omitempty"`
+
+	// Skin makes this a skinned mesh, deformed by the joints it names, with each vertex's Joints and Weights saying
+	// which joints move it.  It makes the Model a hierarchy too, since the joints are nodes.  See skin.go.
+	Skin *ModelSkin `json:"skin,omitempty"`
+
+	// MorphWeights are the default weights of the morph targets in the vertices' Morphs, one per target, and become the
+	// mesh's weights.  Nil leaves them all at 0.  Morph targets make the Model a hierarchy, so that the Geometry gets a
+	// mesh of its own.
+	MorphWeights []float64 `json:"morphWeights,omitempty"`
+
+	// Quantization, set by optimizeModel when asked to quantize, has the positions written as normalized shorts and
+	// the normals and tangents as normalized bytes, with KHR_mesh_quantization.  See quantize.go.
+	Quantization *Quantization `json:"quantization,omitempty"`
 }
 
 // Material as defined in the binary file
@@ -876,6 +2715,64 @@ type Material struct {
 	SpecularPower float32    `json:"specularPower"`
 	EmissiveColor [3]float32 `json:"emissiveColor,omitempty"`
 	Opacity       float32    `json:"opacity"`
+
+	// MetallicFactor and RoughnessFactor, each from 0 to 1, become the glTF material's.  The zero MetallicFactor is a
+	// dielectric, which suits most untextured meshes; the spec's default of 1 makes them look like chrome.  A zero
+	// RoughnessFactor is taken from SpecularPower instead, or is 0.5 if that's zero too, so a perfectly smooth material
+	// needs a RoughnessFactor just above 0.
+	MetallicFactor  float32 `json:"metallicFactor,omitempty"`
+	RoughnessFactor float32 `json:"roughnessFactor,omitempty"`
+
+	// AlphaMode is how Opacity is used.  It goes in the fourth component of the glTF material's base color factor, or,
+	// with vertex colors, theirs.  Left empty, a material with an Opacity under 1 is AlphaBlend and any other is
+	// opaque.  AlphaCutoff is the alpha below which AlphaMask discards a fragment; it's only written for AlphaMask, and
+	// 0 means the spec default of 0.5.
+	AlphaMode   AlphaMode `json:"alphaMode,omitempty"`
+	AlphaCutoff float32   `json:"alphaCutoff,omitempty"`
+
+	// TexturePath is an optional PNG or JPEG file to use in place of the diffuse color in the texture atlas.  Vertex
+	// UVs index into this texture; optimizeModel remaps them into the atlas.
+	TexturePath string `json:"texturePath,omitempty"`
+
+	// NormalMapPath is an optional tangent space normal map.  Unlike TexturePath it isn't packed into the atlas; the
+	// image is referenced by this path, so it should be relative to where the glTF file will be written.  With vertex
+	// colors it's sampled with Vertex.UV, but the atlas remaps those, so atlas meshes sample it with Vertex.UV2, and
+	// optimizeModel rejects those without one.
+	NormalMapPath string `json:"normalMapPath,omitempty"`
+
+	// OcclusionMapPath is an optional ambient occlusion map, referenced like NormalMapPath.  It's sampled with
+	// Vertex.UV2 whenever the mesh has one, since occlusion is usually baked into a lightmap UV set of its own, and
+	// atlas meshes need one just as they do for NormalMapPath.
+	OcclusionMapPath string `json:"occlusionMapPath,omitempty"`
+
+	// EmissiveMapPath is an optional emissive map, referenced and sampled like NormalMapPath.  It's multiplied by
+	// EmissiveFactor, or by EmissiveColor if EmissiveFactor is black, or by white if both are.
+	EmissiveMapPath string `json:"emissiveMapPath,omitempty"`
+
+	// EmissiveFactor is the light the material gives off, as a linear color from 0 to 1, whether or not it has an
+	// emissive map; black gives off none.  EmissiveStrength multiplies it, using the KHR_materials_emissive_strength
+	// extension, for emission brighter than the factor alone allows; 0 and 1 leave it as it is.
+	EmissiveFactor   [3]float32 `json:"emissiveFactor,omitempty"`
+	EmissiveStrength float32    `json:"emissiveStrength,omitempty"`
+
+	// DoubleSided turns off back face culling, so the material's triangles are seen from behind too, as foliage cards
+	// need.  It's the spec default of false for a zero Material, but the loaders' materials have it set, since files
+	// rarely say either way and their winding is often inconsistent.
+	DoubleSided bool `json:"doubleSided,omitempty"`
+
+	// Unlit makes the material ignore lighting, using the KHR_materials_unlit extension.
+	Unlit bool `json:"unlit,omitempty"`
+
+	// Wrap, MagFilter and MinFilter set up the sampler for the normal, occlusion and emissive maps, with Wrap used
+	// along both U and V.  Zero values leave the spec defaults.  The texture atlas is never wrapped, so they don't
+	// apply to it.
+	Wrap      WrapMode      `json:"wrap,omitempty"`
+	MagFilter TextureFilter `json:"magFilter,omitempty"`
+	MinFilter TextureFilter `json:"minFilter,omitempty"`
+
+	// AtlasTransform is set by optimizeModel when it's asked for texture transforms: rather than the vertex UVs being
+	// remapped into the material's part of the texture atlas, the atlas is sampled through this KHR_texture_transform.
+	AtlasTransform *TextureTransform `json:"atlasTransform,omitempty"`
 }
 
 // Triangle ...
@@ -910,17 +2807,77 @@ type Vertex struct {
 	Position Vector3 `json:"position,omitempty"`
 	Normal   Vector3 `json:"normal,omitempty"`
 	UV       Vector2 `json:"uv,omitempty"`
+	UV2      Vector2 `json:"uv2,omitempty"`      // emitted as TEXCOORD_1 when any vertex in a mesh has one, for lightmaps and the like.
+	Velocity Vector3 `json:"velocity,omitempty"` // emitted as the _VELOCITY attribute when any vertex in a mesh has one.
+
+	// the direction of increasing U for normal mapping, in R, G and B, with A being 1 or -1 to give the handedness of
+	// the bitangent, as glTF's TANGENT attribute has it.  Emitted when any vertex in a mesh has a non-zero tangent.
+	Tangent Vector4 `json:"tangent,omitempty"`
+
+	// set by optimizeModelMaterialIndexed, and emitted as the _MATERIAL_INDEX attribute when any vertex in a mesh has
+	// one other than 0.
+	MaterialIndex uint16 `json:"materialIndex,omitempty"`
+
+	// the joints of the Geometry's skin that move this vertex, as indices into ModelSkin.Joints, and how much each one
+	// does.  Emitted as JOINTS_0 and WEIGHTS_0 when any vertex in a mesh has a weight, and the weights then have to sum
+	// to 1.
+	Joints  [4]uint16  `json:"joints,omitempty"`
+	Weights [4]float32 `json:"weights,omitempty"`
+
+	// how far each of the Geometry's morph targets moves this vertex, one per target; see morph.go.
+	Morphs []MorphDelta `json:"morphs,omitempty"`
 }
 
-func failIf(condition bool, message ...interface{}) {
-	if condition {
-		log.Fatal(message...)
+// setExtension returns the supplied extensions object with the named extension set to value.  Extensions objects are
+// stored as map[string]interface{}, which is also what encoding/json produces when it decodes one.
+func setExtension(extensions interface{}, name string, value interface{}) (interface{}, error) {
+	switch e := extensions.(type) {
+	case nil:
+		return map[string]interface{}{name: value}, nil
+	case map[string]interface{}:
+		e[name] = value
+		return e, nil
+	default:
+		return extensions, fmt.Errorf("don't know how to add %s to extensions of type %T", name, extensions)
 	}
 }
 
-func printIf(condition bool, message ...interface{}) {
-	if condition {
-		fmt.Println(message...)
+// requireExtension lists the named extension in ExtensionsRequired, as well as in ExtensionsUsed, for extensions the
+// document can't be shown properly without.
+func (gltfDoc *GlTF) requireExtension(name string) {
+	gltfDoc.useExtension(name)
+
+	for _, required := range gltfDoc.ExtensionsRequired {
+		if required == name {
+			return
+		}
+	}
+
+	gltfDoc.ExtensionsRequired = append(gltfDoc.ExtensionsRequired, name)
+	sort.Strings(gltfDoc.ExtensionsRequired)
+}
+
+// useExtension adds the named extension to ExtensionsUsed if it isn't already listed.  The list is kept sorted, so
+// the order the extensions were added in doesn't show in the output.
+func (gltfDoc *GlTF) useExtension(name string) {
+	for _, used := range gltfDoc.ExtensionsUsed {
+		if used == name {
+			return
+		}
+	}
+
+	gltfDoc.ExtensionsUsed = append(gltfDoc.ExtensionsUsed, name)
+	sort.Strings(gltfDoc.ExtensionsUsed)
+}
+
+// SortExtensions removes any duplicates from ExtensionsUsed and ExtensionsRequired and sorts both, so that documents
+// using the same extensions list them the same way, however the lists were put together.  WriteGltf does this before
+// writing.  It doesn't add required extensions missing from ExtensionsUsed; Validate reports those, and Repair adds
+// them.
+func (gltfDoc *GlTF) SortExtensions() {
+	for _, names := range []*[]string{&gltfDoc.ExtensionsUsed, &gltfDoc.ExtensionsRequired} {
+		*names = dedupe(*names, func(string) {})
+		sort.Strings(*names)
 	}
 }
 
@@ -930,6 +2887,10 @@ func logIf(condition bool, message ...interface{}) {
 	}
 }
 
+func isPowerOfTwo(n int) bool {
+	return n > 0 && n&(n-1) == 0
+}
+
 func mapRange(x float64, inMin float64, inMax float64, outMin float64, outMax float64) float64 {
 	return (x-inMin)*(outMax-outMin)/(inMax-inMin) + outMin
 }
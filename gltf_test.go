@@ -0,0 +1,672 @@
+package gltf
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// returns a triangle in the XY plane, wound counter-clockwise seen from +Z, with normals along +Z.
+func testTriangle(material Material) Geometry {
+	normal := Vector3{Z: 1}
+
+	return Geometry{
+		Vertices: []Vertex{
+			{Position: Vector3{X: 0, Y: 0}, Normal: normal, UV: Vector2{U: 0, V: 1}},
+			{Position: Vector3{X: 1, Y: 0}, Normal: normal, UV: Vector2{U: 1, V: 1}},
+			{Position: Vector3{X: 0, Y: 1}, Normal: normal, UV: Vector2{U: 0, V: 0}},
+		},
+		Faces:    []Triangle{{TriangleIndices: [3]int32{0, 1, 2}}},
+		Material: material,
+	}
+}
+
+// runs the Model through OptimizeModel, failing the test if it returns an error.
+func optimizeForTest(t *testing.T, model Model, opts PipelineOptions) *GlTF {
+	t.Helper()
+
+	gltfDoc, _, err := OptimizeModel(model, opts)
+
+	if err != nil {
+		t.Fatalf("OptimizeModel: %v", err)
+	}
+
+	return gltfDoc
+}
+
+func TestBaseColorFactorWithoutVertexColors(t *testing.T) {
+	red := Material{DiffuseColor: [3]float32{1, 0, 0}, Opacity: 1}
+	gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{testTriangle(red)}}, PipelineOptions{Options: Options{VertexColors: true}})
+
+	if got, want := gltfDoc.Materials[0].PbrMetallicRoughness.BaseColorFactor, []float64{1, 0, 0, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("baseColorFactor is %v, want %v", got, want)
+	}
+
+	if _, ok := gltfDoc.Meshes[0].Primitives[0].Attributes["COLOR_0"]; ok {
+		t.Error("COLOR_0 was written for a mesh without vertex colors")
+	}
+}
+
+func TestBaseColorFactorDefaultOmitted(t *testing.T) {
+	white := Material{DiffuseColor: [3]float32{1, 1, 1}, Opacity: 1}
+	gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{testTriangle(white)}}, PipelineOptions{Options: Options{VertexColors: true}})
+
+	if factor := gltfDoc.Materials[0].PbrMetallicRoughness.BaseColorFactor; factor != nil {
+		t.Errorf("baseColorFactor is %v, want it left out", factor)
+	}
+}
+
+func TestNormalMapTexCoord(t *testing.T) {
+	mapped := Material{DiffuseColor: [3]float32{1, 1, 1}, Opacity: 1, NormalMapPath: "normal.png"}
+
+	// the atlas remaps Vertex.UV, so without a UV2 there's nothing to sample the normal map with.
+	if _, _, err := OptimizeModel(Model{Meshes: []Geometry{testTriangle(mapped)}}, PipelineOptions{}); err == nil {
+		t.Error("an atlas mesh with a normal map but no UV2 was accepted")
+	}
+
+	withUV2 := testTriangle(mapped)
+
+	for i := range withUV2.Vertices {
+		withUV2.Vertices[i].UV2 = withUV2.Vertices[i].UV
+	}
+
+	tests := []struct {
+		name         string
+		geo          Geometry
+		vertexColors bool
+		texCoord     int
+	}{
+		{"atlas", withUV2, false, 1},
+		{"vertex colors", testTriangle(mapped), true, 0},
+	}
+
+	for _, test := range tests {
+		gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{test.geo}}, PipelineOptions{Options: Options{VertexColors: test.vertexColors}})
+		normalTexture := gltfDoc.Materials[0].NormalTexture
+
+		if normalTexture == nil {
+			t.Errorf("%s: no normalTexture was written", test.name)
+			continue
+		}
+
+		if normalTexture.TexCoord != test.texCoord {
+			t.Errorf("%s: normalTexture.texCoord is %d, want %d", test.name, normalTexture.TexCoord, test.texCoord)
+		}
+
+		if _, ok := gltfDoc.Meshes[0].Primitives[0].Attributes[fmt.Sprintf("TEXCOORD_%d", test.texCoord)]; !ok {
+			t.Errorf("%s: the normal map samples TEXCOORD_%d, which wasn't written", test.name, test.texCoord)
+		}
+	}
+}
+
+func TestKeepGeometry(t *testing.T) {
+	red := Material{DiffuseColor: [3]float32{1, 0, 0}, Opacity: 1}
+	first, second := testTriangle(red), testTriangle(red)
+
+	for i := range second.Vertices {
+		second.Vertices[i].Position.Z = 1
+	}
+
+	model := Model{Meshes: []Geometry{first, second}}
+
+	tests := []struct {
+		keepGeometry bool
+		indexCounts  []int
+	}{
+		// merging by material is the default, as it always was.
+		{false, []int{6}},
+		{true, []int{3, 3}},
+	}
+
+	for _, test := range tests {
+		gltfDoc := optimizeForTest(t, model, PipelineOptions{Options: Options{VertexColors: true}, KeepGeometry: test.keepGeometry})
+		var counts []int
+
+		for _, mesh := range gltfDoc.Meshes {
+			for _, primitive := range mesh.Primitives {
+				counts = append(counts, gltfDoc.Accessors[*primitive.Indices].Count)
+
+				if primitive.Material != 0 {
+					t.Errorf("KeepGeometry %t: a primitive doesn't use the one shared material", test.keepGeometry)
+				}
+			}
+		}
+
+		if !reflect.DeepEqual(counts, test.indexCounts) {
+			t.Errorf("KeepGeometry %t: primitives have %v indices, want %v", test.keepGeometry, counts, test.indexCounts)
+		}
+
+		if len(gltfDoc.Materials) != 1 {
+			t.Errorf("KeepGeometry %t: %d materials were written, want 1", test.keepGeometry, len(gltfDoc.Materials))
+		}
+	}
+}
+
+func TestTriangleIndicesAreUnsignedShort(t *testing.T) {
+	gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{testTriangle(Material{Opacity: 1})}}, PipelineOptions{Options: Options{VertexColors: true}})
+	indices := gltfDoc.Accessors[*gltfDoc.Meshes[0].Primitives[0].Indices]
+
+	if indices.ComponentType != UnsignedShort {
+		t.Errorf("indices have componentType %d, want %d", indices.ComponentType, UnsignedShort)
+	}
+
+	// three uint16 indices, rather than three uint32s.
+	if length := gltfDoc.BufferViews[*indices.BufferView].ByteLength; length != 6 {
+		t.Errorf("index buffer view is %d bytes, want 6", length)
+	}
+}
+
+func TestAddGeometryKeepsAlignment(t *testing.T) {
+	gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{testTriangle(Material{Opacity: 1})}}, PipelineOptions{Options: Options{VertexColors: true}})
+
+	// each triangle's indices are 6 bytes, so every other append would start off a 4 byte boundary without padding.
+	for i := 0; i < 100; i++ {
+		if _, err := gltfDoc.AddGeometry(testTriangle(Material{Opacity: 1}), Options{VertexColors: true}); err != nil {
+			t.Fatalf("append %d: %v", i, err)
+		}
+	}
+
+	if err := gltfDoc.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+
+	if err := gltfDoc.ValidateReferences(); err != nil {
+		t.Errorf("ValidateReferences: %v", err)
+	}
+
+	// Validate doesn't look at alignment, so check that the way ValidateFile does.
+	if problems := gltfDoc.structureErrors(); len(problems) > 0 {
+		t.Errorf("structure problems: %v", problems)
+	}
+
+	for i, view := range gltfDoc.BufferViews {
+		if view.ByteOffset%4 != 0 {
+			t.Errorf("bufferViews[%d] starts at byte %d, which isn't 4 byte aligned", i, view.ByteOffset)
+		}
+	}
+}
+
+func TestPositionBounds(t *testing.T) {
+	// all of it beyond 100, which the bounds used to start at.
+	geo := testTriangle(Material{Opacity: 1})
+
+	for i := range geo.Vertices {
+		geo.Vertices[i].Position = add(geo.Vertices[i].Position, Vector3{X: 200, Y: -300, Z: 150})
+	}
+
+	gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{geo}}, PipelineOptions{Options: Options{VertexColors: true}})
+	position := gltfDoc.Accessors[gltfDoc.Meshes[0].Primitives[0].Attributes["POSITION"]]
+
+	if want := []float32{200, -300, 150}; !reflect.DeepEqual(position.Min, want) {
+		t.Errorf("POSITION min is %v, want %v", position.Min, want)
+	}
+
+	if want := []float32{201, -299, 150}; !reflect.DeepEqual(position.Max, want) {
+		t.Errorf("POSITION max is %v, want %v", position.Max, want)
+	}
+
+	geo.Vertices[1].Position.Y = float32(math.NaN())
+
+	if _, _, err := OptimizeModel(Model{Meshes: []Geometry{geo}}, PipelineOptions{}); err == nil || !strings.Contains(err.Error(), "finite") {
+		t.Errorf("a NaN position gave error %v, want one saying it isn't finite", err)
+	}
+}
+
+func TestInterleavedStride(t *testing.T) {
+	opts := Options{VertexColors: true, Attributes: []string{"NORMAL"}, Interleave: true}
+	gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{testTriangle(Material{Opacity: 1})}}, PipelineOptions{Options: opts})
+	attributes := gltfDoc.Meshes[0].Primitives[0].Attributes
+	position, normal := gltfDoc.Accessors[attributes["POSITION"]], gltfDoc.Accessors[attributes["NORMAL"]]
+
+	if *position.BufferView != *normal.BufferView {
+		t.Fatalf("POSITION and NORMAL are in views %d and %d, want them sharing one", *position.BufferView, *normal.BufferView)
+	}
+
+	// each vertex's position, then its normal: six floats.
+	if stride := gltfDoc.BufferViews[*position.BufferView].ByteStride; stride != 24 {
+		t.Errorf("interleaved view has byteStride %d, want 24", stride)
+	}
+
+	indices := gltfDoc.Accessors[*gltfDoc.Meshes[0].Primitives[0].Indices]
+
+	if stride := gltfDoc.BufferViews[*indices.BufferView].ByteStride; stride != 0 {
+		t.Errorf("tightly packed index view has byteStride %d, want it left out", stride)
+	}
+
+	if err := gltfDoc.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+
+	gltfDoc.BufferViews[*position.BufferView].ByteStride = 26
+
+	if err := gltfDoc.Validate(); err == nil || !strings.Contains(err.Error(), "multiple of 4") {
+		t.Errorf("a byteStride of 26 gave error %v, want one saying it isn't a multiple of 4", err)
+	}
+}
+
+func TestEmptyModelOmitsScene(t *testing.T) {
+	gltfDoc := ToGltfDoc(Model{}, bytes.Buffer{}, true)
+	data, err := json.Marshal(gltfDoc)
+
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	var document map[string]json.RawMessage
+
+	if err := json.Unmarshal(data, &document); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+
+	for _, field := range []string{"scene", "scenes"} {
+		if value, ok := document[field]; ok {
+			t.Errorf("an empty model has %q: %s, want it left out", field, value)
+		}
+	}
+}
+
+func TestDefaultScene(t *testing.T) {
+	model := Model{
+		Meshes:       []Geometry{testTriangle(Material{Opacity: 1}), testTriangle(Material{Opacity: 1})},
+		Scenes:       []ModelScene{{Name: "first", Roots: []int{0}}, {Name: "second", Roots: []int{1}}},
+		DefaultScene: 1,
+	}
+	gltfDoc := optimizeForTest(t, model, PipelineOptions{Options: Options{VertexColors: true}})
+
+	if len(gltfDoc.Scenes) != 2 {
+		t.Fatalf("got %d scenes, want 2", len(gltfDoc.Scenes))
+	}
+
+	if gltfDoc.Scene == nil || *gltfDoc.Scene != 1 {
+		t.Errorf("scene is %v, want 1", gltfDoc.Scene)
+	}
+
+	if name := gltfDoc.Scenes[1].Name; name != "second" {
+		t.Errorf("scenes[1] is named %v, want second", name)
+	}
+}
+
+// returns the floats of a FLOAT accessor, element by element, however its buffer view lays them out.
+func readFloatsForTest(t *testing.T, gltfDoc *GlTF, accessorIndex int) []float32 {
+	t.Helper()
+
+	accessor := gltfDoc.Accessors[accessorIndex]
+	components := componentCount(accessor.Type)
+
+	if accessor.ComponentType != Float {
+		t.Fatalf("accessors[%d] has componentType %d, not FLOAT", accessorIndex, accessor.ComponentType)
+	}
+
+	data, stride, ok := gltfDoc.viewBytes(*accessor.BufferView, accessor.ByteOffset, accessor.Count, 4*components, 0)
+
+	if !ok {
+		t.Fatalf("accessors[%d] isn't in its buffer view", accessorIndex)
+	}
+
+	floats := make([]float32, 0, accessor.Count*components)
+
+	for i := 0; i < accessor.Count; i++ {
+		for c := 0; c < components; c++ {
+			floats = append(floats, math.Float32frombits(binary.LittleEndian.Uint32(data[i*stride+4*c:])))
+		}
+	}
+
+	return floats
+}
+
+func TestInterleavedMatchesPacked(t *testing.T) {
+	quad := testQuad(Material{Opacity: 1})
+
+	for i := range quad.Vertices {
+		quad.Vertices[i].UV2 = Vector2{U: float32(i) / 4, V: 0.5}
+		quad.Vertices[i].Color = Vector4{R: 1, G: float32(i) / 4, B: 0, A: 1}
+	}
+
+	packed := optimizeForTest(t, Model{Meshes: []Geometry{quad}}, PipelineOptions{Options: Options{VertexColors: true}})
+	interleaved := optimizeForTest(t, Model{Meshes: []Geometry{quad}}, PipelineOptions{Options: Options{VertexColors: true, Interleave: true}})
+	packedAttributes := packed.Meshes[0].Primitives[0].Attributes
+	interleavedAttributes := interleaved.Meshes[0].Primitives[0].Attributes
+
+	for _, name := range []string{"POSITION", "NORMAL", "TEXCOORD_1", "COLOR_0"} {
+		if _, ok := interleavedAttributes[name]; !ok {
+			t.Errorf("the interleaved primitive has no %s", name)
+		}
+	}
+
+	if len(packedAttributes) != len(interleavedAttributes) {
+		t.Fatalf("packed has attributes %v, interleaved %v", packedAttributes, interleavedAttributes)
+	}
+
+	views := make(map[int]bool)
+
+	for name, index := range interleavedAttributes {
+		views[*interleaved.Accessors[index].BufferView] = true
+
+		if got, want := readFloatsForTest(t, interleaved, index), readFloatsForTest(t, packed, packedAttributes[name]); !reflect.DeepEqual(got, want) {
+			t.Errorf("interleaved %s is %v, want %v as packed", name, got, want)
+		}
+	}
+
+	if len(views) != 1 {
+		t.Errorf("the interleaved attributes are in %d buffer views, want 1", len(views))
+	}
+
+	for _, gltfDoc := range []*GlTF{packed, interleaved} {
+		for i, view := range gltfDoc.BufferViews {
+			if view.ByteStride%4 != 0 {
+				t.Errorf("bufferViews[%d] has byteStride %d, which isn't a multiple of 4", i, view.ByteStride)
+			}
+		}
+	}
+}
+
+// writes a 2x2 flat normal map as normal.png in dir, and returns its path.
+func writeTestNormalMap(t *testing.T, dir string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, "normal.png")
+	flat := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.RGBA{R: 128, G: 128, B: 255, A: 255}), image.Point{}, draw.Src)
+	encoded := new(bytes.Buffer)
+
+	if err := png.Encode(encoded, flat); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, encoded.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestWriteGltfDeterministic(t *testing.T) {
+	dir := t.TempDir()
+	normalMap := writeTestNormalMap(t, dir)
+
+	// materials with extensions, whose maps are where key order could come loose.
+	model := Model{Meshes: []Geometry{
+		testTriangle(Material{DiffuseColor: [3]float32{1, 0, 0}, Opacity: 1, Unlit: true}),
+		testTriangle(Material{DiffuseColor: [3]float32{0, 1, 0}, Opacity: 0.5, EmissiveFactor: [3]float32{1, 1, 0}, EmissiveStrength: 4}),
+		testQuad(Material{DiffuseColor: [3]float32{0, 0, 1}, Opacity: 1, NormalMapPath: normalMap, Wrap: WrapClampToEdge}),
+	}}
+	written := [][]byte{}
+
+	for i := 0; i < 2; i++ {
+		gltfDoc := optimizeForTest(t, model, PipelineOptions{Options: Options{VertexColors: true}})
+		gltfDoc.Nodes[0].Extras = map[string]interface{}{"b": 1, "a": []int{2, 3}, "c": map[string]int{"z": 1, "y": 2}}
+		// the same name both times, since WriteGltf names the mesh and node after it.
+		filename := filepath.Join(dir, "model")
+
+		if err := WriteGltf(gltfDoc, filename, OutputEmbedded, false); err != nil {
+			t.Fatalf("WriteGltf: %v", err)
+		}
+
+		data, err := os.ReadFile(filename + ".gltf")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		written = append(written, data)
+	}
+
+	if !bytes.Equal(written[0], written[1]) {
+		t.Errorf("writing the same Model twice gave different files:\n%s\n%s", written[0], written[1])
+	}
+
+	gltfDoc := optimizeForTest(t, model, PipelineOptions{Options: Options{VertexColors: true}})
+	first, firstErr := json.Marshal(gltfDoc)
+	second, secondErr := json.Marshal(gltfDoc)
+
+	if firstErr != nil || secondErr != nil {
+		t.Fatalf("json.Marshal: %v, %v", firstErr, secondErr)
+	}
+
+	if !bytes.Equal(first, second) {
+		t.Errorf("marshalling the same GlTF twice gave different bytes:\n%s\n%s", first, second)
+	}
+}
+
+func TestMetallicRoughnessRoundTrip(t *testing.T) {
+	tests := []struct {
+		name                string
+		material            Material
+		metallic, roughness float64
+	}{
+		// a dielectric look, rather than the spec's chrome default of metallic 1 and roughness 1.
+		{"defaults", Material{Opacity: 1}, 0, 0.5},
+		{"custom", Material{Opacity: 1, MetallicFactor: 0.75, RoughnessFactor: 0.25}, 0.75, 0.25},
+	}
+
+	for _, test := range tests {
+		gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{testTriangle(test.material)}}, PipelineOptions{Options: Options{VertexColors: true}})
+		filename := filepath.Join(t.TempDir(), "model")
+
+		if err := WriteGltf(gltfDoc, filename, OutputEmbedded, false); err != nil {
+			t.Fatalf("%s: WriteGltf: %v", test.name, err)
+		}
+
+		file, err := os.Open(filename + ".gltf")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		loaded, err := LoadGltf(file)
+		file.Close()
+
+		if err != nil {
+			t.Fatalf("%s: LoadGltf: %v", test.name, err)
+		}
+
+		pbr := loaded.Materials[0].PbrMetallicRoughness
+
+		if pbr.MetallicFactor != test.metallic || pbr.RoughnessFactor != test.roughness {
+			t.Errorf("%s: metallic and roughness came back as %g and %g, want %g and %g", test.name, pbr.MetallicFactor, pbr.RoughnessFactor, test.metallic, test.roughness)
+		}
+	}
+
+	// a SpecularPower of 128 or more works out fully smooth, which has to be written rather than left to the default.
+	gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{testTriangle(Material{Opacity: 1, SpecularPower: 128})}}, PipelineOptions{Options: Options{VertexColors: true}})
+	data, err := json.Marshal(gltfDoc.Materials[0])
+
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	if want := `"roughnessFactor":0}`; !strings.Contains(string(data), want) {
+		t.Errorf("a SpecularPower of 128 marshals to %s, want it to contain %s", data, want)
+	}
+
+	for _, bad := range []Material{{Opacity: 1, MetallicFactor: 1.5}, {Opacity: 1, RoughnessFactor: -0.25}} {
+		if _, _, err := OptimizeModel(Model{Meshes: []Geometry{testTriangle(bad)}}, PipelineOptions{}); err == nil {
+			t.Errorf("metallic %g and roughness %g were accepted", bad.MetallicFactor, bad.RoughnessFactor)
+		}
+	}
+}
+
+func TestEmbeddedDataURIs(t *testing.T) {
+	// the atlas gives an image data URI as well as the buffer's.
+	gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{testTriangle(Material{DiffuseColor: [3]float32{1, 0, 0}, Opacity: 1})}}, PipelineOptions{})
+	data, err := SerializeEmbeddedGlTF(*gltfDoc)
+
+	if err != nil {
+		t.Fatalf("SerializeEmbeddedGlTF: %v", err)
+	}
+
+	var document struct {
+		Buffers []struct{ URI string }
+		Images  []struct{ URI string }
+	}
+
+	if err := json.Unmarshal(data, &document); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+
+	if len(document.Buffers) != 1 || len(document.Images) != 1 {
+		t.Fatalf("got %d buffers and %d images, want 1 of each", len(document.Buffers), len(document.Images))
+	}
+
+	for _, uri := range []string{document.Buffers[0].URI, document.Images[0].URI} {
+		prefix, payload, found := strings.Cut(uri, ";base64,")
+
+		if !found || !strings.HasPrefix(prefix, "data:") {
+			t.Errorf("%.40s... isn't a base64 data URI", uri)
+			continue
+		}
+
+		if strings.ContainsAny(payload, "\r\n") {
+			t.Errorf("the %s data URI has line breaks in it", prefix)
+		}
+
+		if _, err := base64.StdEncoding.DecodeString(payload); err != nil {
+			t.Errorf("the %s data URI isn't standard base64: %v", prefix, err)
+		}
+	}
+}
+
+func TestMaterialIndexedRejectsWhatItCantKeep(t *testing.T) {
+	plain := func() Geometry { return testTriangle(Material{Opacity: 1}) }
+	opts := PipelineOptions{MaterialIndexed: true}
+
+	if _, _, err := OptimizeModel(Model{Meshes: []Geometry{plain(), plain()}}, opts); err != nil {
+		t.Fatalf("two plain triangles were rejected: %v", err)
+	}
+
+	translated, morphed, lines, translucent := plain(), plain(), plain(), plain()
+	translated.Translation = []float64{1, 2, 3}
+	lines.Mode, lines.Edges = ModeLines, [][2]int32{{0, 1}}
+	translucent.Material.Opacity = 0.5
+
+	for i := range morphed.Vertices {
+		morphed.Vertices[i].Morphs = []MorphDelta{{Position: Vector3{Z: 1}}}
+	}
+
+	tests := []struct {
+		name  string
+		model Model
+	}{
+		{"a transform", Model{Meshes: []Geometry{translated}}},
+		{"scenes", Model{Meshes: []Geometry{plain()}, Scenes: []ModelScene{{Roots: []int{0}}}}},
+		{"morph targets", Model{Meshes: []Geometry{morphed}}},
+		{"lines", Model{Meshes: []Geometry{plain(), lines}}},
+		{"a translucent material", Model{Meshes: []Geometry{plain(), translucent}}},
+		{"a masked material", Model{Meshes: []Geometry{testTriangle(Material{Opacity: 1, AlphaMode: AlphaMask})}}},
+	}
+
+	for _, test := range tests {
+		if _, _, err := OptimizeModel(test.model, opts); err == nil {
+			t.Errorf("a model with %s was accepted", test.name)
+		}
+	}
+}
+
+func TestGroupByMaterialKeepsSpecularAndEmissiveColor(t *testing.T) {
+	shiny := Material{Opacity: 1, SpecularPower: 96}
+	dull := Material{Opacity: 1, SpecularPower: 16}
+	glowing := Material{Opacity: 1, EmissiveMapPath: "glow.png", EmissiveColor: [3]float32{1, 0, 0}}
+	dim := Material{Opacity: 1, EmissiveMapPath: "glow.png", EmissiveColor: [3]float32{0.25, 0, 0}}
+
+	for _, pair := range [][2]Material{{shiny, dull}, {glowing, dim}} {
+		model := Model{Meshes: []Geometry{testTriangle(pair[0]), testTriangle(pair[1])}}
+
+		if groups := groupByMaterial(model, false); len(groups) != 2 {
+			t.Errorf("materials %+v and %+v were merged into %d group", pair[0], pair[1], len(groups))
+		}
+
+		gltfDoc := optimizeForTest(t, model, PipelineOptions{Options: Options{VertexColors: true}})
+
+		if len(gltfDoc.Materials) != 2 {
+			t.Errorf("materials %+v and %+v became %d glTF material", pair[0], pair[1], len(gltfDoc.Materials))
+		}
+	}
+}
+
+func TestWriteGltfZipPacksImageFiles(t *testing.T) {
+	normalMap := writeTestNormalMap(t, t.TempDir())
+	geo := testTriangle(Material{DiffuseColor: [3]float32{1, 0, 0}, Opacity: 1, NormalMapPath: normalMap})
+
+	for i := range geo.Vertices {
+		geo.Vertices[i].UV2 = geo.Vertices[i].UV
+	}
+
+	tests := []struct {
+		name string
+		opts PipelineOptions
+		uris []string
+	}{
+		{"atlas", PipelineOptions{}, []string{"atlas.png", "image_1.png"}},
+		{"vertex colors", PipelineOptions{Options: Options{VertexColors: true}}, []string{"image_0.png"}},
+	}
+
+	for _, test := range tests {
+		gltfDoc, atlas, err := OptimizeModel(Model{Meshes: []Geometry{geo}}, test.opts)
+
+		if err != nil {
+			t.Fatalf("%s: OptimizeModel: %v", test.name, err)
+		}
+
+		archive := new(bytes.Buffer)
+
+		if err := WriteGltfZip(*gltfDoc, bytes.NewBuffer(atlas), archive); err != nil {
+			t.Fatalf("%s: WriteGltfZip: %v", test.name, err)
+		}
+
+		if uri := gltfDoc.Images[len(gltfDoc.Images)-1].URI; uri != filepath.ToSlash(normalMap) {
+			t.Errorf("%s: the caller's normal map URI was changed to %s", test.name, uri)
+		}
+
+		reader, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+
+		if err != nil {
+			t.Fatalf("%s: zip.NewReader: %v", test.name, err)
+		}
+
+		entries := make(map[string]*zip.File)
+
+		for _, file := range reader.File {
+			entries[file.Name] = file
+		}
+
+		document, err := entries["model.gltf"].Open()
+
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		packed, err := LoadGltf(document)
+		document.Close()
+
+		if err != nil {
+			t.Fatalf("%s: LoadGltf: %v", test.name, err)
+		}
+
+		var uris []string
+
+		for _, img := range packed.Images {
+			uris = append(uris, img.URI)
+
+			if entries[img.URI] == nil {
+				t.Errorf("%s: image %s isn't in the archive", test.name, img.URI)
+			}
+		}
+
+		if !reflect.DeepEqual(uris, test.uris) {
+			t.Errorf("%s: image URIs are %v, want %v", test.name, uris, test.uris)
+		}
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/naikrovek/gltf-go
+
+go 1.18
@@ -0,0 +1,179 @@
+package gltf
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+)
+
+// ContentHash returns a stable hex-encoded SHA-256 hash of everything in the Model that ends up in the glTF output:
+// vertex positions, normals, tangents, both UV sets, colors and skin weights, triangle indices, materials, skins, and
+// the hierarchy and scenes, and extras.  Two Models with the same hash produce the same glTF, so the hash can be used as a cache key to skip
+// redundant exports.
+//
+// The order of meshes, vertices and faces is part of the hash because it is also part of the output; reordering them
+// changes the buffer layout even if the rendered result is identical.
+func (model Model) ContentHash() string {
+	h := sha256.New()
+
+	binary.Write(h, binary.LittleEndian, uint32(len(model.Meshes)))
+
+	for _, mesh := range model.Meshes {
+		hashGeometry(h, mesh)
+	}
+
+	binary.Write(h, binary.LittleEndian, uint32(len(model.Scenes)))
+
+	for _, scene := range model.Scenes {
+		hashString(h, scene.Name)
+		binary.Write(h, binary.LittleEndian, uint32(len(scene.Roots)))
+
+		for _, root := range scene.Roots {
+			binary.Write(h, binary.LittleEndian, int64(root))
+		}
+	}
+
+	binary.Write(h, binary.LittleEndian, int64(model.DefaultScene))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ContentHash returns a stable hex-encoded SHA-256 hash of a single Geometry.  See (Model).ContentHash.
+func (geo Geometry) ContentHash() string {
+	h := sha256.New()
+
+	hashGeometry(h, geo)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writes the hashed fields of a Geometry to the supplied hash.  The element counts are written before each slice so
+// that moving data from one slice to its neighbor can't produce the same byte stream.
+func hashGeometry(h hash.Hash, geo Geometry) {
+	binary.Write(h, binary.LittleEndian, uint32(len(geo.Vertices)))
+
+	for _, v := range geo.Vertices {
+		binary.Write(h, binary.LittleEndian, v.Position)
+		binary.Write(h, binary.LittleEndian, v.Normal)
+		binary.Write(h, binary.LittleEndian, v.UV)
+		binary.Write(h, binary.LittleEndian, v.UV2)
+		binary.Write(h, binary.LittleEndian, v.Color)
+		binary.Write(h, binary.LittleEndian, v.Velocity)
+		binary.Write(h, binary.LittleEndian, v.Tangent)
+		binary.Write(h, binary.LittleEndian, v.MaterialIndex)
+		binary.Write(h, binary.LittleEndian, v.Joints)
+		binary.Write(h, binary.LittleEndian, v.Weights)
+		binary.Write(h, binary.LittleEndian, uint32(len(v.Morphs)))
+		binary.Write(h, binary.LittleEndian, v.Morphs)
+	}
+
+	binary.Write(h, binary.LittleEndian, uint32(len(geo.MorphWeights)))
+	binary.Write(h, binary.LittleEndian, geo.MorphWeights)
+
+	binary.Write(h, binary.LittleEndian, geo.Quantization != nil)
+
+	if geo.Quantization != nil {
+		binary.Write(h, binary.LittleEndian, *geo.Quantization)
+	}
+
+	binary.Write(h, binary.LittleEndian, uint32(len(geo.Faces)))
+
+	for _, f := range geo.Faces {
+		binary.Write(h, binary.LittleEndian, f.TriangleIndices)
+	}
+
+	binary.Write(h, binary.LittleEndian, int64(geo.Mode))
+	binary.Write(h, binary.LittleEndian, uint32(len(geo.Edges)))
+	binary.Write(h, binary.LittleEndian, geo.Edges)
+
+	binary.Write(h, binary.LittleEndian, uint32(len(geo.Children)))
+
+	for _, child := range geo.Children {
+		binary.Write(h, binary.LittleEndian, int64(child))
+	}
+
+	for _, transform := range [][]float64{geo.Translation, geo.Rotation, geo.Scale, geo.Matrix} {
+		binary.Write(h, binary.LittleEndian, uint32(len(transform)))
+		binary.Write(h, binary.LittleEndian, transform)
+	}
+
+	binary.Write(h, binary.LittleEndian, geo.Skin != nil)
+
+	if geo.Skin != nil {
+		binary.Write(h, binary.LittleEndian, uint32(len(geo.Skin.Joints)))
+
+		for _, joint := range geo.Skin.Joints {
+			binary.Write(h, binary.LittleEndian, int64(joint))
+		}
+
+		binary.Write(h, binary.LittleEndian, uint32(len(geo.Skin.InverseBindMatrices)))
+		binary.Write(h, binary.LittleEndian, geo.Skin.InverseBindMatrices)
+
+		skeleton := int64(-1)
+
+		if geo.Skin.Skeleton != nil {
+			skeleton = int64(*geo.Skin.Skeleton)
+		}
+
+		binary.Write(h, binary.LittleEndian, skeleton)
+	}
+
+	m := geo.Material
+	binary.Write(h, binary.LittleEndian, m.AmbientColor)
+	binary.Write(h, binary.LittleEndian, m.DiffuseColor)
+	binary.Write(h, binary.LittleEndian, m.SpecularColor)
+	binary.Write(h, binary.LittleEndian, m.SpecularPower)
+	binary.Write(h, binary.LittleEndian, m.EmissiveColor)
+	binary.Write(h, binary.LittleEndian, m.Opacity)
+	binary.Write(h, binary.LittleEndian, m.MetallicFactor)
+	binary.Write(h, binary.LittleEndian, m.RoughnessFactor)
+	binary.Write(h, binary.LittleEndian, m.EmissiveFactor)
+	binary.Write(h, binary.LittleEndian, m.EmissiveStrength)
+	binary.Write(h, binary.LittleEndian, m.DoubleSided)
+	binary.Write(h, binary.LittleEndian, m.Unlit)
+	binary.Write(h, binary.LittleEndian, int64(m.Wrap))
+	binary.Write(h, binary.LittleEndian, int64(m.MagFilter))
+	binary.Write(h, binary.LittleEndian, int64(m.MinFilter))
+	hashString(h, string(m.AlphaMode))
+	binary.Write(h, binary.LittleEndian, m.AlphaCutoff)
+
+	binary.Write(h, binary.LittleEndian, m.AtlasTransform != nil)
+
+	if t := m.AtlasTransform; t != nil {
+		for _, values := range [][]float64{t.Offset, {t.Rotation}, t.Scale} {
+			binary.Write(h, binary.LittleEndian, uint32(len(values)))
+			binary.Write(h, binary.LittleEndian, values)
+		}
+
+		texCoord := int64(-1)
+
+		if t.TexCoord != nil {
+			texCoord = int64(*t.TexCoord)
+		}
+
+		binary.Write(h, binary.LittleEndian, texCoord)
+	}
+
+	// only the paths are hashed, so replacing a texture file's contents doesn't change the hash.
+	for _, path := range []string{m.TexturePath, m.NormalMapPath, m.OcclusionMapPath, m.EmissiveMapPath} {
+		hashString(h, path)
+	}
+
+	// the extras are written out as JSON, so that's what's hashed; encoding/json sorts map keys, so it's stable.
+	extras, err := json.Marshal(geo.Extras)
+
+	if err != nil {
+		// extras that can't be marshalled can't be written either, but they still mustn't collide with no extras.
+		extras = []byte(err.Error())
+	}
+
+	hashString(h, string(extras))
+}
+
+// writes a string to the supplied hash, preceded by its length so that it can't run into whatever follows it.
+func hashString(h hash.Hash, s string) {
+	binary.Write(h, binary.LittleEndian, uint32(len(s)))
+	h.Write([]byte(s))
+}
@@ -0,0 +1,36 @@
+package gltf
+
+import "testing"
+
+func TestContentHashCoversScenesAndExtras(t *testing.T) {
+	base := func() Model {
+		return Model{
+			Meshes: []Geometry{testTriangle(Material{Opacity: 1}), testTriangle(Material{Opacity: 1})},
+			Scenes: []ModelScene{{Name: "first", Roots: []int{0}}, {Name: "second", Roots: []int{1}}},
+		}
+	}
+
+	if base().ContentHash() != base().ContentHash() {
+		t.Fatal("the same Model hashed differently twice")
+	}
+
+	tests := []struct {
+		name   string
+		modify func(model *Model)
+	}{
+		{"the default scene", func(model *Model) { model.DefaultScene = 1 }},
+		{"a scene's name", func(model *Model) { model.Scenes[0].Name = "other" }},
+		{"a scene's roots", func(model *Model) { model.Scenes[1].Roots = []int{0, 1} }},
+		{"no scenes", func(model *Model) { model.Scenes = nil }},
+		{"a Geometry's extras", func(model *Model) { model.Meshes[0].Extras = map[string]interface{}{"lod": 1} }},
+	}
+
+	for _, test := range tests {
+		model := base()
+		test.modify(&model)
+
+		if model.ContentHash() == base().ContentHash() {
+			t.Errorf("changing %s didn't change the hash", test.name)
+		}
+	}
+}
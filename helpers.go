@@ -0,0 +1,237 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// boxEdges lists the 12 edges of a box as pairs of corner indices, where bit 0 of a corner index selects max X, bit 1
+// max Y, and bit 2 max Z.
+var boxEdges = [12][2]uint32{
+	{0, 1}, {2, 3}, {4, 5}, {6, 7}, // along X
+	{0, 2}, {1, 3}, {4, 6}, {5, 7}, // along Y
+	{0, 4}, {1, 5}, {2, 6}, {3, 7}, // along Z
+}
+
+// AddBoundingBoxHelper computes the axis-aligned bounding box of everything in the default scene and adds a node named
+// "BoundingBoxHelper" to it, holding a LINES primitive that draws the box's 12 edges.  Being a separate, named root
+// node, it's easy to hide or strip out later.  The bounds come from each POSITION accessor's min and max, transformed
+// by the node's world matrix.  The new node's index is returned.
+func (gltfDoc *GlTF) AddBoundingBoxHelper() (nodeIndex int, err error) {
+	min, max, ok := gltfDoc.sceneBounds()
+
+	if !ok {
+		return -1, errors.New("the default scene has no geometry with known bounds")
+	}
+
+	corners := make([]Vector3, 8)
+
+	for i := range corners {
+		corners[i] = min
+
+		if i&1 != 0 {
+			corners[i].X = max.X
+		}
+
+		if i&2 != 0 {
+			corners[i].Y = max.Y
+		}
+
+		if i&4 != 0 {
+			corners[i].Z = max.Z
+		}
+	}
+
+	outBuf := gltfDoc.beginAppend()
+
+	positionAccessorIndex := getAccessorIndexFromVector3(outBuf, corners, &gltfDoc.BufferViews, &gltfDoc.Accessors)
+	indicesAccessorIndex := getAccessorIndexFromLines(outBuf, boxEdges[:], &gltfDoc.BufferViews, &gltfDoc.Accessors)
+
+	gltfDoc.endAppend(outBuf)
+
+	// a plain white material, so the helper doesn't pick up the atlas or any other textured material.
+	materialIndex, newMaterials := addMaterial(GltfMaterial{
+		PbrMetallicRoughness: MaterialPbrMetallicRoughness{
+			BaseColorFactor: []float64{1.0, 1.0, 1.0, 1.0},
+			MetallicFactor:  0.0,
+			RoughnessFactor: 1.0,
+		},
+	}, gltfDoc.Materials)
+
+	gltfDoc.Materials = newMaterials
+
+	mode := ModeLines.gltfMode()
+
+	gltfDoc.Meshes = append(gltfDoc.Meshes, Mesh{
+		Name: "BoundingBoxHelper",
+		Primitives: []MeshPrimitive{
+			MeshPrimitive{
+				Attributes: Attributes{"POSITION": positionAccessorIndex},
+				Indices:    &indicesAccessorIndex,
+				Material:   materialIndex,
+				Mode:       &mode,
+			},
+		},
+	})
+
+	gltfDoc.Nodes = append(gltfDoc.Nodes, Node{Mesh: len(gltfDoc.Meshes) - 1, Name: "BoundingBoxHelper"})
+	gltfDoc.addToDefaultScene(len(gltfDoc.Nodes) - 1)
+
+	return len(gltfDoc.Nodes) - 1, nil
+}
+
+// returns the world space bounds of every mesh under the default scene, and whether there were any.
+func (gltfDoc GlTF) sceneBounds() (min, max Vector3, ok bool) {
+	sceneIndex, found := gltfDoc.defaultScene()
+
+	if !found {
+		return min, max, false
+	}
+
+	points := []Vector3{}
+	visited := make(map[int]bool)
+	pending := append([]int{}, gltfDoc.Scenes[sceneIndex].Nodes...)
+
+	for len(pending) > 0 {
+		nodeIndex := pending[0]
+		pending = pending[1:]
+
+		if nodeIndex < 0 || nodeIndex >= len(gltfDoc.Nodes) || visited[nodeIndex] {
+			continue
+		}
+
+		visited[nodeIndex] = true
+		node := gltfDoc.Nodes[nodeIndex]
+		pending = append(pending, node.Children...)
+
+		meshIndex := jsonNumber(node.Mesh)
+
+		if node.Mesh == nil || meshIndex < 0 || meshIndex >= len(gltfDoc.Meshes) {
+			continue
+		}
+
+		world, err := gltfDoc.WorldMatrix(nodeIndex)
+
+		if err != nil {
+			continue
+		}
+
+		for _, primitive := range gltfDoc.Meshes[meshIndex].Primitives {
+			localMin, localMax, found := gltfDoc.positionBounds(primitive)
+
+			if !found {
+				continue
+			}
+
+			// transforming the 8 corners of the local box keeps the world box correct under rotation.
+			for i := 0; i < 8; i++ {
+				corner := localMin
+
+				if i&1 != 0 {
+					corner.X = localMax.X
+				}
+
+				if i&2 != 0 {
+					corner.Y = localMax.Y
+				}
+
+				if i&4 != 0 {
+					corner.Z = localMax.Z
+				}
+
+				points = append(points, transformPoint(world, corner))
+			}
+		}
+	}
+
+	if len(points) == 0 {
+		return min, max, false
+	}
+
+	min, max = bounds(points)
+
+	return min, max, true
+}
+
+// returns the local bounds of a primitive's POSITION data, from the accessor's min and max or from its data.
+func (gltfDoc GlTF) positionBounds(primitive MeshPrimitive) (min, max Vector3, ok bool) {
+	accessorIndex, found := primitive.Attributes["POSITION"]
+
+	if !found || accessorIndex < 0 || accessorIndex >= len(gltfDoc.Accessors) {
+		return min, max, false
+	}
+
+	accessor := gltfDoc.Accessors[accessorIndex]
+
+	if len(accessor.Min) == 3 && len(accessor.Max) == 3 {
+		min = Vector3{X: accessor.Min[0], Y: accessor.Min[1], Z: accessor.Min[2]}
+		max = Vector3{X: accessor.Max[0], Y: accessor.Max[1], Z: accessor.Max[2]}
+
+		return min, max, true
+	}
+
+	positions, found := gltfDoc.readVector3Floats(accessor)
+
+	if !found {
+		return min, max, false
+	}
+
+	min, max = bounds(positions)
+
+	return min, max, true
+}
+
+// transforms a point by a column-major matrix.
+func transformPoint(m [16]float64, p Vector3) Vector3 {
+	x, y, z := float64(p.X), float64(p.Y), float64(p.Z)
+
+	w := m[3]*x + m[7]*y + m[11]*z + m[15]
+
+	if w == 0 || math.IsNaN(w) {
+		w = 1
+	}
+
+	return Vector3{
+		X: float32((m[0]*x + m[4]*y + m[8]*z + m[12]) / w),
+		Y: float32((m[1]*x + m[5]*y + m[9]*z + m[13]) / w),
+		Z: float32((m[2]*x + m[6]*y + m[10]*z + m[14]) / w),
+	}
+}
+
+// Appends line segment indices to the supplied bytes.Buffer and adds the BufferView and Accessor for them, like
+// getAccessorIndexFromIndices does for triangles.
+func getAccessorIndexFromLines(outBuf *bytes.Buffer, lines [][2]uint32, gltfBufferViews *[]BufferView, gltfAccessors *[]Accessor) (accessorIndex int) {
+	padToAlignment(outBuf)
+	byteOffset := outBuf.Len()
+	min, max := uint32(math.MaxUint32), uint32(0)
+
+	for _, line := range lines {
+		binary.Write(outBuf, binary.LittleEndian, line)
+
+		for _, index := range line {
+			min = uint32(math.Min(float64(min), float64(index)))
+			max = uint32(math.Max(float64(max), float64(index)))
+		}
+	}
+
+	*gltfBufferViews = append(*gltfBufferViews, BufferView{
+		Buffer:     0,
+		ByteOffset: byteOffset,
+		ByteLength: outBuf.Len() - byteOffset,
+		Target:     34963,
+	})
+
+	*gltfAccessors = append(*gltfAccessors, Accessor{
+		BufferView:    lastBufferView(*gltfBufferViews),
+		ByteOffset:    0,
+		ComponentType: UnsignedInt,
+		Count:         len(lines) * 2,
+		Type:          Scalar,
+		Max:           []float32{float32(max)},
+		Min:           []float32{float32(min)},
+	})
+
+	return len(*gltfAccessors) - 1
+}
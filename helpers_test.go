@@ -0,0 +1,111 @@
+package gltf
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestAddBoundingBoxHelper(t *testing.T) {
+	half := math.Sqrt(0.5)
+
+	tests := []struct {
+		name     string
+		modify   func(geo *Geometry)
+		min, max Vector3
+	}{
+		{"untransformed", func(geo *Geometry) {}, Vector3{}, Vector3{X: 1, Y: 1}},
+		{"translated and scaled", func(geo *Geometry) {
+			geo.Translation, geo.Scale = []float64{10, 0, 0}, []float64{2, 2, 2}
+		}, Vector3{X: 10}, Vector3{X: 12, Y: 2}},
+		// a quarter turn about Z takes the triangle from +X over to -X.
+		{"rotated", func(geo *Geometry) { geo.Rotation = []float64{0, 0, half, half} }, Vector3{X: -1}, Vector3{Y: 1}},
+	}
+
+	for _, test := range tests {
+		geo := testTriangle(Material{Opacity: 1})
+		test.modify(&geo)
+		gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{geo}}, PipelineOptions{Options: Options{VertexColors: true}})
+
+		nodeIndex, err := gltfDoc.AddBoundingBoxHelper()
+
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		primitive := gltfDoc.Meshes[jsonNumber(gltfDoc.Nodes[nodeIndex].Mesh)].Primitives[0]
+
+		if primitive.Mode == nil || *primitive.Mode != ModeLines.gltfMode() {
+			t.Errorf("%s: the helper's mode is %v, want LINES", test.name, primitive.Mode)
+		}
+
+		positions := readFloatsForTest(t, gltfDoc, primitive.Attributes["POSITION"])
+
+		if len(positions) != 8*3 {
+			t.Errorf("%s: the helper has %d vertices, want 8", test.name, len(positions)/3)
+			continue
+		}
+
+		// vertex v is the corner whose bits 0, 1 and 2 pick the max X, Y and Z, which is what the segments index by.
+		for v := 0; v < 8; v++ {
+			want := test.min
+
+			if v&1 != 0 {
+				want.X = test.max.X
+			}
+
+			if v&2 != 0 {
+				want.Y = test.max.Y
+			}
+
+			if v&4 != 0 {
+				want.Z = test.max.Z
+			}
+
+			if got := positions[3*v : 3*v+3]; !near(got[0], want.X) || !near(got[1], want.Y) || !near(got[2], want.Z) {
+				t.Errorf("%s: corner %d is at %v, want %v", test.name, v, got, want)
+			}
+		}
+
+		indices := gltfDoc.Accessors[*primitive.Indices]
+		view := gltfDoc.BufferViews[*indices.BufferView]
+		data := gltfDoc.Buffers[0].Bytes[view.ByteOffset+indices.ByteOffset:]
+
+		if indices.Count != 2*12 {
+			t.Errorf("%s: the helper has %d line indices, want 24 for 12 segments", test.name, indices.Count)
+			continue
+		}
+
+		// every segment has to run between two corners that differ along exactly one axis, and none may repeat.
+		segments := make(map[[2]uint32]bool)
+
+		for s := 0; s < 12; s++ {
+			a, b := binary.LittleEndian.Uint32(data[8*s:]), binary.LittleEndian.Uint32(data[8*s+4:])
+
+			if a >= 8 || b >= 8 {
+				t.Errorf("%s: segment %d joins vertices %d and %d, past the 8 corners", test.name, s, a, b)
+				continue
+			}
+
+			if differ := a ^ b; differ != 1 && differ != 2 && differ != 4 {
+				t.Errorf("%s: segment %d joins corners %d and %d, which isn't an edge of the box", test.name, s, a, b)
+			}
+
+			if a > b {
+				a, b = b, a
+			}
+
+			segments[[2]uint32{a, b}] = true
+		}
+
+		if len(segments) != 12 {
+			t.Errorf("%s: the helper has %d distinct segments, want 12", test.name, len(segments))
+		}
+	}
+}
+
+// reports whether two floats are equal, give or take rounding in a transform.
+func near(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-5
+}
@@ -0,0 +1,189 @@
+package gltf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A Model is a flat list of Geometry unless any of them has Children or a transform, in which case each Geometry is a
+// node of a tree: its Children are indices into Model.Meshes, and its transform is relative to its parent.  The
+// Geometry that are nobody's child are the roots of the scene.  A hierarchical Model keeps one glTF mesh and node per
+// Geometry, since merging Geometry with different transforms would put them in the wrong place.
+
+// reports whether the Model has scenes, or any Geometry in it has children, a transform, a skin or morph targets, so the
+// Model needs a node per Geometry.
+func (model Model) hasHierarchy() bool {
+	if len(model.Scenes) > 0 {
+		return true
+	}
+
+	for _, geo := range model.Meshes {
+		if len(geo.Children) > 0 || geo.Translation != nil || geo.Rotation != nil || geo.Scale != nil || geo.Matrix != nil || geo.Skin != nil || hasMorphs(geo) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reports whether a Geometry is only there to group its children or to be a joint, and has nothing of its own to draw.
+func (geo Geometry) isGroup() bool {
+	hasTransform := geo.Translation != nil || geo.Rotation != nil || geo.Scale != nil || geo.Matrix != nil
+
+	return len(geo.Vertices) == 0 && len(geo.Faces) == 0 && (len(geo.Children) > 0 || hasTransform)
+}
+
+// makes sure the Model's Children form a tree, where every child exists, has only one parent, and isn't its own
+// ancestor, that its scenes only have roots of that tree at their roots, and that its skins' joints exist.
+func (model Model) checkHierarchy() error {
+	parents := make([]int, len(model.Meshes))
+
+	for i := range parents {
+		parents[i] = -1
+	}
+
+	for p, geo := range model.Meshes {
+		for _, child := range geo.Children {
+			if child < 0 || child >= len(model.Meshes) {
+				return fmt.Errorf("mesh %d has child %d, which does not exist", p, child)
+			}
+
+			if parents[child] != -1 {
+				return fmt.Errorf("mesh %d has two parents, %d and %d", child, parents[child], p)
+			}
+
+			parents[child] = p
+		}
+	}
+
+	for i := range model.Meshes {
+		visited := make(map[int]bool)
+
+		for m := i; m >= 0; m = parents[m] {
+			if visited[m] {
+				return fmt.Errorf("mesh %d is its own ancestor", m)
+			}
+
+			visited[m] = true
+		}
+	}
+
+	for s, scene := range model.Scenes {
+		for _, root := range scene.Roots {
+			if root < 0 || root >= len(model.Meshes) {
+				return fmt.Errorf("scene %d has root %d, which does not exist", s, root)
+			}
+
+			if parents[root] != -1 {
+				return fmt.Errorf("scene %d has root %d, which is a child of mesh %d", s, root, parents[root])
+			}
+		}
+	}
+
+	for m, geo := range model.Meshes {
+		if geo.Skin == nil {
+			continue
+		}
+
+		skin := Skin{Joints: geo.Skin.Joints, Skeleton: geo.Skin.Skeleton}
+
+		if err := checkSkin(skin, len(geo.Skin.InverseBindMatrices), len(model.Meshes)); err != nil {
+			return fmt.Errorf("mesh %d: %w", m, err)
+		}
+
+		if geo.isGroup() || !hasWeights(geo) {
+			return fmt.Errorf("mesh %d has a skin, but none of its vertices are weighted to its joints", m)
+		}
+	}
+
+	if len(model.Scenes) > 0 && (model.DefaultScene < 0 || model.DefaultScene >= len(model.Scenes)) {
+		return fmt.Errorf("default scene %d does not exist; there are %d scenes", model.DefaultScene, len(model.Scenes))
+	}
+
+	return nil
+}
+
+// makes sure a transform has the right number of components, and isn't given both as a matrix and as TRS, which the
+// spec forbids.
+func checkTransform(translation, rotation, scale, matrix []float64) error {
+	if matrix != nil && (translation != nil || rotation != nil || scale != nil) {
+		return errors.New("transform has both a matrix and a translation, rotation or scale; use one or the other")
+	}
+
+	for _, t := range []struct {
+		name       string
+		values     []float64
+		components int
+	}{
+		{"translation", translation, 3},
+		{"rotation", rotation, 4},
+		{"scale", scale, 3},
+		{"matrix", matrix, 16},
+	} {
+		if t.values != nil && len(t.values) != t.components {
+			return fmt.Errorf("%s has %d components; it needs %d", t.name, len(t.values), t.components)
+		}
+	}
+
+	return nil
+}
+
+// returns the indices of the Geometry that aren't anyone's child, in order.
+func (model Model) roots() []int {
+	isChild := make(map[int]bool)
+
+	for _, geo := range model.Meshes {
+		for _, child := range geo.Children {
+			isChild[child] = true
+		}
+	}
+
+	roots := []int{}
+
+	for i := range model.Meshes {
+		if !isChild[i] {
+			roots = append(roots, i)
+		}
+	}
+
+	return roots
+}
+
+// builds a node for each Geometry of a hierarchical Model, at the same index, with a mesh of its own unless it's just a
+// group.  The associations line up with model.Meshes.
+func hierarchyNodes(model Model, associations []meshInfoAssociation) (meshes []Mesh, nodes []Node) {
+	for i, geo := range model.Meshes {
+		// copied, because NormalizeRotations and Repair change node transforms in place.
+		node := Node{
+			Children:    append([]int(nil), geo.Children...),
+			Translation: append([]float64(nil), geo.Translation...),
+			Rotation:    append([]float64(nil), geo.Rotation...),
+			Scale:       append([]float64(nil), geo.Scale...),
+			Matrix:      append([]float64(nil), geo.Matrix...),
+		}
+
+		if !geo.isGroup() {
+			meshes = append(meshes, Mesh{Primitives: []MeshPrimitive{meshPrimitive(associations[i])}, Weights: append([]float64(nil), geo.MorphWeights...)})
+			node.Mesh = len(meshes) - 1
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	// the dequantization transform mustn't apply to the node's children, or change the node if it's a joint, so a
+	// quantized mesh goes on a child node of its own.  It's added after the rest to keep them at the Geometry's indices.
+	for i, geo := range model.Meshes {
+		if geo.Quantization == nil || geo.isGroup() {
+			continue
+		}
+
+		child := geo.Quantization.node()
+		child.Mesh = nodes[i].Mesh
+		nodes[i].Mesh = nil
+
+		nodes = append(nodes, child)
+		nodes[i].Children = append(nodes[i].Children, len(nodes)-1)
+	}
+
+	return meshes, nodes
+}
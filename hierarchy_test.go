@@ -0,0 +1,56 @@
+package gltf
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNodeTranslation(t *testing.T) {
+	geo := testTriangle(Material{Opacity: 1})
+	geo.Translation = []float64{1, 2, 3}
+	gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{geo}}, PipelineOptions{Options: Options{VertexColors: true}})
+
+	data, err := json.Marshal(gltfDoc.Nodes)
+
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	if !strings.Contains(string(data), `"translation":[1,2,3]`) {
+		t.Errorf("nodes marshal to %s, want a translation of [1,2,3]", data)
+	}
+
+	if strings.Contains(string(data), `"matrix"`) || strings.Contains(string(data), `"rotation"`) {
+		t.Errorf("nodes marshal to %s, which has transforms that weren't set", data)
+	}
+}
+
+func TestMatrixWithTRSRejected(t *testing.T) {
+	identity := []float64{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
+
+	tests := []struct {
+		name   string
+		modify func(geo *Geometry)
+	}{
+		{"matrix and translation", func(geo *Geometry) { geo.Matrix, geo.Translation = identity, []float64{1, 2, 3} }},
+		{"matrix and scale", func(geo *Geometry) { geo.Matrix, geo.Scale = identity, []float64{2, 2, 2} }},
+		{"short rotation", func(geo *Geometry) { geo.Rotation = []float64{0, 0, 1} }},
+	}
+
+	for _, test := range tests {
+		geo := testTriangle(Material{Opacity: 1})
+		test.modify(&geo)
+
+		if _, _, err := OptimizeModel(Model{Meshes: []Geometry{geo}}, PipelineOptions{}); err == nil {
+			t.Errorf("%s was accepted", test.name)
+		}
+	}
+
+	geo := testTriangle(Material{Opacity: 1})
+	geo.Matrix = identity
+
+	if _, _, err := OptimizeModel(Model{Meshes: []Geometry{geo}}, PipelineOptions{}); err != nil {
+		t.Errorf("a matrix on its own was rejected: %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The images a Material names by path, such as its NormalMapPath, start out in the document as URIs pointing at the
+// files.  EmbedImages swaps those for the images themselves, so the output doesn't depend on files next to it.
+
+// the signatures at the start of the image formats glTF allows.
+var imageSignatures = []struct {
+	signature []byte
+	mimeType  string
+}{
+	{[]byte("\x89PNG\r\n\x1a\n"), "image/png"},
+	{[]byte{0xff, 0xd8, 0xff}, "image/jpeg"},
+}
+
+// returns the MIME type of the supplied image data, from its signature, or an error if it's neither PNG nor JPEG.
+func imageMimeType(data []byte) (string, error) {
+	for _, s := range imageSignatures {
+		if bytes.HasPrefix(data, s.signature) {
+			return s.mimeType, nil
+		}
+	}
+
+	return "", errors.New("image is neither PNG nor JPEG; glTF only allows those")
+}
+
+// EmbedImages reads each image file the document refers to by a relative path or file URI and embeds it in the form
+// the output format needs: a buffer view in the first buffer for OutputGlb, with the image's MimeType set, and a base64
+// data URI for OutputEmbedded.  OutputSeparateBin keeps the paths, so the files have to be shipped alongside the .gltf,
+// but they're still read so that a broken one is caught.  Images that are already data URIs or buffer views are left
+// as they are.  An image that can't be read, or that isn't a PNG or JPEG, is an error.
+func (gltfDoc *GlTF) EmbedImages(format OutputFormat) error {
+	for i := range gltfDoc.Images {
+		img := &gltfDoc.Images[i]
+
+		if !img.isFile() {
+			continue
+		}
+
+		data, mimeType, err := img.readFile()
+
+		if err != nil {
+			return fmt.Errorf("images[%d]: %w", i, err)
+		}
+
+		switch format {
+		case OutputGlb:
+			viewIndex := gltfDoc.addImageBufferView(data)
+
+			img.URI = ""
+			img.BufferView = &viewIndex
+			img.MimeType = mimeType
+		case OutputEmbedded:
+			img.URI = "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+		}
+	}
+
+	return nil
+}
+
+// reports whether the image refers to a file by a path or file URI, rather than being a data URI or a buffer view.
+func (img GltfImage) isFile() bool {
+	return img.BufferView == nil && img.URI != "" && !strings.HasPrefix(img.URI, "data:")
+}
+
+// reads the file the image refers to, returning its data and MIME type, or an error if it can't be read or isn't a PNG
+// or JPEG.
+func (img GltfImage) readFile() ([]byte, string, error) {
+	data, err := os.ReadFile(filepath.FromSlash(strings.TrimPrefix(img.URI, "file://")))
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	mimeType, err := imageMimeType(data)
+
+	if err != nil {
+		return nil, "", fmt.Errorf("%q: %w", img.URI, err)
+	}
+
+	return data, mimeType, nil
+}
+
+// appends the supplied data to the first buffer, which is the GLB BIN chunk, adding the buffer if the document has none,
+// and returns the index of a new buffer view holding it.
+func (gltfDoc *GlTF) addImageBufferView(data []byte) int {
+	if len(gltfDoc.Buffers) == 0 {
+		gltfDoc.Buffers = append(gltfDoc.Buffers, GltfBuffer{})
+	}
+
+	buffer := &gltfDoc.Buffers[0]
+
+	// images have no alignment requirement, but starting on a 4 byte boundary keeps anything appended after aligned.
+	for len(buffer.Bytes)%4 != 0 {
+		buffer.Bytes = append(buffer.Bytes, 0)
+	}
+
+	gltfDoc.BufferViews = append(gltfDoc.BufferViews, BufferView{
+		Buffer:     0,
+		ByteOffset: len(buffer.Bytes),
+		ByteLength: len(data),
+	})
+
+	buffer.Bytes = append(buffer.Bytes, data...)
+	buffer.ByteLength = len(buffer.Bytes)
+
+	return len(gltfDoc.BufferViews) - 1
+}
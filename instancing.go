@@ -0,0 +1,165 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// The EXT_mesh_gpu_instancing extension lets a single node draw its mesh many times, with a translation, rotation and
+// scale per instance stored in accessors.  Viewers without the extension draw the mesh once, untransformed.
+// See https://github.com/KhronosGroup/glTF/tree/main/extensions/2.0/Vendor/EXT_mesh_gpu_instancing
+
+const instancingExtensionName = "EXT_mesh_gpu_instancing"
+
+type instancingExtension struct {
+	Attributes map[string]int `json:"attributes"`
+}
+
+// AddInstances places copies of the mesh at meshIndex at each of the supplied column-major transforms, and adds them
+// to the default scene.  If useGPUInstancing is false, each copy gets its own node, which works everywhere.  If it's
+// true, a single node is added with EXT_mesh_gpu_instancing TRANSLATION, ROTATION and SCALE accessors decomposed from
+// the transforms, which is far smaller for large instance counts but needs a viewer that supports the extension.
+func (gltfDoc *GlTF) AddInstances(meshIndex int, transforms [][16]float64, useGPUInstancing bool) error {
+	if meshIndex < 0 || meshIndex >= len(gltfDoc.Meshes) {
+		return fmt.Errorf("mesh %d does not exist", meshIndex)
+	}
+
+	if len(transforms) == 0 {
+		return errors.New("no instance transforms supplied")
+	}
+
+	if !useGPUInstancing {
+		for _, transform := range transforms {
+			gltfDoc.Nodes = append(gltfDoc.Nodes, Node{Mesh: meshIndex, Matrix: append([]float64{}, transform[:]...)})
+			gltfDoc.addToDefaultScene(len(gltfDoc.Nodes) - 1)
+		}
+
+		return nil
+	}
+
+	translations := make([]float32, 0, len(transforms)*3)
+	rotations := make([]float32, 0, len(transforms)*4)
+	scales := make([]float32, 0, len(transforms)*3)
+
+	for _, transform := range transforms {
+		t, r, s := decomposeMatrix(transform)
+
+		translations = append(translations, float32(t[0]), float32(t[1]), float32(t[2]))
+		rotations = append(rotations, float32(r[0]), float32(r[1]), float32(r[2]), float32(r[3]))
+		scales = append(scales, float32(s[0]), float32(s[1]), float32(s[2]))
+	}
+
+	outBuf := gltfDoc.beginAppend()
+
+	attributes := map[string]int{
+		"TRANSLATION": getAccessorIndexFromFloats(outBuf, translations, Vec3, &gltfDoc.BufferViews, &gltfDoc.Accessors),
+		"ROTATION":    getAccessorIndexFromFloats(outBuf, rotations, Vec4, &gltfDoc.BufferViews, &gltfDoc.Accessors),
+		"SCALE":       getAccessorIndexFromFloats(outBuf, scales, Vec3, &gltfDoc.BufferViews, &gltfDoc.Accessors),
+	}
+
+	gltfDoc.endAppend(outBuf)
+
+	node := Node{Mesh: meshIndex}
+
+	var err error
+
+	if node.Extensions, err = setExtension(node.Extensions, instancingExtensionName, instancingExtension{Attributes: attributes}); err != nil {
+		return err
+	}
+
+	gltfDoc.Nodes = append(gltfDoc.Nodes, node)
+	gltfDoc.addToDefaultScene(len(gltfDoc.Nodes) - 1)
+	gltfDoc.useExtension(instancingExtensionName)
+
+	return nil
+}
+
+// adds a root node to the document's default scene, if it has one.
+func (gltfDoc *GlTF) addToDefaultScene(nodeIndex int) {
+	if sceneIndex, ok := gltfDoc.defaultScene(); ok {
+		gltfDoc.Scenes[sceneIndex].Nodes = append(gltfDoc.Scenes[sceneIndex].Nodes, nodeIndex)
+	}
+}
+
+// Appends float elements of the supplied type to the supplied bytes.Buffer, and adds a BufferView and an Accessor for
+// them.  Unlike the vertex attribute versions, the BufferView gets no target or stride, which is what non-vertex data
+// such as instance transforms and animation keyframes need.
+func getAccessorIndexFromFloats(outBuf *bytes.Buffer, data []float32, accessorType AccessorType, gltfBufferViews *[]BufferView, gltfAccessors *[]Accessor) (accessorIndex int) {
+	padToAlignment(outBuf)
+	byteOffset := outBuf.Len()
+	binary.Write(outBuf, binary.LittleEndian, data)
+	byteLength := outBuf.Len() - byteOffset
+
+	*gltfBufferViews = append(*gltfBufferViews, BufferView{
+		Buffer:     0,
+		ByteOffset: byteOffset,
+		ByteLength: byteLength,
+	})
+
+	*gltfAccessors = append(*gltfAccessors, Accessor{
+		BufferView:    lastBufferView(*gltfBufferViews),
+		ByteOffset:    0,
+		ComponentType: Float,
+		Count:         len(data) / componentCount(accessorType),
+		Type:          accessorType,
+	})
+
+	return len(*gltfAccessors) - 1
+}
+
+// splits a column-major affine transform into a translation, a unit quaternion (x, y, z, w) and a scale.  Shear can't
+// be represented and is lost.  A mirroring transform is represented with a negative X scale.
+func decomposeMatrix(m [16]float64) (t [3]float64, r [4]float64, s [3]float64) {
+	t = [3]float64{m[12], m[13], m[14]}
+
+	for col := 0; col < 3; col++ {
+		s[col] = math.Sqrt(m[col*4]*m[col*4] + m[col*4+1]*m[col*4+1] + m[col*4+2]*m[col*4+2])
+	}
+
+	det := m[0]*(m[5]*m[10]-m[9]*m[6]) - m[4]*(m[1]*m[10]-m[9]*m[2]) + m[8]*(m[1]*m[6]-m[5]*m[2])
+
+	if det < 0 {
+		s[0] = -s[0]
+	}
+
+	// the rotation matrix, by row and column, with the scale taken out.
+	rot := func(row, col int) float64 {
+		if s[col] == 0 {
+			if row == col {
+				return 1
+			}
+
+			return 0
+		}
+
+		return m[col*4+row] / s[col]
+	}
+
+	trace := rot(0, 0) + rot(1, 1) + rot(2, 2)
+
+	switch {
+	case trace > 0:
+		k := 0.5 / math.Sqrt(trace+1)
+		r = [4]float64{(rot(2, 1) - rot(1, 2)) * k, (rot(0, 2) - rot(2, 0)) * k, (rot(1, 0) - rot(0, 1)) * k, 0.25 / k}
+	case rot(0, 0) > rot(1, 1) && rot(0, 0) > rot(2, 2):
+		k := 2 * math.Sqrt(1+rot(0, 0)-rot(1, 1)-rot(2, 2))
+		r = [4]float64{0.25 * k, (rot(0, 1) + rot(1, 0)) / k, (rot(0, 2) + rot(2, 0)) / k, (rot(2, 1) - rot(1, 2)) / k}
+	case rot(1, 1) > rot(2, 2):
+		k := 2 * math.Sqrt(1+rot(1, 1)-rot(0, 0)-rot(2, 2))
+		r = [4]float64{(rot(0, 1) + rot(1, 0)) / k, 0.25 * k, (rot(1, 2) + rot(2, 1)) / k, (rot(0, 2) - rot(2, 0)) / k}
+	default:
+		k := 2 * math.Sqrt(1+rot(2, 2)-rot(0, 0)-rot(1, 1))
+		r = [4]float64{(rot(0, 2) + rot(2, 0)) / k, (rot(1, 2) + rot(2, 1)) / k, 0.25 * k, (rot(1, 0) - rot(0, 1)) / k}
+	}
+
+	length := math.Sqrt(r[0]*r[0] + r[1]*r[1] + r[2]*r[2] + r[3]*r[3])
+
+	for i := range r {
+		r[i] /= length
+	}
+
+	return t, r, s
+}
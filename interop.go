@@ -0,0 +1,145 @@
+package gltf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ApplyInteropProfile rearranges a document into a canonical layout that glTF-Transform and similar tools can work on
+// without re-packing it first.  Geometry and materials are not changed.  The profile enforces exactly this:
+//
+//   - the document has at most one buffer, and every buffer view points into it;
+//   - vertex attribute buffer views (target ARRAY_BUFFER) come first, followed by views with no target, followed by
+//     index buffer views (target ELEMENT_ARRAY_BUFFER); within each group the original order is kept;
+//   - every buffer view starts on a 4 byte boundary and the buffer contains no other gaps;
+//   - every accessor used by a mesh primitive has a name, "mesh<m>_primitive<p>_<ATTRIBUTE>" for attributes,
+//     "mesh<m>_primitive<p>_target<t>_<ATTRIBUTE>" for morph target attributes and "mesh<m>_primitive<p>_indices" for
+//     indices.  Accessors that already have a name keep it.
+func ApplyInteropProfile(gltfDoc *GlTF) error {
+	if len(gltfDoc.Buffers) > 1 {
+		return fmt.Errorf("document has %d buffers; the interop profile requires a single buffer", len(gltfDoc.Buffers))
+	}
+
+	if len(gltfDoc.Buffers) == 0 {
+		if len(gltfDoc.BufferViews) > 0 {
+			return errors.New("document has buffer views but no buffer")
+		}
+
+		return nil
+	}
+
+	for i, accessor := range gltfDoc.Accessors {
+		views := []int{}
+
+		// an accessor without a buffer view is all zeros, so it has nothing to reference.
+		if accessor.BufferView != nil {
+			views = append(views, *accessor.BufferView)
+		}
+
+		if accessor.Sparse != nil {
+			views = append(views, accessor.Sparse.Indices.BufferView, accessor.Sparse.Values.BufferView)
+		}
+
+		for _, view := range views {
+			if view < 0 || view >= len(gltfDoc.BufferViews) {
+				return fmt.Errorf("accessor %d references buffer view %d, which does not exist", i, view)
+			}
+		}
+	}
+
+	nameAccessors(gltfDoc)
+
+	order := make([]int, len(gltfDoc.BufferViews))
+
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return bufferViewRank(gltfDoc.BufferViews[order[i]]) < bufferViewRank(gltfDoc.BufferViews[order[j]])
+	})
+
+	oldBytes := gltfDoc.Buffers[0].Bytes
+	newBuf := new(bytes.Buffer)
+	newViews := make([]BufferView, 0, len(order))
+	newIndex := make([]int, len(order))
+
+	for i, old := range order {
+		view := gltfDoc.BufferViews[old]
+
+		if view.Buffer != 0 || view.ByteOffset+view.ByteLength > len(oldBytes) {
+			return fmt.Errorf("buffer view %d does not fit in the document's buffer", old)
+		}
+
+		padToAlignment(newBuf)
+
+		data := oldBytes[view.ByteOffset : view.ByteOffset+view.ByteLength]
+		view.ByteOffset = newBuf.Len()
+		newBuf.Write(data)
+
+		newViews = append(newViews, view)
+		newIndex[old] = i
+	}
+
+	for i := range gltfDoc.Accessors {
+		accessor := &gltfDoc.Accessors[i]
+
+		if accessor.BufferView != nil {
+			accessor.BufferView = &newIndex[*accessor.BufferView]
+		}
+
+		if accessor.Sparse != nil {
+			sparse := *accessor.Sparse
+			sparse.Indices.BufferView = newIndex[sparse.Indices.BufferView]
+			sparse.Values.BufferView = newIndex[sparse.Values.BufferView]
+			accessor.Sparse = &sparse
+		}
+	}
+
+	gltfDoc.BufferViews = newViews
+	gltfDoc.Buffers[0].Bytes = newBuf.Bytes()
+	gltfDoc.Buffers[0].ByteLength = newBuf.Len()
+
+	return nil
+}
+
+// vertex data first, then untargeted views, then indices.
+func bufferViewRank(view BufferView) int {
+	switch view.Target {
+	case 34962:
+		return 0
+	case 34963:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// gives every accessor used by a mesh primitive a descriptive name, unless it has one already.
+func nameAccessors(gltfDoc *GlTF) {
+	name := func(accessorIndex int, accessorName string) {
+		if accessorIndex >= 0 && accessorIndex < len(gltfDoc.Accessors) && gltfDoc.Accessors[accessorIndex].Name == nil {
+			gltfDoc.Accessors[accessorIndex].Name = accessorName
+		}
+	}
+
+	for m, mesh := range gltfDoc.Meshes {
+		for p, primitive := range mesh.Primitives {
+			if primitive.Indices != nil {
+				name(*primitive.Indices, fmt.Sprintf("mesh%d_primitive%d_indices", m, p))
+			}
+
+			for attribute, accessorIndex := range primitive.Attributes {
+				name(accessorIndex, fmt.Sprintf("mesh%d_primitive%d_%s", m, p, attribute))
+			}
+
+			for t, target := range primitive.Targets {
+				for attribute, accessorIndex := range target {
+					name(accessorIndex, fmt.Sprintf("mesh%d_primitive%d_target%d_%s", m, p, t, attribute))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package gltf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// The KHR_lights_punctual extension stores directional, point and spot lights in a document-level array, and nodes
+// place a light in the scene by pointing at it by index.  A light shines down its node's -Z axis.
+// See https://github.com/KhronosGroup/glTF/tree/main/extensions/2.0/Khronos/KHR_lights_punctual
+
+const lightsExtensionName = "KHR_lights_punctual"
+
+// Light is one KHR_lights_punctual light.  Type is "directional", "point" or "spot".  Color is linear RGB, and
+// Intensity is in lux for directional lights and candela for the others; leaving them out gives white and 1.  Range is
+// how far a point or spot light reaches, 0 meaning infinitely, and directional lights can't have one.  Spot is only
+// set for spot lights.
+type Light struct {
+	Color     []float64  `json:"color,omitempty"`
+	Intensity float64    `json:"intensity,omitempty" validator:"gte=0"`
+	Name      string     `json:"name,omitempty"`
+	Range     float64    `json:"range,omitempty" validator:"gte=0"`
+	Spot      *LightSpot `json:"spot,omitempty"`
+	Type      string     `json:"type"`
+}
+
+// LightSpot holds a spot light's cone angles, in radians from the light's direction.  The light is at full intensity
+// inside InnerConeAngle and fades out by OuterConeAngle.  Zero values leave the spec defaults of 0 and pi / 4.
+type LightSpot struct {
+	InnerConeAngle float64 `json:"innerConeAngle,omitempty" validator:"gte=0"`
+	OuterConeAngle float64 `json:"outerConeAngle,omitempty" validator:"gte=0"`
+}
+
+// lightsExtension is the document-level KHR_lights_punctual object.
+type lightsExtension struct {
+	Lights []Light `json:"lights"`
+}
+
+// lightReference is the node-level KHR_lights_punctual object.
+type lightReference struct {
+	Light int `json:"light"`
+}
+
+// AddLight checks the supplied Light, adds it to the document's KHR_lights_punctual lights, and attaches it to the
+// node at nodeIndex.  The new light's index is returned.
+func (gltfDoc *GlTF) AddLight(light Light, nodeIndex int) (lightIndex int, err error) {
+	if nodeIndex < 0 || nodeIndex >= len(gltfDoc.Nodes) {
+		return -1, fmt.Errorf("node %d does not exist", nodeIndex)
+	}
+
+	// the spot property is required for spot lights, but an empty one is fine; it just means the default cone.
+	if light.Type == "spot" && light.Spot == nil {
+		light.Spot = &LightSpot{}
+	}
+
+	if err := light.check(); err != nil {
+		return -1, err
+	}
+
+	lights, err := gltfDoc.lightsExtension()
+
+	if err != nil {
+		return -1, err
+	}
+
+	lights.Lights = append(lights.Lights, light)
+	lightIndex = len(lights.Lights) - 1
+
+	node := &gltfDoc.Nodes[nodeIndex]
+
+	if node.Extensions, err = setExtension(node.Extensions, lightsExtensionName, lightReference{Light: lightIndex}); err != nil {
+		return -1, err
+	}
+
+	if gltfDoc.Extensions, err = setExtension(gltfDoc.Extensions, lightsExtensionName, lights); err != nil {
+		return -1, err
+	}
+
+	gltfDoc.useExtension(lightsExtensionName)
+
+	return lightIndex, nil
+}
+
+// Lights returns the document's KHR_lights_punctual lights, whether the document was built in Go or decoded from JSON.
+func (gltfDoc GlTF) Lights() ([]Light, error) {
+	lights, err := gltfDoc.lightsExtension()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return lights.Lights, nil
+}
+
+// makes sure the light only has the properties its type allows.
+func (light Light) check() error {
+	if light.Color != nil && len(light.Color) != 3 {
+		return fmt.Errorf("light color has %d components; it needs 3", len(light.Color))
+	}
+
+	switch light.Type {
+	case "directional":
+		if light.Range != 0 {
+			return fmt.Errorf("directional lights have no range, but this one has %g", light.Range)
+		}
+
+		if light.Spot != nil {
+			return fmt.Errorf("only spot lights have cone angles, but this light is %s", light.Type)
+		}
+	case "point":
+		if light.Spot != nil {
+			return fmt.Errorf("only spot lights have cone angles, but this light is %s", light.Type)
+		}
+	case "spot":
+		if light.Spot == nil {
+			return errors.New("spot lights need their spot property, even if it's empty")
+		}
+
+		outer := light.Spot.OuterConeAngle
+
+		if outer == 0 {
+			outer = math.Pi / 4
+		}
+
+		if light.Spot.InnerConeAngle >= outer || outer > math.Pi/2 {
+			return fmt.Errorf("spot cone angles are %g and %g; they need 0 <= inner < outer <= pi / 2", light.Spot.InnerConeAngle, outer)
+		}
+	default:
+		return fmt.Errorf("light type %q is not \"directional\", \"point\" or \"spot\"", light.Type)
+	}
+
+	return nil
+}
+
+// returns the document's KHR_lights_punctual object, or an empty one if it doesn't have one yet.
+func (gltfDoc GlTF) lightsExtension() (lightsExtension, error) {
+	lights := lightsExtension{}
+
+	extensions, ok := gltfDoc.Extensions.(map[string]interface{})
+
+	if !ok || extensions[lightsExtensionName] == nil {
+		return lights, nil
+	}
+
+	if typed, ok := extensions[lightsExtensionName].(lightsExtension); ok {
+		return typed, nil
+	}
+
+	// decoded documents hold a generic map, so round-trip it through JSON to get the typed version.
+	raw, err := json.Marshal(extensions[lightsExtensionName])
+
+	if err != nil {
+		return lights, err
+	}
+
+	err = json.Unmarshal(raw, &lights)
+
+	return lights, err
+}
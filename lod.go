@@ -0,0 +1,143 @@
+package gltf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The MSFT_lod extension lists lower detail alternatives for a node, in order of decreasing detail.  The alternatives
+// are ordinary nodes that aren't part of any scene, so viewers without the extension just render the base node.
+// See https://github.com/KhronosGroup/glTF/tree/main/extensions/2.0/Vendor/MSFT_lod
+
+const lodExtensionName = "MSFT_lod"
+
+type lodExtension struct {
+	IDs []int `json:"ids"`
+}
+
+// AddLODs adds the supplied Geometry as lower levels of detail for the node at nodeIndex, which is the highest detail
+// level.  lods must be ordered from most to least detailed.  screenCoverage is optional; if given, it needs one entry
+// per level including the base node, which is len(lods) + 1 entries, each the minimum fraction of the screen the
+// object must cover for that level to be used.  The new nodes' indices are returned in the same order as lods.
+//
+// lodOpts holds either a single Options used for every level, or one per level so that each can emit its own subset
+// of vertex attributes; lower levels rarely need more than POSITION and NORMAL.  Each new primitive is checked to make
+// sure its attributes and indices agree with each other.
+func (gltfDoc *GlTF) AddLODs(nodeIndex int, lods []Geometry, screenCoverage []float64, lodOpts []Options) (lodNodes []int, err error) {
+	if nodeIndex < 0 || nodeIndex >= len(gltfDoc.Nodes) {
+		return nil, fmt.Errorf("node %d does not exist", nodeIndex)
+	}
+
+	if len(lods) == 0 {
+		return nil, errors.New("no levels of detail supplied")
+	}
+
+	if len(screenCoverage) != 0 && len(screenCoverage) != len(lods)+1 {
+		return nil, fmt.Errorf("%d screen coverage values supplied for %d levels of detail; need %d", len(screenCoverage), len(lods)+1, len(lods)+1)
+	}
+
+	if len(lodOpts) != 1 && len(lodOpts) != len(lods) {
+		return nil, fmt.Errorf("%d options supplied for %d levels of detail; need 1 or %d", len(lodOpts), len(lods), len(lods))
+	}
+
+	base := gltfDoc.Nodes[nodeIndex]
+
+	for i, lod := range lods {
+		opts := lodOpts[0]
+
+		if len(lodOpts) > 1 {
+			opts = lodOpts[i]
+		}
+
+		meshIndex, err := gltfDoc.AddGeometry(lod, opts)
+
+		if err != nil {
+			return nil, fmt.Errorf("level of detail %d: %w", i+1, err)
+		}
+
+		for _, primitive := range gltfDoc.Meshes[meshIndex].Primitives {
+			if err := gltfDoc.checkPrimitive(primitive); err != nil {
+				return nil, fmt.Errorf("level of detail %d: %w", i+1, err)
+			}
+		}
+
+		gltfDoc.Nodes = append(gltfDoc.Nodes, Node{
+			Mesh:        meshIndex,
+			Name:        fmt.Sprintf("%s_LOD%d", base.Name, i+1),
+			Matrix:      base.Matrix,
+			Rotation:    base.Rotation,
+			Scale:       base.Scale,
+			Translation: base.Translation,
+		})
+
+		lodNodes = append(lodNodes, len(gltfDoc.Nodes)-1)
+	}
+
+	node := &gltfDoc.Nodes[nodeIndex]
+
+	if node.Extensions, err = setExtension(node.Extensions, lodExtensionName, lodExtension{IDs: lodNodes}); err != nil {
+		return nil, err
+	}
+
+	// the screen coverage values live in extras rather than in the extension itself; extras are the same kind of map.
+	if len(screenCoverage) != 0 {
+		if node.Extras, err = setExtension(node.Extras, "MSFT_screencoverage", screenCoverage); err != nil {
+			return nil, err
+		}
+	}
+
+	gltfDoc.useExtension(lodExtensionName)
+
+	return lodNodes, nil
+}
+
+// makes sure every attribute of a primitive, and of each of its morph targets, refers to an accessor with the same
+// number of elements as POSITION, and that its indices only refer to vertices that exist.
+func (gltfDoc GlTF) checkPrimitive(primitive MeshPrimitive) error {
+	positionIndex, ok := primitive.Attributes["POSITION"]
+
+	if !ok || positionIndex < 0 || positionIndex >= len(gltfDoc.Accessors) {
+		return errors.New("primitive has no POSITION accessor")
+	}
+
+	count := gltfDoc.Accessors[positionIndex].Count
+
+	for name, accessorIndex := range primitive.Attributes {
+		if accessorIndex < 0 || accessorIndex >= len(gltfDoc.Accessors) {
+			return fmt.Errorf("%s refers to accessor %d, which does not exist", name, accessorIndex)
+		}
+
+		if gltfDoc.Accessors[accessorIndex].Count != count {
+			return fmt.Errorf("%s has %d elements but POSITION has %d", name, gltfDoc.Accessors[accessorIndex].Count, count)
+		}
+	}
+
+	for t, target := range primitive.Targets {
+		for name, accessorIndex := range target {
+			if accessorIndex < 0 || accessorIndex >= len(gltfDoc.Accessors) {
+				return fmt.Errorf("target %d's %s refers to accessor %d, which does not exist", t, name, accessorIndex)
+			}
+
+			if gltfDoc.Accessors[accessorIndex].Count != count {
+				return fmt.Errorf("target %d's %s has %d elements but POSITION has %d", t, name, gltfDoc.Accessors[accessorIndex].Count, count)
+			}
+		}
+	}
+
+	// without indices the vertices are drawn in order, so there's nothing more to check.
+	if primitive.Indices == nil {
+		return nil
+	}
+
+	if *primitive.Indices < 0 || *primitive.Indices >= len(gltfDoc.Accessors) {
+		return fmt.Errorf("indices refer to accessor %d, which does not exist", *primitive.Indices)
+	}
+
+	indices := gltfDoc.Accessors[*primitive.Indices]
+
+	if len(indices.Max) == 1 && int(indices.Max[0]) >= count {
+		return fmt.Errorf("indices refer to vertex %d, but there are only %d", int(indices.Max[0]), count)
+	}
+
+	return nil
+}
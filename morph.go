@@ -0,0 +1,92 @@
+package gltf
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// A morph target is a set of per-vertex offsets that a viewer adds to the mesh, scaled by the target's weight, to
+// blend between shapes such as facial expressions.  Each vertex carries its own offsets, one MorphDelta per target in
+// Vertex.Morphs, so they follow the vertex through merging, welding and everything else that moves vertices around.
+// All the vertices of a Geometry need the same number of them.
+
+// MorphDelta is how far one morph target moves a vertex: an offset to its position, normal and tangent.  The tangent's
+// handedness can't be morphed, so its offset only has X, Y and Z.
+type MorphDelta struct {
+	Position Vector3 `json:"position,omitempty"`
+	Normal   Vector3 `json:"normal,omitempty"`
+	Tangent  Vector3 `json:"tangent,omitempty"`
+}
+
+// returns the number of morph targets the Geometry has, taken from its first vertex.
+func morphTargetCount(geo Geometry) int {
+	if len(geo.Vertices) == 0 {
+		return 0
+	}
+
+	return len(geo.Vertices[0].Morphs)
+}
+
+// reports whether the Geometry has any morph targets.
+func hasMorphs(geo Geometry) bool {
+	return morphTargetCount(geo) > 0
+}
+
+// makes sure every vertex has an offset for each of the Geometry's morph targets, and that there's a default weight
+// for each target if there are any.
+func checkMorphs(geo Geometry) error {
+	targets := morphTargetCount(geo)
+
+	for i, v := range geo.Vertices {
+		if len(v.Morphs) != targets {
+			return fmt.Errorf("vertex %d has %d morph targets, but vertex 0 has %d; every vertex needs one for each", i, len(v.Morphs), targets)
+		}
+	}
+
+	if geo.MorphWeights != nil && len(geo.MorphWeights) != targets {
+		return fmt.Errorf("geometry has %d morph weights but %d morph targets", len(geo.MorphWeights), targets)
+	}
+
+	return nil
+}
+
+// Appends the Geometry's morph target offsets to the supplied bytes.Buffer, adding an accessor for each attribute of
+// each target, and returns the attribute maps for MeshPrimitive.Targets.  POSITION is always written; NORMAL and TANGENT
+// are written for every target if includeNormals or includeTangents is set and any target moves them.  Targets that
+// only move a few vertices are written as sparse accessors.
+func getMorphTargets(outBuf *bytes.Buffer, geo Geometry, includeNormals, includeTangents bool, gltfBufferViews *[]BufferView, gltfAccessors *[]Accessor) []Attributes {
+	targets := make([]Attributes, morphTargetCount(geo))
+	movesNormals, movesTangents := false, false
+
+	for _, v := range geo.Vertices {
+		for _, delta := range v.Morphs {
+			movesNormals = movesNormals || delta.Normal != (Vector3{})
+			movesTangents = movesTangents || delta.Tangent != (Vector3{})
+		}
+	}
+
+	for t := range targets {
+		positions := make([]Vector3, len(geo.Vertices))
+		normals := make([]Vector3, len(geo.Vertices))
+		tangents := make([]Vector3, len(geo.Vertices))
+
+		for i, v := range geo.Vertices {
+			positions[i] = v.Morphs[t].Position
+			normals[i] = v.Morphs[t].Normal
+			tangents[i] = v.Morphs[t].Tangent
+		}
+
+		// the spec requires the bounds of a target's POSITION, which getAccessorIndexFromMorphOffsets always sets.
+		targets[t] = Attributes{"POSITION": getAccessorIndexFromMorphOffsets(outBuf, positions, gltfBufferViews, gltfAccessors)}
+
+		if includeNormals && movesNormals {
+			targets[t]["NORMAL"] = getAccessorIndexFromMorphOffsets(outBuf, normals, gltfBufferViews, gltfAccessors)
+		}
+
+		if includeTangents && movesTangents {
+			targets[t]["TANGENT"] = getAccessorIndexFromMorphOffsets(outBuf, tangents, gltfBufferViews, gltfAccessors)
+		}
+	}
+
+	return targets
+}
@@ -0,0 +1,69 @@
+package gltf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSingleMorphTarget(t *testing.T) {
+	geo := testTriangle(Material{Opacity: 1})
+	geo.MorphWeights = []float64{0.5}
+
+	for i := range geo.Vertices {
+		geo.Vertices[i].Morphs = []MorphDelta{{Position: Vector3{Z: 1}}}
+	}
+
+	gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{geo}}, PipelineOptions{Options: Options{VertexColors: true}})
+	mesh := gltfDoc.Meshes[0]
+	targets := mesh.Primitives[0].Targets
+
+	if len(targets) != 1 {
+		t.Fatalf("got %d morph targets, want 1", len(targets))
+	}
+
+	position, ok := targets[0]["POSITION"]
+
+	if !ok {
+		t.Fatalf("the morph target has attributes %v, want a POSITION", targets[0])
+	}
+
+	// nothing moves the normals, so the target doesn't need them.
+	if _, ok := targets[0]["NORMAL"]; ok {
+		t.Error("the morph target has a NORMAL, though it doesn't move any normals")
+	}
+
+	accessor := gltfDoc.Accessors[position]
+
+	if accessor.Count != 3 || accessor.Type != Vec3 {
+		t.Errorf("target POSITION is %d of %s, want 3 of %s", accessor.Count, accessor.Type, Vec3)
+	}
+
+	if !reflect.DeepEqual(accessor.Min, []float32{0, 0, 1}) || !reflect.DeepEqual(accessor.Max, []float32{0, 0, 1}) {
+		t.Errorf("target POSITION runs from %v to %v, want [0 0 1] for both", accessor.Min, accessor.Max)
+	}
+
+	if !reflect.DeepEqual(mesh.Weights, []float64{0.5}) {
+		t.Errorf("mesh weights are %v, want [0.5]", mesh.Weights)
+	}
+}
+
+func TestCheckMorphs(t *testing.T) {
+	geo := testTriangle(Material{Opacity: 1})
+
+	for i := range geo.Vertices {
+		geo.Vertices[i].Morphs = []MorphDelta{{Position: Vector3{Z: 1}}}
+	}
+
+	missing := geo
+	missing.Vertices = append([]Vertex(nil), geo.Vertices...)
+	missing.Vertices[2].Morphs = nil
+
+	tooManyWeights := geo
+	tooManyWeights.MorphWeights = []float64{0.5, 0.5}
+
+	for name, bad := range map[string]Geometry{"a vertex without the target": missing, "two weights for one target": tooManyWeights} {
+		if err := checkMorphs(bad); err == nil {
+			t.Errorf("%s was accepted", name)
+		}
+	}
+}
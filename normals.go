@@ -0,0 +1,90 @@
+package gltf
+
+// GenerateNormals replaces the normals of the Geometry's vertices with ones worked out from its triangles.  With smooth
+// set, each vertex gets the sum of the normals of the triangles that use it, weighted by their area, so big faces count
+// for more than slivers, and normalized; only vertices that share an index are smoothed together, so weld a triangle
+// soup first.  Otherwise every triangle gets three vertices of its own with its normal, for a faceted look.  The
+// triangles' winding decides which way the normals point, counter-clockwise being the front as glTF has it.
+//
+// Vertices no triangle uses, and those of Geometry that isn't ModeTriangles, are left as they are.
+func (g *Geometry) GenerateNormals(smooth bool) {
+	if g.Mode != ModeTriangles || len(g.Faces) == 0 {
+		return
+	}
+
+	// a fresh slice, so a Geometry copied from this one keeps its own normals.
+	vertices := make([]Vertex, 0, len(g.Vertices))
+
+	if smooth {
+		vertices = append(vertices, g.Vertices...)
+		sums := make([]Vector3, len(vertices))
+		used := make([]bool, len(vertices))
+
+		for _, f := range g.Faces {
+			// the cross product's length is twice the triangle's area, which is the weighting wanted.
+			normal := faceNormal(*g, f)
+
+			for _, i := range f.TriangleIndices {
+				sums[i] = add(sums[i], normal)
+				used[i] = true
+			}
+		}
+
+		for i := range vertices {
+			if used[i] {
+				vertices[i].Normal = normalize(sums[i])
+			}
+		}
+
+		g.Vertices = vertices
+
+		return
+	}
+
+	faces := make([]Triangle, len(g.Faces))
+
+	for n, f := range g.Faces {
+		normal := normalize(faceNormal(*g, f))
+		first := int32(len(vertices))
+
+		for _, i := range f.TriangleIndices {
+			v := g.Vertices[i]
+			v.Normal = normal
+			vertices = append(vertices, v)
+		}
+
+		faces[n] = Triangle{TriangleIndices: [3]int32{first, first + 1, first + 2}}
+	}
+
+	g.Vertices = vertices
+	g.Faces = faces
+}
+
+// reports whether every one of the Geometry's vertices has a zero normal, as they do when whatever built it had none.
+func lacksNormals(geo Geometry) bool {
+	for _, v := range geo.Vertices {
+		if v.Normal != (Vector3{}) {
+			return false
+		}
+	}
+
+	return len(geo.Vertices) > 0
+}
+
+// returns a copy of the Model where each Geometry without any normals has smooth ones generated for it.  The Model
+// passed in is left alone.
+func generateMissingNormals(model Model) Model {
+	meshes := make([]Geometry, len(model.Meshes))
+
+	for i, geo := range model.Meshes {
+		if !geo.isGroup() && lacksNormals(geo) {
+			geo.GenerateNormals(true)
+		}
+
+		meshes[i] = geo
+	}
+
+	model.Meshes = meshes
+
+	return model
+}
@@ -0,0 +1,52 @@
+package gltf
+
+import "testing"
+
+// returns testTriangle with its normals zeroed, as a loader that found none would leave it.
+func testTriangleWithoutNormals() Geometry {
+	geo := testTriangle(Material{Opacity: 1})
+
+	for i := range geo.Vertices {
+		geo.Vertices[i].Normal = Vector3{}
+	}
+
+	return geo
+}
+
+func TestGenerateNormalsFlatTriangle(t *testing.T) {
+	for _, smooth := range []bool{false, true} {
+		geo := testTriangleWithoutNormals()
+		geo.GenerateNormals(smooth)
+
+		if len(geo.Vertices) != 3 {
+			t.Errorf("smooth %t: the triangle has %d vertices, want 3", smooth, len(geo.Vertices))
+		}
+
+		for i, v := range geo.Vertices {
+			if v.Normal != (Vector3{Z: 1}) {
+				t.Errorf("smooth %t: vertex %d has normal %v, want +Z", smooth, i, v.Normal)
+			}
+		}
+	}
+}
+
+func TestGenerateNormalsFlatQuad(t *testing.T) {
+	quad := testQuad(Material{})
+	quad.GenerateNormals(false)
+
+	// each triangle gets its own corners, so the two shared ones are split.
+	if len(quad.Vertices) != 6 {
+		t.Errorf("the flat quad has %d vertices, want 6", len(quad.Vertices))
+	}
+}
+
+func TestOptimizeModelFillsMissingNormals(t *testing.T) {
+	gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{testTriangleWithoutNormals()}}, PipelineOptions{Options: Options{VertexColors: true}})
+	normals := readFloatsForTest(t, gltfDoc, gltfDoc.Meshes[0].Primitives[0].Attributes["NORMAL"])
+
+	for i := 0; i < len(normals); i += 3 {
+		if normals[i] != 0 || normals[i+1] != 0 || normals[i+2] != 1 {
+			t.Errorf("vertex %d has normal %v, want +Z", i/3, normals[i:i+3])
+		}
+	}
+}
@@ -0,0 +1,424 @@
+package gltf
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Wavefront .obj files list positions (v), texture coordinates (vt) and normals (vn), then faces (f) whose corners
+// refer to them by 1-based index, or by negative index counting back from the last one read.  Materials come from .mtl
+// libraries named by mtllib and are selected by usemtl.  The loader understands those statements and ignores the rest,
+// such as groups, objects, smoothing groups and lines.
+
+// the material an imported face gets when the file doesn't give it one: in an .obj, when no usemtl has selected one or
+// the one selected isn't in any library.  The gray is what most modelling tools give a new material.
+var defaultImportedMaterial = Material{DiffuseColor: [3]float32{0.8, 0.8, 0.8}, Opacity: 1, DoubleSided: true}
+
+// LoadOBJ reads a Wavefront .obj file from r and returns a Model with a Geometry for each material its faces use, in
+// the order they're first used, ready for OptimizeModel.  Polygons with more than three corners are split into a fan of
+// triangles, so they have to be convex.  Corners without a normal get their face's, and corners without a texture
+// coordinate get a UV of 0, 0.  V is flipped, since .obj puts the origin of a texture at its bottom left and glTF at
+// its top left.  Each vertex's color is its material's diffuse color and opacity, unless the file gives it one after
+// its position, so the vertex color strategy works as well as the texture atlas.
+//
+// Nothing is loaded from the filesystem, so mtllib statements are ignored and every face gets a plain gray material;
+// use LoadOBJFile to load the material libraries as well.
+func LoadOBJ(r io.Reader) (Model, error) {
+	return loadOBJ(r, nil)
+}
+
+// LoadOBJFile reads the named .obj file like LoadOBJ, along with the .mtl libraries it names, which are looked for
+// relative to it.  Material texture paths are made relative to the working directory, so optimizeModel can load them.
+func LoadOBJFile(path string) (Model, error) {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return Model{}, err
+	}
+
+	defer file.Close()
+
+	dir := filepath.Dir(path)
+
+	return loadOBJ(file, func(name string) (map[string]Material, error) {
+		libPath := filepath.Join(dir, name)
+		lib, err := os.Open(libPath)
+
+		if err != nil {
+			return nil, err
+		}
+
+		defer lib.Close()
+
+		return parseMTL(lib, filepath.Dir(libPath))
+	})
+}
+
+// LoadMTL reads a Wavefront .mtl material library from r, and returns its materials by name.  It understands Ka, Kd,
+// Ks, Ke, Ns, d, Tr and map_Kd; texture paths are returned as the library has them.
+func LoadMTL(r io.Reader) (map[string]Material, error) {
+	return parseMTL(r, "")
+}
+
+// one corner of an .obj face: indices into the positions, texture coordinates and normals read so far, with -1 for a
+// texture coordinate or normal it doesn't have.
+type objCorner struct {
+	position, uv, normal int
+}
+
+// the Geometry being built for one material, and the vertex made for each corner seen so far.
+type objGroup struct {
+	geometry Geometry
+	vertices map[objCorner]int32
+}
+
+// reads an .obj file, calling loadLibrary, if it isn't nil, for each material library it names.
+func loadOBJ(r io.Reader, loadLibrary func(name string) (map[string]Material, error)) (Model, error) {
+	positions := []Vector3{}
+	colors := []Vector4{} // only the positions that were given a color have one.
+	uvs := []Vector2{}
+	normals := []Vector3{}
+
+	materials := map[string]Material{}
+	groups := []*objGroup{}
+	groupIndex := map[string]int{}
+	current := ""
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		switch fields[0] {
+		case "v":
+			values, err := parseFloats(fields[1:], 3, 7)
+
+			if err != nil {
+				return Model{}, fmt.Errorf("line %d: %w", line, err)
+			}
+
+			positions = append(positions, Vector3{X: values[0], Y: values[1], Z: values[2]})
+
+			// some exporters put an RGB color after the position, which isn't standard but is common enough.
+			if len(values) >= 6 {
+				for len(colors) < len(positions)-1 {
+					colors = append(colors, Vector4{})
+				}
+
+				colors = append(colors, Vector4{R: values[3], G: values[4], B: values[5], A: 1})
+			}
+		case "vt":
+			values, err := parseFloats(fields[1:], 1, 3)
+
+			if err != nil {
+				return Model{}, fmt.Errorf("line %d: %w", line, err)
+			}
+
+			uv := Vector2{U: values[0]}
+
+			if len(values) > 1 {
+				uv.V = 1 - values[1]
+			}
+
+			uvs = append(uvs, uv)
+		case "vn":
+			values, err := parseFloats(fields[1:], 3, 3)
+
+			if err != nil {
+				return Model{}, fmt.Errorf("line %d: %w", line, err)
+			}
+
+			normals = append(normals, normalize(Vector3{X: values[0], Y: values[1], Z: values[2]}))
+		case "f":
+			if len(fields) < 4 {
+				return Model{}, fmt.Errorf("line %d: a face needs at least 3 corners, but this one has %d", line, len(fields)-1)
+			}
+
+			corners := make([]objCorner, len(fields)-1)
+
+			for i, field := range fields[1:] {
+				corner, err := parseOBJCorner(field, len(positions), len(uvs), len(normals))
+
+				if err != nil {
+					return Model{}, fmt.Errorf("line %d: %w", line, err)
+				}
+
+				corners[i] = corner
+			}
+
+			i, ok := groupIndex[current]
+
+			if !ok {
+				material, found := materials[current]
+
+				if !found {
+					material = defaultImportedMaterial
+				}
+
+				i = len(groups)
+				groupIndex[current] = i
+				groups = append(groups, &objGroup{geometry: Geometry{Material: material}, vertices: map[objCorner]int32{}})
+			}
+
+			groups[i].addFace(corners, positions, colors, uvs, normals)
+		case "usemtl":
+			current = strings.Join(fields[1:], " ")
+		case "mtllib":
+			if loadLibrary == nil {
+				continue
+			}
+
+			for _, name := range fields[1:] {
+				lib, err := loadLibrary(name)
+
+				if err != nil {
+					return Model{}, fmt.Errorf("line %d: couldn't load material library %s: %w", line, name, err)
+				}
+
+				for materialName, material := range lib {
+					materials[materialName] = material
+				}
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return Model{}, err
+	}
+
+	model := Model{}
+
+	// a material whose faces were all skipped for having no area has nothing to draw.
+	for _, group := range groups {
+		if len(group.geometry.Faces) > 0 {
+			model.Meshes = append(model.Meshes, group.geometry)
+		}
+	}
+
+	if len(model.Meshes) == 0 {
+		return Model{}, errors.New("obj file has no faces")
+	}
+
+	return model, nil
+}
+
+// adds a face to the group, as a fan of triangles around its first corner.  Corners that have a normal share a vertex
+// with the other corners that use the same position, texture coordinate and normal; the rest get a vertex of their own
+// with the face's normal.  A face with no area, whose corners are all in a line, is skipped: it has nothing to draw,
+// and no normal to give the corners that need one.
+func (group *objGroup) addFace(corners []objCorner, positions []Vector3, colors []Vector4, uvs []Vector2, normals []Vector3) {
+	// the normal of a polygon that's not quite flat is best taken from all of its triangles.
+	faceNormal := Vector3{}
+
+	for i := 2; i < len(corners); i++ {
+		a := positions[corners[0].position]
+		n := cross(sub(positions[corners[i-1].position], a), sub(positions[corners[i].position], a))
+		faceNormal = Vector3{X: faceNormal.X + n.X, Y: faceNormal.Y + n.Y, Z: faceNormal.Z + n.Z}
+	}
+
+	if faceNormal == (Vector3{}) {
+		return
+	}
+
+	faceNormal = normalize(faceNormal)
+	indices := make([]int32, len(corners))
+
+	for i, corner := range corners {
+		if index, ok := group.vertices[corner]; ok && corner.normal >= 0 {
+			indices[i] = index
+			continue
+		}
+
+		material := group.geometry.Material
+		v := Vertex{
+			Position: positions[corner.position],
+			Normal:   faceNormal,
+			Color:    Vector4{R: material.DiffuseColor[0], G: material.DiffuseColor[1], B: material.DiffuseColor[2], A: material.Opacity},
+		}
+
+		if corner.position < len(colors) && colors[corner.position] != (Vector4{}) {
+			v.Color = colors[corner.position]
+		}
+
+		if corner.uv >= 0 {
+			v.UV = uvs[corner.uv]
+		}
+
+		if corner.normal >= 0 {
+			v.Normal = normals[corner.normal]
+		}
+
+		indices[i] = int32(len(group.geometry.Vertices))
+		group.geometry.Vertices = append(group.geometry.Vertices, v)
+
+		if corner.normal >= 0 {
+			group.vertices[corner] = indices[i]
+		}
+	}
+
+	for i := 2; i < len(indices); i++ {
+		group.geometry.Faces = append(group.geometry.Faces, Triangle{TriangleIndices: [3]int32{indices[0], indices[i-1], indices[i]}})
+	}
+}
+
+// parses one corner of a face, which is a position index, optionally followed by a texture coordinate index and a
+// normal index, separated by slashes: v, v/vt, v//vn or v/vt/vn.
+func parseOBJCorner(field string, positionCount, uvCount, normalCount int) (objCorner, error) {
+	parts := strings.Split(field, "/")
+
+	if len(parts) > 3 {
+		return objCorner{}, fmt.Errorf("face corner %q has more than 3 indices", field)
+	}
+
+	corner := objCorner{uv: -1, normal: -1}
+	counts := []int{positionCount, uvCount, normalCount}
+	targets := []*int{&corner.position, &corner.uv, &corner.normal}
+	names := []string{"position", "texture coordinate", "normal"}
+
+	for i, part := range parts {
+		if part == "" {
+			if i == 0 {
+				return objCorner{}, fmt.Errorf("face corner %q has no position", field)
+			}
+
+			continue
+		}
+
+		index, err := strconv.Atoi(part)
+
+		if err != nil {
+			return objCorner{}, fmt.Errorf("face corner %q has an index that isn't a number", field)
+		}
+
+		// negative indices count back from the last one read, so -1 is the one just before the face.
+		if index < 0 {
+			index = counts[i] + index
+		} else {
+			index--
+		}
+
+		if index < 0 || index >= counts[i] {
+			return objCorner{}, fmt.Errorf("face corner %q refers to %s %s, but there are only %d", field, names[i], part, counts[i])
+		}
+
+		*targets[i] = index
+	}
+
+	return corner, nil
+}
+
+// parses between min and max floats, returning an error naming the first that isn't one.
+func parseFloats(fields []string, min, max int) ([]float32, error) {
+	if len(fields) < min {
+		return nil, fmt.Errorf("expected at least %d numbers, but got %d", min, len(fields))
+	}
+
+	if len(fields) > max {
+		fields = fields[:max]
+	}
+
+	values := make([]float32, len(fields))
+
+	for i, field := range fields {
+		value, err := strconv.ParseFloat(field, 32)
+
+		if err != nil {
+			return nil, fmt.Errorf("%q isn't a number", field)
+		}
+
+		values[i] = float32(value)
+	}
+
+	return values, nil
+}
+
+// reads an .mtl material library, joining texture paths onto dir.
+func parseMTL(r io.Reader, dir string) (map[string]Material, error) {
+	materials := map[string]Material{}
+	current := ""
+
+	scanner := bufio.NewScanner(r)
+
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		if fields[0] == "newmtl" {
+			current = strings.Join(fields[1:], " ")
+			materials[current] = defaultImportedMaterial
+
+			continue
+		}
+
+		material, ok := materials[current]
+
+		if !ok {
+			continue
+		}
+
+		var err error
+		var values []float32
+
+		switch fields[0] {
+		case "Ka", "Kd", "Ks", "Ke":
+			// a single value is a gray.
+			if values, err = parseFloats(fields[1:], 1, 3); err == nil {
+				if len(values) < 3 {
+					values = []float32{values[0], values[0], values[0]}
+				}
+
+				color := [3]float32{values[0], values[1], values[2]}
+
+				switch fields[0] {
+				case "Ka":
+					material.AmbientColor = color
+				case "Kd":
+					material.DiffuseColor = color
+				case "Ks":
+					material.SpecularColor = color
+				case "Ke":
+					material.EmissiveColor = color
+				}
+			}
+		case "Ns":
+			if values, err = parseFloats(fields[1:], 1, 1); err == nil {
+				material.SpecularPower = values[0]
+			}
+		case "d":
+			if values, err = parseFloats(fields[1:], 1, 1); err == nil {
+				material.Opacity = values[0]
+			}
+		case "Tr":
+			if values, err = parseFloats(fields[1:], 1, 1); err == nil {
+				material.Opacity = 1 - values[0]
+			}
+		case "map_Kd":
+			// options such as -s come before the file name, which is last.
+			if len(fields) > 1 {
+				material.TexturePath = filepath.Join(dir, fields[len(fields)-1])
+			}
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		materials[current] = material
+	}
+
+	return materials, scanner.Err()
+}
@@ -0,0 +1,121 @@
+package gltf
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// fails the test unless every vertex of the Model has a unit length normal, as the spec requires.
+func checkUnitNormals(t *testing.T, model Model) {
+	t.Helper()
+
+	for m, geo := range model.Meshes {
+		for i, v := range geo.Vertices {
+			if length := math.Sqrt(float64(dot(v.Normal, v.Normal))); math.Abs(length-1) > 1e-6 {
+				t.Errorf("mesh %d vertex %d has normal %v, of length %g", m, i, v.Normal, length)
+			}
+		}
+	}
+}
+
+func TestLoadOBJQuad(t *testing.T) {
+	// a quad with negative indices and no normals, which is split into two triangles facing +Z.
+	obj := `
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+vt 0 0
+vt 1 0
+vt 1 1
+vt 0 1
+f -4/-4 -3/-3 -2/-2 -1/-1
+`
+	model, err := LoadOBJ(strings.NewReader(obj))
+
+	if err != nil {
+		t.Fatalf("LoadOBJ: %v", err)
+	}
+
+	if len(model.Meshes) != 1 || len(model.Meshes[0].Faces) != 2 {
+		t.Fatalf("got %d meshes, want 1 with 2 triangles", len(model.Meshes))
+	}
+
+	geo := model.Meshes[0]
+
+	for i, v := range geo.Vertices {
+		if v.Normal != (Vector3{Z: 1}) {
+			t.Errorf("vertex %d has normal %v, want +Z", i, v.Normal)
+		}
+	}
+
+	// .obj's V runs up from the bottom, and glTF's down from the top.
+	if uv := geo.Vertices[0].UV; uv != (Vector2{U: 0, V: 1}) {
+		t.Errorf("the first corner's UV is %v, want 0, 1", uv)
+	}
+}
+
+func TestLoadOBJMaterials(t *testing.T) {
+	mtl := `
+newmtl red
+Kd 1 0 0
+`
+	obj := `
+mtllib colors.mtl
+v 0 0 0
+v 1 0 0
+v 0 1 0
+vn 0 0 1
+usemtl red
+f 1//1 2//1 3//1
+usemtl missing
+f 1 2 3
+`
+	model, err := loadOBJ(strings.NewReader(obj), func(name string) (map[string]Material, error) {
+		return LoadMTL(strings.NewReader(mtl))
+	})
+
+	if err != nil {
+		t.Fatalf("loadOBJ: %v", err)
+	}
+
+	if len(model.Meshes) != 2 {
+		t.Fatalf("got %d meshes, want one for each material", len(model.Meshes))
+	}
+
+	if got := model.Meshes[0].Material.DiffuseColor; got != [3]float32{1, 0, 0} {
+		t.Errorf("the red material's diffuse color is %v", got)
+	}
+
+	if got := model.Meshes[1].Material; got.DiffuseColor != defaultImportedMaterial.DiffuseColor {
+		t.Errorf("a face with an unknown material got %v, want the default", got.DiffuseColor)
+	}
+}
+
+func TestLoadOBJDegenerateFace(t *testing.T) {
+	// the second face's corners are in a line, so it has no normal to give them.
+	obj := `
+v 0 0 0
+v 1 0 0
+v 0 1 0
+v 2 0 0
+f 1 2 3
+f 1 2 4
+`
+	model, err := LoadOBJ(strings.NewReader(obj))
+
+	if err != nil {
+		t.Fatalf("LoadOBJ: %v", err)
+	}
+
+	if faces := len(model.Meshes[0].Faces); faces != 1 {
+		t.Errorf("got %d triangles, want the degenerate one skipped", faces)
+	}
+
+	checkUnitNormals(t, model)
+
+	if _, err := LoadOBJ(strings.NewReader("v 0 0 0\nv 1 0 0\nv 2 0 0\nf 1 2 3\n")); err == nil {
+		t.Error("a file whose only face has no area was accepted")
+	}
+}
@@ -0,0 +1,302 @@
+package gltf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A PLY file starts with a text header that declares its elements, such as vertex and face, how many of each there are,
+// and the properties each one has, in order.  The data follows, either as text or as binary, one element after another.
+// A property is a number of a declared type, or a list: a count followed by that many numbers.
+
+// the sizes, in bytes, of the types PLY properties can have, under both the old and new names.
+var plyTypeSizes = map[string]int{
+	"char": 1, "int8": 1, "uchar": 1, "uint8": 1,
+	"short": 2, "int16": 2, "ushort": 2, "uint16": 2,
+	"int": 4, "int32": 4, "uint": 4, "uint32": 4,
+	"float": 4, "float32": 4, "double": 8, "float64": 8,
+}
+
+// one property of a PLY element.  A list property has a countType as well as a valueType.
+type plyProperty struct {
+	name      string
+	countType string
+	valueType string
+}
+
+// one element of a PLY header: its name, how many there are, and the properties each has.
+type plyElement struct {
+	name       string
+	count      int
+	properties []plyProperty
+}
+
+// LoadPLY reads an ASCII or binary little endian PLY file from r.  It returns a Model of one Geometry with the file's
+// faces, split into fans of triangles if they have more than three corners, or a ModePoints Geometry of its vertices
+// if it has no faces, such as a point cloud.
+//
+// These vertex properties are read: x, y and z for the position; nx, ny and nz for the normal; red, green, blue and
+// alpha for the color; and s and t, u and v, or texture_u and texture_v for the UV, with V flipped as in LoadOBJ.
+// Integer colors are taken to go up to 255, and float ones up to 1.  A face's corners are its vertex_indices, or
+// vertex_index, list.  Other properties and elements are skipped.  Vertices without a color get the plain gray
+// material's, like LoadOBJ gives them, and ones without a normal are left with a zero one, which OptimizeModel replaces
+// with a generated normal.  binary_big_endian files aren't supported.
+func LoadPLY(r io.Reader) (Model, error) {
+	reader := bufio.NewReader(r)
+	elements, format, err := parsePLYHeader(reader)
+
+	if err != nil {
+		return Model{}, err
+	}
+
+	var next func(dataType string) (float64, error)
+
+	switch format {
+	case "ascii":
+		words := bufio.NewScanner(reader)
+		words.Split(bufio.ScanWords)
+
+		next = func(dataType string) (float64, error) {
+			if !words.Scan() {
+				if err := words.Err(); err != nil {
+					return 0, err
+				}
+
+				return 0, io.ErrUnexpectedEOF
+			}
+
+			return strconv.ParseFloat(words.Text(), 64)
+		}
+	case "binary_little_endian":
+		next = func(dataType string) (float64, error) {
+			return readPLYBinary(reader, dataType)
+		}
+	default:
+		return Model{}, fmt.Errorf("PLY format %q isn't supported; only ascii and binary_little_endian are", format)
+	}
+
+	geo := Geometry{Material: defaultImportedMaterial}
+	hasFaces := false
+
+	for _, element := range elements {
+		for i := 0; i < element.count; i++ {
+			values := make(map[string]float64, len(element.properties))
+			var corners []int32
+
+			for _, property := range element.properties {
+				if property.countType == "" {
+					value, err := next(property.valueType)
+
+					if err != nil {
+						return Model{}, fmt.Errorf("%s %d: %s: %w", element.name, i, property.name, err)
+					}
+
+					values[property.name] = value
+					continue
+				}
+
+				count, err := next(property.countType)
+
+				if err != nil {
+					return Model{}, fmt.Errorf("%s %d: %s: %w", element.name, i, property.name, err)
+				}
+
+				isCorners := element.name == "face" && (property.name == "vertex_indices" || property.name == "vertex_index")
+
+				for c := 0; c < int(count); c++ {
+					value, err := next(property.valueType)
+
+					if err != nil {
+						return Model{}, fmt.Errorf("%s %d: %s: %w", element.name, i, property.name, err)
+					}
+
+					if isCorners {
+						corners = append(corners, int32(value))
+					}
+				}
+			}
+
+			switch element.name {
+			case "vertex":
+				geo.Vertices = append(geo.Vertices, plyVertex(element, values))
+			case "face":
+				hasFaces = true
+
+				if len(corners) < 3 {
+					return Model{}, fmt.Errorf("face %d has %d corners; it needs at least 3", i, len(corners))
+				}
+
+				for c := 2; c < len(corners); c++ {
+					geo.Faces = append(geo.Faces, Triangle{TriangleIndices: [3]int32{corners[0], corners[c-1], corners[c]}})
+				}
+			}
+		}
+	}
+
+	if len(geo.Vertices) == 0 {
+		return Model{}, errors.New("PLY file has no vertices")
+	}
+
+	if !hasFaces {
+		geo.Mode = ModePoints
+	}
+
+	if err := checkGeometry(geo); err != nil {
+		return Model{}, err
+	}
+
+	return Model{Meshes: []Geometry{geo}}, nil
+}
+
+// reads a PLY header up to and including its end_header line, and returns its elements and format.
+func parsePLYHeader(reader *bufio.Reader) (elements []plyElement, format string, err error) {
+	for line := 1; ; line++ {
+		text, err := reader.ReadString('\n')
+
+		if err != nil {
+			return nil, "", fmt.Errorf("PLY header ends early, at line %d: %w", line, err)
+		}
+
+		fields := strings.Fields(text)
+
+		if line == 1 {
+			if len(fields) != 1 || fields[0] != "ply" {
+				return nil, "", errors.New("not a PLY file: it doesn't start with \"ply\"")
+			}
+
+			continue
+		}
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "format":
+			if len(fields) < 2 {
+				return nil, "", fmt.Errorf("line %d: format has no name", line)
+			}
+
+			format = fields[1]
+		case "element":
+			if len(fields) != 3 {
+				return nil, "", fmt.Errorf("line %d: element needs a name and a count", line)
+			}
+
+			count, err := strconv.Atoi(fields[2])
+
+			if err != nil || count < 0 {
+				return nil, "", fmt.Errorf("line %d: element %s has a count of %q", line, fields[1], fields[2])
+			}
+
+			elements = append(elements, plyElement{name: fields[1], count: count})
+		case "property":
+			if len(elements) == 0 {
+				return nil, "", fmt.Errorf("line %d: property comes before any element", line)
+			}
+
+			property := plyProperty{}
+
+			switch {
+			case len(fields) == 5 && fields[1] == "list":
+				property = plyProperty{name: fields[4], countType: fields[2], valueType: fields[3]}
+			case len(fields) == 3:
+				property = plyProperty{name: fields[2], valueType: fields[1]}
+			default:
+				return nil, "", fmt.Errorf("line %d: can't read property %q", line, strings.TrimSpace(text))
+			}
+
+			for _, dataType := range []string{property.countType, property.valueType} {
+				if _, ok := plyTypeSizes[dataType]; dataType != "" && !ok {
+					return nil, "", fmt.Errorf("line %d: property %s has unknown type %q", line, property.name, dataType)
+				}
+			}
+
+			elements[len(elements)-1].properties = append(elements[len(elements)-1].properties, property)
+		case "end_header":
+			if format == "" {
+				return nil, "", errors.New("PLY header has no format")
+			}
+
+			return elements, format, nil
+		}
+	}
+}
+
+// reads one little endian number of the supplied PLY type.
+func readPLYBinary(reader io.Reader, dataType string) (float64, error) {
+	var data [8]byte
+	size := plyTypeSizes[dataType]
+
+	if _, err := io.ReadFull(reader, data[:size]); err != nil {
+		return 0, err
+	}
+
+	switch dataType {
+	case "char", "int8":
+		return float64(int8(data[0])), nil
+	case "uchar", "uint8":
+		return float64(data[0]), nil
+	case "short", "int16":
+		return float64(int16(binary.LittleEndian.Uint16(data[:]))), nil
+	case "ushort", "uint16":
+		return float64(binary.LittleEndian.Uint16(data[:])), nil
+	case "int", "int32":
+		return float64(int32(binary.LittleEndian.Uint32(data[:]))), nil
+	case "uint", "uint32":
+		return float64(binary.LittleEndian.Uint32(data[:])), nil
+	case "float", "float32":
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(data[:]))), nil
+	default:
+		return math.Float64frombits(binary.LittleEndian.Uint64(data[:])), nil
+	}
+}
+
+// builds a Vertex from the properties of a PLY vertex element.
+func plyVertex(element plyElement, values map[string]float64) Vertex {
+	material := defaultImportedMaterial
+	v := Vertex{
+		Position: Vector3{X: float32(values["x"]), Y: float32(values["y"]), Z: float32(values["z"])},
+		Normal:   Vector3{X: float32(values["nx"]), Y: float32(values["ny"]), Z: float32(values["nz"])},
+		Color:    Vector4{R: material.DiffuseColor[0], G: material.DiffuseColor[1], B: material.DiffuseColor[2], A: material.Opacity},
+	}
+
+	// each channel's scale depends on its own type, since nothing stops a file mixing them.
+	channel := func(name string) (float32, bool) {
+		for _, property := range element.properties {
+			if property.name == name && property.countType == "" {
+				if strings.HasPrefix(property.valueType, "float") || property.valueType == "double" {
+					return float32(values[name]), true
+				}
+
+				return float32(values[name] / 255), true
+			}
+		}
+
+		return 0, false
+	}
+
+	if r, ok := channel("red"); ok {
+		g, _ := channel("green")
+		b, _ := channel("blue")
+		v.Color = Vector4{R: r, G: g, B: b, A: 1}
+
+		if a, ok := channel("alpha"); ok {
+			v.Color.A = a
+		}
+	}
+
+	for _, names := range [][2]string{{"s", "t"}, {"u", "v"}, {"texture_u", "texture_v"}} {
+		if u, ok := values[names[0]]; ok {
+			v.UV = Vector2{U: float32(u), V: float32(1 - values[names[1]])}
+		}
+	}
+
+	return v
+}
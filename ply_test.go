@@ -0,0 +1,166 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadPLYASCII(t *testing.T) {
+	document := `ply
+format ascii 1.0
+comment a unit quad in the XY plane
+element vertex 4
+property float x
+property float y
+property float z
+property float nx
+property float ny
+property float nz
+property float s
+property float t
+element face 1
+property list uchar int vertex_indices
+end_header
+0 0 0 0 0 1 0 0
+1 0 0 0 0 1 1 0
+1 1 0 0 0 1 1 1
+0 1 0 0 0 1 0 1
+4 0 1 2 3
+`
+	model, err := LoadPLY(strings.NewReader(document))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	geo := model.Meshes[0]
+
+	if len(geo.Vertices) != 4 {
+		t.Fatalf("%d vertices were read, want 4", len(geo.Vertices))
+	}
+
+	if geo.Mode != ModeTriangles {
+		t.Errorf("mode is %d, want ModeTriangles", geo.Mode)
+	}
+
+	// the quad is split into a fan around its first corner.
+	want := []Triangle{{TriangleIndices: [3]int32{0, 1, 2}}, {TriangleIndices: [3]int32{0, 2, 3}}}
+
+	if !reflect.DeepEqual(geo.Faces, want) {
+		t.Errorf("faces are %v, want %v", geo.Faces, want)
+	}
+
+	corner := geo.Vertices[2]
+
+	if corner.Position != (Vector3{X: 1, Y: 1}) || corner.Normal != (Vector3{Z: 1}) {
+		t.Errorf("vertex 2 is at %v with normal %v, want (1, 1, 0) and +Z", corner.Position, corner.Normal)
+	}
+
+	// V is flipped, as LoadOBJ does.
+	if corner.UV != (Vector2{U: 1, V: 0}) {
+		t.Errorf("vertex 2 has UV %v, want (1, 0)", corner.UV)
+	}
+}
+
+func TestLoadPLYBinaryLittleEndian(t *testing.T) {
+	header := `ply
+format binary_little_endian 1.0
+element vertex 3
+property float x
+property float y
+property float z
+property uchar red
+property uchar green
+property uchar blue
+element face 1
+property list uchar int vertex_indices
+end_header
+`
+	data := bytes.NewBufferString(header)
+
+	for _, p := range []Vector3{{}, {X: 1}, {Y: 1}} {
+		binary.Write(data, binary.LittleEndian, [3]float32{p.X, p.Y, p.Z})
+		data.Write([]byte{255, 0, 51})
+	}
+
+	data.WriteByte(3)
+	binary.Write(data, binary.LittleEndian, [3]int32{0, 1, 2})
+
+	model, err := LoadPLY(data)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	geo := model.Meshes[0]
+
+	if len(geo.Vertices) != 3 || len(geo.Faces) != 1 {
+		t.Fatalf("%d vertices and %d faces were read, want 3 and 1", len(geo.Vertices), len(geo.Faces))
+	}
+
+	if geo.Vertices[1].Position != (Vector3{X: 1}) {
+		t.Errorf("vertex 1 is at %v, want (1, 0, 0)", geo.Vertices[1].Position)
+	}
+
+	if geo.Faces[0].TriangleIndices != [3]int32{0, 1, 2} {
+		t.Errorf("face is %v, want 0, 1, 2", geo.Faces[0].TriangleIndices)
+	}
+
+	// uchar colors go up to 255.
+	if color := geo.Vertices[0].Color; color != (Vector4{R: 1, G: 0, B: 0.2, A: 1}) {
+		t.Errorf("vertex 0 has color %v, want (1, 0, 0.2, 1)", color)
+	}
+}
+
+func TestLoadPLYColorTypes(t *testing.T) {
+	tests := []struct {
+		name       string
+		properties string
+		values     string
+	}{
+		{"uchar", "property uchar red\nproperty uchar green\nproperty uchar blue\nproperty uchar alpha\n", "255 51 0 102"},
+		{"float", "property float red\nproperty float green\nproperty float blue\nproperty float alpha\n", "1 0.2 0 0.4"},
+	}
+
+	for _, test := range tests {
+		document := "ply\nformat ascii 1.0\nelement vertex 1\nproperty float x\nproperty float y\nproperty float z\n" +
+			test.properties + "end_header\n0 0 0 " + test.values + "\n"
+		model, err := LoadPLY(strings.NewReader(document))
+
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		// 51 and 102 out of 255 are 0.2 and 0.4, the same as the float file's.
+		if color := model.Meshes[0].Vertices[0].Color; !near(color.R, 1) || !near(color.G, 0.2) || !near(color.B, 0) || !near(color.A, 0.4) {
+			t.Errorf("%s: color is %v, want (1, 0.2, 0, 0.4)", test.name, color)
+		}
+	}
+}
+
+func TestLoadPLYPointCloud(t *testing.T) {
+	document := "ply\nformat ascii 1.0\nelement vertex 3\nproperty float x\nproperty float y\nproperty float z\nend_header\n" +
+		"0 0 0\n1 0 0\n0 1 0\n"
+	model, err := LoadPLY(strings.NewReader(document))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if geo := model.Meshes[0]; geo.Mode != ModePoints || len(geo.Vertices) != 3 || len(geo.Faces) != 0 {
+		t.Errorf("a faceless file gave mode %d with %d vertices and %d faces, want ModePoints with 3 and 0", geo.Mode, len(geo.Vertices), len(geo.Faces))
+	}
+}
+
+func TestLoadPLYBigEndianRejected(t *testing.T) {
+	document := "ply\nformat binary_big_endian 1.0\nelement vertex 1\nproperty float x\nproperty float y\nproperty float z\nend_header\n" +
+		"\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"
+
+	if _, err := LoadPLY(strings.NewReader(document)); err == nil || !strings.Contains(err.Error(), "binary_big_endian") {
+		t.Errorf("a binary_big_endian file gave %v, want an error naming its format", err)
+	}
+}
@@ -0,0 +1,232 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// The KHR_mesh_quantization extension lets vertex attributes be stored as normalized integers rather than floats.
+// Positions are written as signed shorts in [-1,1], and a node transform scales and moves them back to where they
+// belong; normals and tangents are written as signed bytes, which is plenty for unit vectors.  The scale is the same on
+// every axis, so the transform doesn't bend the normals, at the cost of some precision along an object's thin sides.
+// See https://github.com/KhronosGroup/glTF/tree/main/extensions/2.0/Khronos/KHR_mesh_quantization
+
+const meshQuantizationExtensionName = "KHR_mesh_quantization"
+
+// Quantization is the frame a Geometry's positions are quantized in: a position p is stored as (p - Offset) / Scale,
+// which puts every component in [-1,1], and the mesh's node gets Offset as its translation and Scale as its scale.
+type Quantization struct {
+	Offset Vector3 `json:"offset"`
+	Scale  float32 `json:"scale"`
+}
+
+// returns the frame that fits every position of the supplied Geometry, skipping any that can't be quantized, and false
+// if none of them have any positions.
+func quantizationFrame(meshes []Geometry) (Quantization, bool) {
+	positions := []Vector3{}
+
+	for _, geo := range meshes {
+		if canQuantize(geo) {
+			positions = append(positions, getVertices(geo)...)
+		}
+	}
+
+	if len(positions) == 0 {
+		return Quantization{}, false
+	}
+
+	min, max := bounds(positions)
+	offset := Vector3{X: (min.X + max.X) / 2, Y: (min.Y + max.Y) / 2, Z: (min.Z + max.Z) / 2}
+	scale := float32(math.Max(float64(max.X-min.X), math.Max(float64(max.Y-min.Y), float64(max.Z-min.Z)))) / 2
+
+	// a single point, or a model of them all in one place, still needs a scale the node can use.
+	if scale == 0 {
+		scale = 1
+	}
+
+	return Quantization{Offset: offset, Scale: scale}, true
+}
+
+// reports whether the Geometry's positions can be quantized.  The transform of a skinned mesh's node is ignored, so its
+// positions would never be put back.
+func canQuantize(geo Geometry) bool {
+	return !geo.isGroup() && geo.Skin == nil && len(geo.Vertices) > 0
+}
+
+// sets the Quantization of each of the Model's Geometry that can be quantized, unless quantize is false.  A flat
+// Model's Geometry all end up on one node, so they share a frame; in a hierarchy each has its own.
+func quantizeModel(model Model, quantize bool) Model {
+	if !quantize {
+		return model
+	}
+
+	hierarchical := model.hasHierarchy()
+	shared, sharedOK := quantizationFrame(model.Meshes)
+
+	for i, geo := range model.Meshes {
+		if !canQuantize(geo) {
+			continue
+		}
+
+		frame, ok := shared, sharedOK
+
+		if hierarchical {
+			frame, ok = quantizationFrame([]Geometry{geo})
+		}
+
+		if ok {
+			model.Meshes[i].Quantization = &frame
+		}
+	}
+
+	return model
+}
+
+// returns the node transform that turns the quantized positions back into the originals.
+func (q Quantization) node() Node {
+	return Node{
+		Translation: []float64{float64(q.Offset.X), float64(q.Offset.Y), float64(q.Offset.Z)},
+		Scale:       []float64{float64(q.Scale), float64(q.Scale), float64(q.Scale)},
+	}
+}
+
+// returns a copy of the Geometry with its morph target position offsets divided by the frame's scale, since the node
+// transform scales them back up along with everything else.  The normal and tangent offsets are left alone, because the
+// scale is the same on every axis.
+func (q Quantization) scaleMorphs(geo Geometry) Geometry {
+	vertices := make([]Vertex, len(geo.Vertices))
+
+	for i, v := range geo.Vertices {
+		morphs := make([]MorphDelta, len(v.Morphs))
+
+		for t, delta := range v.Morphs {
+			delta.Position = Vector3{X: delta.Position.X / q.Scale, Y: delta.Position.Y / q.Scale, Z: delta.Position.Z / q.Scale}
+			morphs[t] = delta
+		}
+
+		v.Morphs = morphs
+		vertices[i] = v
+	}
+
+	geo.Vertices = vertices
+
+	return geo
+}
+
+// returns the value in [-1,1] as a normalized signed integer whose largest value is max.
+func quantizeComponent(value float32, max float64) float64 {
+	return math.Round(math.Max(-1, math.Min(1, float64(value))) * max)
+}
+
+// Appends the supplied positions to the supplied bytes.Buffer quantized in the supplied frame, as normalized signed
+// shorts, and adds a BufferView and Accessor for them.  Each element is padded to 8 bytes, since vertex attributes have
+// to start on a 4 byte boundary; the min and max are of the quantized values, as the spec asks.
+func getAccessorIndexFromQuantizedPositions(outBuf *bytes.Buffer, positions []Vector3, q Quantization, gltfBufferViews *[]BufferView, gltfAccessors *[]Accessor) (accessorIndex int) {
+	padToAlignment(outBuf)
+	byteOffset := outBuf.Len()
+
+	quantized := make([]Vector3, len(positions))
+
+	for i, p := range positions {
+		quantized[i] = Vector3{
+			X: float32(quantizeComponent((p.X-q.Offset.X)/q.Scale, math.MaxInt16)),
+			Y: float32(quantizeComponent((p.Y-q.Offset.Y)/q.Scale, math.MaxInt16)),
+			Z: float32(quantizeComponent((p.Z-q.Offset.Z)/q.Scale, math.MaxInt16)),
+		}
+
+		binary.Write(outBuf, binary.LittleEndian, [4]int16{int16(quantized[i].X), int16(quantized[i].Y), int16(quantized[i].Z), 0})
+	}
+
+	*gltfBufferViews = append(*gltfBufferViews, BufferView{
+		Buffer:     0,
+		ByteOffset: byteOffset,
+		ByteLength: outBuf.Len() - byteOffset,
+		ByteStride: 8,
+		Target:     34962,
+	})
+
+	min, max := bounds(quantized)
+
+	*gltfAccessors = append(*gltfAccessors, Accessor{
+		BufferView:    lastBufferView(*gltfBufferViews),
+		ByteOffset:    0,
+		ComponentType: Short,
+		Count:         len(positions),
+		Type:          Vec3,
+		Max:           []float32{max.X, max.Y, max.Z},
+		Min:           []float32{min.X, min.Y, min.Z},
+		Normalized:    true,
+	})
+
+	return len(*gltfAccessors) - 1
+}
+
+// Appends the supplied normals to the supplied bytes.Buffer as normalized signed bytes, each padded to 4 bytes, and adds
+// a BufferView and Accessor for them.
+func getAccessorIndexFromQuantizedNormals(outBuf *bytes.Buffer, normals []Vector3, gltfBufferViews *[]BufferView, gltfAccessors *[]Accessor) (accessorIndex int) {
+	padToAlignment(outBuf)
+	byteOffset := outBuf.Len()
+
+	for _, n := range normals {
+		binary.Write(outBuf, binary.LittleEndian, [4]int8{
+			int8(quantizeComponent(n.X, math.MaxInt8)),
+			int8(quantizeComponent(n.Y, math.MaxInt8)),
+			int8(quantizeComponent(n.Z, math.MaxInt8)),
+			0,
+		})
+	}
+
+	*gltfBufferViews = append(*gltfBufferViews, BufferView{
+		Buffer:     0,
+		ByteOffset: byteOffset,
+		ByteLength: outBuf.Len() - byteOffset,
+		ByteStride: 4,
+		Target:     34962,
+	})
+
+	*gltfAccessors = append(*gltfAccessors, Accessor{
+		BufferView:    lastBufferView(*gltfBufferViews),
+		ByteOffset:    0,
+		ComponentType: Byte,
+		Count:         len(normals),
+		Type:          Vec3,
+		Normalized:    true,
+	})
+
+	return len(*gltfAccessors) - 1
+}
+
+// Appends the supplied tangents to the supplied bytes.Buffer as normalized signed bytes, and adds a BufferView and
+// Accessor for them.  The handedness is 1 or -1, so it survives exactly.
+func getAccessorIndexFromQuantizedTangents(outBuf *bytes.Buffer, tangents []Vector4, gltfBufferViews *[]BufferView, gltfAccessors *[]Accessor) (accessorIndex int) {
+	padToAlignment(outBuf)
+	byteOffset := outBuf.Len()
+
+	for _, t := range tangents {
+		binary.Write(outBuf, binary.LittleEndian, [4]int8{
+			int8(quantizeComponent(t.R, math.MaxInt8)),
+			int8(quantizeComponent(t.G, math.MaxInt8)),
+			int8(quantizeComponent(t.B, math.MaxInt8)),
+			int8(quantizeComponent(t.A, math.MaxInt8)),
+		})
+	}
+
+	*gltfBufferViews = append(*gltfBufferViews, BufferView{
+		Buffer:     0,
+		ByteOffset: byteOffset,
+		ByteLength: outBuf.Len() - byteOffset,
+		Target:     34962,
+	})
+
+	*gltfAccessors = append(*gltfAccessors, Accessor{
+		BufferView:    lastBufferView(*gltfBufferViews),
+		ByteOffset:    0,
+		ComponentType: Byte,
+		Count:         len(tangents),
+		Type:          Vec4,
+		Normalized:    true,
+	})
+
+	return len(*gltfAccessors) - 1
+}
@@ -0,0 +1,64 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestQuantizedCubeRoundTrip(t *testing.T) {
+	cube, err := LoadSTL(bytes.NewReader(binarySTLCube()))
+
+	if err != nil {
+		t.Fatalf("LoadSTL: %v", err)
+	}
+
+	gltfDoc := optimizeForTest(t, cube, PipelineOptions{Options: Options{VertexColors: true}, Quantize: true})
+
+	for name, list := range map[string][]string{"extensionsUsed": gltfDoc.ExtensionsUsed, "extensionsRequired": gltfDoc.ExtensionsRequired} {
+		if len(list) != 1 || list[0] != meshQuantizationExtensionName {
+			t.Errorf("%s is %v, want just %s", name, list, meshQuantizationExtensionName)
+		}
+	}
+
+	primitive := gltfDoc.Meshes[0].Primitives[0]
+	position := gltfDoc.Accessors[primitive.Attributes["POSITION"]]
+
+	if position.ComponentType != Short || !position.Normalized {
+		t.Fatalf("POSITION is componentType %d, normalized %t; want %d, normalized", position.ComponentType, position.Normalized, Short)
+	}
+
+	if normal := gltfDoc.Accessors[primitive.Attributes["NORMAL"]]; normal.ComponentType != Byte || !normal.Normalized {
+		t.Errorf("NORMAL is componentType %d, normalized %t; want %d, normalized", normal.ComponentType, normal.Normalized, Byte)
+	}
+
+	var node *Node
+
+	for i := range gltfDoc.Nodes {
+		if mesh, ok := gltfDoc.Nodes[i].Mesh.(int); ok && mesh == 0 {
+			node = &gltfDoc.Nodes[i]
+		}
+	}
+
+	if node == nil || len(node.Translation) != 3 || len(node.Scale) != 3 {
+		t.Fatalf("the mesh's node is %+v, want one with the dequantization translation and scale", node)
+	}
+
+	view := gltfDoc.BufferViews[*position.BufferView]
+	data := gltfDoc.Buffers[0].Bytes[view.ByteOffset:]
+	// one step of a normalized short, in the cube's half-size frame, and a little for float32 rounding.
+	tolerance := node.Scale[0]/math.MaxInt16 + 1e-6
+
+	for i := 0; i < position.Count; i++ {
+		for c := 0; c < 3; c++ {
+			stored := int16(binary.LittleEndian.Uint16(data[i*view.ByteStride+2*c:]))
+			decoded := float64(stored)/math.MaxInt16*node.Scale[c] + node.Translation[c]
+
+			// every corner of the unit cube is at 0 or 1 on each axis.
+			if math.Abs(decoded-math.Round(decoded)) > tolerance || math.Round(decoded) < 0 || math.Round(decoded) > 1 {
+				t.Errorf("vertex %d component %d decodes to %g, which isn't within %g of 0 or 1", i, c, decoded, tolerance)
+			}
+		}
+	}
+}
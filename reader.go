@@ -0,0 +1,334 @@
+package gltf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	glbMagic     = "glTF"
+	glbHeaderLen = 12
+	glbChunkJSON = 0x4E4F534A // "JSON", read as a little endian uint32.
+	glbChunkBIN  = 0x004E4942 // "BIN\0", read as a little endian uint32.
+)
+
+// DefaultMaxBufferBytes is the MaxBufferBytes LoadGltf uses: far more than any real model needs, but finite, so a
+// hostile file can't make the reader allocate whatever its header claims.
+const DefaultMaxBufferBytes = 512 << 20
+
+// ErrBufferTooLarge is returned, wrapped with the size involved, when a document declares a buffer or GLB chunk, or
+// has JSON text, bigger than ReadOptions.MaxBufferBytes.
+var ErrBufferTooLarge = errors.New("larger than the reader's MaxBufferBytes")
+
+// ReadOptions controls LoadGltfWithOptions.
+type ReadOptions struct {
+	// MaxBufferBytes caps the byteLength of any buffer, the length of any GLB chunk, and the size of a .gltf file's
+	// JSON, and is checked before anything that size is allocated.  Zero means DefaultMaxBufferBytes; raise it for
+	// trusted inputs that need more.
+	MaxBufferBytes int64
+}
+
+// ErrUnsupportedVersion is returned, wrapped with the version, for glTF 1.0 files and anything else that isn't glTF 2.0:
+// either a GLB container version other than 2, or an asset.version with a major version other than 2.  Such files
+// aren't parsed any further.
+var ErrUnsupportedVersion = errors.New("unsupported glTF version")
+
+// returns the limit to read with, filling in the default.
+func (opts ReadOptions) maxBufferBytes() int64 {
+	if opts.MaxBufferBytes <= 0 {
+		return DefaultMaxBufferBytes
+	}
+
+	return opts.MaxBufferBytes
+}
+
+// LoadGltf reads a glTF document from r, which may hold either the JSON text of a .gltf file or the binary container
+// of a .glb file; the format is detected from the first bytes.  Buffer data is decoded into GltfBuffer.Bytes wherever
+// it's available without touching the filesystem: from the BIN chunk of a .glb, or from base64 data URIs.  Buffers
+// that refer to external files are left with no Bytes.  Buffers are limited to DefaultMaxBufferBytes; use
+// LoadGltfWithOptions to read bigger ones.  glTF 1.0 files are rejected with ErrUnsupportedVersion.
+func LoadGltf(r io.Reader) (*GlTF, error) {
+	return LoadGltfWithOptions(r, ReadOptions{})
+}
+
+// LoadGltfWithOptions is LoadGltf with the size limit in opts.
+func LoadGltfWithOptions(r io.Reader, opts ReadOptions) (*GlTF, error) {
+	limit := opts.maxBufferBytes()
+	br := bufio.NewReader(r)
+
+	if magic, _ := br.Peek(len(glbMagic)); string(magic) == glbMagic {
+		data, err := readGlb(br, limit)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return parseGlb(data, limit)
+	}
+
+	// a .gltf file's JSON holds any embedded buffers, so it's held to the same limit.
+	data, err := io.ReadAll(io.LimitReader(br, limit+1))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("glTF json is over %d bytes long: %w", limit, ErrBufferTooLarge)
+	}
+
+	if trimmed := bytes.TrimLeft(data, " \t\r\n\ufeff"); len(trimmed) > 0 && trimmed[0] == '{' {
+		return parseGltfJSON(trimmed, nil, limit)
+	}
+
+	return parseGlb(data, limit)
+}
+
+// reads a .glb file from r a chunk at a time, checking each chunk's length against limit before reading it in.  The
+// whole file is returned for parseGlb; reading stops early, leaving parseGlb to report the problem, if the file is
+// short or isn't a version it can read.
+func readGlb(r io.Reader, limit int64) ([]byte, error) {
+	data := make([]byte, glbHeaderLen)
+
+	if n, err := io.ReadFull(r, data); err != nil {
+		return data[:n], ignoreEOF(err)
+	}
+
+	if version := binary.LittleEndian.Uint32(data[4:8]); version != 2 {
+		return data, nil
+	}
+
+	length := uint64(binary.LittleEndian.Uint32(data[8:12]))
+
+	for index := 0; uint64(len(data))+8 <= length; index++ {
+		header := make([]byte, 8)
+
+		if n, err := io.ReadFull(r, header); err != nil {
+			return append(data, header[:n]...), ignoreEOF(err)
+		}
+
+		data = append(data, header...)
+		chunkLength := uint64(binary.LittleEndian.Uint32(header))
+
+		if chunkLength > uint64(limit) {
+			return nil, fmt.Errorf("GLB chunk %d is %d bytes long: %w", index, chunkLength, ErrBufferTooLarge)
+		}
+
+		if uint64(len(data))+chunkLength > length {
+			return data, nil
+		}
+
+		chunk := make([]byte, chunkLength)
+		n, err := io.ReadFull(r, chunk)
+		data = append(data, chunk[:n]...)
+
+		if err != nil {
+			return data, ignoreEOF(err)
+		}
+	}
+
+	return data, nil
+}
+
+// treats running out of input as a short file rather than a read error, since parseGlb says what's missing.
+func ignoreEOF(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil
+	}
+
+	return err
+}
+
+// parses a .glb file: a 12 byte header, a JSON chunk, and an optional BIN chunk holding the first buffer's data.
+func parseGlb(data []byte, limit int64) (*GlTF, error) {
+	if len(data) < glbHeaderLen {
+		return nil, fmt.Errorf("%d bytes is too short to be a glTF document", len(data))
+	}
+
+	if magic := string(data[0:4]); magic != glbMagic {
+		return nil, fmt.Errorf("not a glTF document: starts with %q rather than JSON or the GLB magic %q", magic, glbMagic)
+	}
+
+	if version := binary.LittleEndian.Uint32(data[4:8]); version != 2 {
+		return nil, fmt.Errorf("GLB container version %d: %w", version, ErrUnsupportedVersion)
+	}
+
+	length := binary.LittleEndian.Uint32(data[8:12])
+
+	if uint64(length) > uint64(len(data)) {
+		return nil, fmt.Errorf("GLB header gives a length of %d bytes, but there are only %d", length, len(data))
+	}
+
+	var jsonChunk, binChunk []byte
+
+	for offset, index := uint64(glbHeaderLen), 0; offset < uint64(length); index++ {
+		if offset+8 > uint64(length) {
+			return nil, fmt.Errorf("GLB chunk %d header runs past the end of the file", index)
+		}
+
+		chunkLength := uint64(binary.LittleEndian.Uint32(data[offset:]))
+		chunkType := binary.LittleEndian.Uint32(data[offset+4:])
+		start := offset + 8
+
+		if start+chunkLength > uint64(length) {
+			return nil, fmt.Errorf("GLB chunk %d is %d bytes long, which runs past the end of the file", index, chunkLength)
+		}
+
+		chunk := data[start : start+chunkLength]
+
+		switch {
+		case index == 0 && chunkType != glbChunkJSON:
+			return nil, fmt.Errorf("GLB chunk 0 has type %q; the first chunk must be %q", chunkTypeName(chunkType), "JSON")
+		case index == 0:
+			jsonChunk = chunk
+		case chunkType == glbChunkJSON:
+			return nil, fmt.Errorf("GLB chunk %d is a second JSON chunk", index)
+		case chunkType == glbChunkBIN && index != 1:
+			return nil, fmt.Errorf("GLB chunk %d is a BIN chunk; only the second chunk may be", index)
+		case chunkType == glbChunkBIN:
+			binChunk = chunk
+		}
+
+		// chunks of any other type are extension data, which the spec says to skip.
+		offset = start + chunkLength
+	}
+
+	if jsonChunk == nil {
+		return nil, errors.New("GLB file has no JSON chunk")
+	}
+
+	return parseGltfJSON(jsonChunk, binChunk, limit)
+}
+
+// decodes a document's JSON, then fills in buffer data from the GLB BIN chunk, if there was one, and from data URIs.
+// Buffers longer than limit are rejected before their data is decoded.
+func parseGltfJSON(data []byte, binChunk []byte, limit int64) (*GlTF, error) {
+	// glTF 1.0 lays out most of its top level differently, so the version has to be checked before decoding the rest.
+	var header struct {
+		Asset Asset `json:"asset"`
+	}
+
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("couldn't parse glTF json: %w", err)
+	}
+
+	if version := header.Asset.Version; version != "" && !strings.HasPrefix(version, "2.") {
+		return nil, fmt.Errorf("asset version %q: %w", version, ErrUnsupportedVersion)
+	}
+
+	gltfDoc := &GlTF{}
+
+	if err := json.Unmarshal(data, gltfDoc); err != nil {
+		return nil, fmt.Errorf("couldn't parse glTF json: %w", err)
+	}
+
+	if err := applyMaterialDefaults(data, gltfDoc); err != nil {
+		return nil, err
+	}
+
+	for i := range gltfDoc.Buffers {
+		buffer := &gltfDoc.Buffers[i]
+
+		// the file is untrusted, and a negative length would otherwise slice out of range below.
+		if buffer.ByteLength < 0 {
+			return nil, fmt.Errorf("buffer %d has a byteLength of %d, which is negative", i, buffer.ByteLength)
+		}
+
+		if int64(buffer.ByteLength) > limit {
+			return nil, fmt.Errorf("buffer %d has a byteLength of %d: %w", i, buffer.ByteLength, ErrBufferTooLarge)
+		}
+
+		switch {
+		case i == 0 && buffer.URI == "" && binChunk != nil:
+			// the BIN chunk may be padded by up to 3 bytes past the buffer's real length.
+			if buffer.ByteLength > len(binChunk) {
+				return nil, fmt.Errorf("buffer 0 is %d bytes long, but the GLB BIN chunk only has %d", buffer.ByteLength, len(binChunk))
+			}
+
+			buffer.Bytes = binChunk[:buffer.ByteLength]
+		case strings.HasPrefix(buffer.URI, "data:"):
+			decoded, err := decodeDataURI(buffer.URI)
+
+			if err != nil {
+				return nil, fmt.Errorf("buffer %d: %w", i, err)
+			}
+
+			if buffer.ByteLength > len(decoded) {
+				return nil, fmt.Errorf("buffer %d is %d bytes long, but its data URI only holds %d", i, buffer.ByteLength, len(decoded))
+			}
+
+			buffer.Bytes = decoded[:buffer.ByteLength]
+		}
+	}
+
+	return gltfDoc, nil
+}
+
+// fills in the spec defaults for the pbrMetallicRoughness factors a document leaves out: 1 for metallicFactor and
+// roughnessFactor, and opaque white for baseColorFactor.  The zero values GlTF decodes them to would otherwise make
+// every such material a smooth dielectric, so the JSON is decoded again with pointers to tell absent from 0.
+func applyMaterialDefaults(data []byte, gltfDoc *GlTF) error {
+	var present struct {
+		Materials []struct {
+			PbrMetallicRoughness struct {
+				BaseColorFactor *[]float64 `json:"baseColorFactor"`
+				MetallicFactor  *float64   `json:"metallicFactor"`
+				RoughnessFactor *float64   `json:"roughnessFactor"`
+			} `json:"pbrMetallicRoughness"`
+		} `json:"materials"`
+	}
+
+	if err := json.Unmarshal(data, &present); err != nil {
+		return fmt.Errorf("couldn't parse glTF json: %w", err)
+	}
+
+	for i, material := range present.Materials {
+		pbr := &gltfDoc.Materials[i].PbrMetallicRoughness
+
+		if material.PbrMetallicRoughness.BaseColorFactor == nil {
+			pbr.BaseColorFactor = []float64{1, 1, 1, 1}
+		}
+
+		if material.PbrMetallicRoughness.MetallicFactor == nil {
+			pbr.MetallicFactor = 1
+		}
+
+		if material.PbrMetallicRoughness.RoughnessFactor == nil {
+			pbr.RoughnessFactor = 1
+		}
+	}
+
+	return nil
+}
+
+// decodes the payload of a base64 data URI, such as the ones SerializeEmbeddedGlTF writes.
+func decodeDataURI(uri string) ([]byte, error) {
+	comma := strings.Index(uri, ",")
+
+	if comma < 0 || !strings.HasSuffix(uri[:comma], ";base64") {
+		return nil, errors.New("only base64 data URIs are supported")
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(uri[comma+1:])
+
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decode data URI: %w", err)
+	}
+
+	return decoded, nil
+}
+
+// returns a chunk type as the four characters it's made of, for error messages.
+func chunkTypeName(chunkType uint32) string {
+	name := make([]byte, 4)
+	binary.LittleEndian.PutUint32(name, chunkType)
+
+	return string(name)
+}
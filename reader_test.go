@@ -0,0 +1,147 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadGltfNegativeByteLength(t *testing.T) {
+	document := `{"asset":{"version":"2.0"},"buffers":[{"byteLength":-1,"uri":"data:application/octet-stream;base64,AAAA"}]}`
+
+	if _, err := LoadGltf(strings.NewReader(document)); err == nil {
+		t.Error("a buffer with a negative byteLength was accepted")
+	}
+}
+
+func TestLoadGltfOversizedBuffer(t *testing.T) {
+	document := `{"asset":{"version":"2.0"},"buffers":[{"byteLength":1099511627776,"uri":"data:application/octet-stream;base64,AAAA"}]}`
+
+	if _, err := LoadGltf(strings.NewReader(document)); !errors.Is(err, ErrBufferTooLarge) {
+		t.Errorf("a 1TB buffer gave %v, not ErrBufferTooLarge", err)
+	}
+}
+
+// reads fail the test: it stands in for the body of a chunk that shouldn't be read, let alone allocated.
+type unreadable struct{ t *testing.T }
+
+func (r unreadable) Read([]byte) (int, error) {
+	r.t.Error("the oversized BIN chunk was read")
+	return 0, io.EOF
+}
+
+func TestLoadGlbOversizedChunk(t *testing.T) {
+	jsonChunk := []byte(`{"asset":{"version":"2.0"},"buffers":[{"byteLength":1073741824}]}`)
+
+	// a JSON chunk has to be padded with spaces to a multiple of 4 bytes.
+	for len(jsonChunk)%4 != 0 {
+		jsonChunk = append(jsonChunk, ' ')
+	}
+
+	header := make([]byte, glbHeaderLen+8)
+	copy(header, glbMagic)
+	binary.LittleEndian.PutUint32(header[4:], 2)
+	binary.LittleEndian.PutUint32(header[8:], 0xFFFFFFFC)
+	binary.LittleEndian.PutUint32(header[12:], uint32(len(jsonChunk)))
+	binary.LittleEndian.PutUint32(header[16:], glbChunkJSON)
+
+	// the BIN chunk's header claims 1GB, and there's nothing after it.
+	binHeader := make([]byte, 8)
+	binary.LittleEndian.PutUint32(binHeader, 1<<30)
+	binary.LittleEndian.PutUint32(binHeader[4:], glbChunkBIN)
+
+	glb := io.MultiReader(bytes.NewReader(header), bytes.NewReader(jsonChunk), bytes.NewReader(binHeader), unreadable{t})
+
+	if _, err := LoadGltf(glb); !errors.Is(err, ErrBufferTooLarge) {
+		t.Errorf("a 1GB BIN chunk gave %v, not ErrBufferTooLarge", err)
+	}
+}
+
+func TestLoadGltfMaxBufferBytes(t *testing.T) {
+	gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{testTriangle(Material{Opacity: 1})}}, PipelineOptions{Options: Options{VertexColors: true}})
+	data, err := SerializeBinaryGlTF(*gltfDoc)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadGltfWithOptions(bytes.NewReader(data), ReadOptions{MaxBufferBytes: 16}); !errors.Is(err, ErrBufferTooLarge) {
+		t.Errorf("a 16 byte limit gave %v, not ErrBufferTooLarge", err)
+	}
+
+	if _, err := LoadGltfWithOptions(bytes.NewReader(data), ReadOptions{MaxBufferBytes: int64(len(data))}); err != nil {
+		t.Errorf("a limit as long as the whole file: %v", err)
+	}
+}
+
+func TestLoadGltfVersion1(t *testing.T) {
+	// glTF 1.0 keeps buffers in an object keyed by id, which wouldn't decode into GlTF.Buffers at all.
+	document := `{"asset":{"version":"1.0"},"buffers":{"buffer_0":{"byteLength":4,"uri":"data.bin"}}}`
+
+	if _, err := LoadGltf(strings.NewReader(document)); !errors.Is(err, ErrUnsupportedVersion) || !strings.Contains(err.Error(), `"1.0"`) {
+		t.Errorf("a glTF 1.0 document gave %v, not ErrUnsupportedVersion naming 1.0", err)
+	}
+
+	// a 1.0 GLB header: magic, version, length, then the content's length and format, where 2.0 has chunks.
+	content := []byte(document)
+	glb := make([]byte, 20, 20+len(content))
+	copy(glb, glbMagic)
+	binary.LittleEndian.PutUint32(glb[4:], 1)
+	binary.LittleEndian.PutUint32(glb[8:], uint32(20+len(content)))
+	binary.LittleEndian.PutUint32(glb[12:], uint32(len(content)))
+	glb = append(glb, content...)
+
+	if _, err := LoadGltf(bytes.NewReader(glb)); !errors.Is(err, ErrUnsupportedVersion) || !strings.Contains(err.Error(), "version 1") {
+		t.Errorf("a version 1 GLB gave %v, not ErrUnsupportedVersion naming version 1", err)
+	}
+}
+
+func TestLoadGltfMaterialDefaults(t *testing.T) {
+	document := `{"asset":{"version":"2.0"},"materials":[{},{"pbrMetallicRoughness":{"metallicFactor":0,"roughnessFactor":0}}]}`
+	gltfDoc, err := LoadGltf(strings.NewReader(document))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defaults := gltfDoc.Materials[0].PbrMetallicRoughness
+
+	if defaults.MetallicFactor != 1 || defaults.RoughnessFactor != 1 || !reflect.DeepEqual(defaults.BaseColorFactor, []float64{1, 1, 1, 1}) {
+		t.Errorf("a minimal material read as %+v, not with the spec defaults", defaults)
+	}
+
+	if explicit := gltfDoc.Materials[1].PbrMetallicRoughness; explicit.MetallicFactor != 0 || explicit.RoughnessFactor != 0 {
+		t.Errorf("explicit zero factors read as metallic %v and roughness %v", explicit.MetallicFactor, explicit.RoughnessFactor)
+	}
+}
+
+func TestSerializeRoundTrip(t *testing.T) {
+	gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{testTriangle(Material{Opacity: 1})}}, PipelineOptions{Options: Options{VertexColors: true}})
+
+	for name, serialize := range map[string]func(GlTF) ([]byte, error){
+		"binary":   SerializeBinaryGlTF,
+		"embedded": SerializeEmbeddedGlTF,
+	} {
+		data, err := serialize(*gltfDoc)
+
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		loaded, err := LoadGltf(bytes.NewReader(data))
+
+		if err != nil {
+			t.Errorf("%s: LoadGltf: %v", name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(loaded.Buffers[0].Bytes, gltfDoc.Buffers[0].Bytes) {
+			t.Errorf("%s: the buffer didn't survive the round trip", name)
+		}
+	}
+}
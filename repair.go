@@ -0,0 +1,339 @@
+package gltf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// RepairAction describes one change made by (*GlTF).Repair.
+type RepairAction struct {
+	Path        string // where the change was made, such as "accessors[3]" or "asset".
+	Description string
+}
+
+// Repair fixes the spec violations that external validators most often report, in place, and returns a description
+// of each change it made.  It is deliberately conservative: it only fills in missing or inconsistent metadata and
+// never changes geometry.  Specifically, it:
+//
+//   - adds a missing asset, or a missing asset.version, as version "2.0";
+//   - adds a scene holding every root node when the document refers to a default scene that doesn't exist;
+//   - adds every entry of extensionsRequired to extensionsUsed, removes duplicates from both, and sorts them;
+//   - normalizes node rotations that aren't unit quaternions;
+//   - sets each buffer view's target to match how mesh primitives use it;
+//   - computes min and max for POSITION accessors, morph targets' included, that lack them, if their data is available.
+func (gltfDoc *GlTF) Repair() []RepairAction {
+	actions := []RepairAction{}
+
+	report := func(path string, format string, args ...interface{}) {
+		actions = append(actions, RepairAction{Path: path, Description: fmt.Sprintf(format, args...)})
+	}
+
+	gltfDoc.repairAsset(report)
+	gltfDoc.repairScene(report)
+	gltfDoc.repairExtensionLists(report)
+	gltfDoc.repairRotations(report)
+	gltfDoc.repairBufferViewTargets(report)
+	gltfDoc.repairPositionBounds(report)
+
+	return actions
+}
+
+type repairReporter func(path string, format string, args ...interface{})
+
+func (gltfDoc *GlTF) repairAsset(report repairReporter) {
+	switch asset := gltfDoc.Asset.(type) {
+	case nil:
+		gltfDoc.Asset = Asset{Version: "2.0"}
+		report("asset", "added missing asset with version 2.0")
+	case Asset:
+		if asset.Version == "" {
+			asset.Version = "2.0"
+			gltfDoc.Asset = asset
+			report("asset", "added missing version 2.0")
+		}
+	case *Asset:
+		if asset.Version == "" {
+			asset.Version = "2.0"
+			report("asset", "added missing version 2.0")
+		}
+	case map[string]interface{}:
+		if version, _ := asset["version"].(string); version == "" {
+			asset["version"] = "2.0"
+			report("asset", "added missing version 2.0")
+		}
+	}
+}
+
+func (gltfDoc *GlTF) repairScene(report repairReporter) {
+	if _, ok := gltfDoc.defaultScene(); ok || gltfDoc.Scene == nil {
+		return
+	}
+
+	// with no scenes at all, the document is a library of nodes and the scene index is just wrong.
+	if len(gltfDoc.Scenes) == 0 {
+		report("scene", "removed default scene %d, since the document has no scenes", *gltfDoc.Scene)
+		gltfDoc.Scene = nil
+
+		return
+	}
+
+	// otherwise the default was meant to be shown, so give it something to point at.
+	parents, err := gltfDoc.parentMap()
+
+	if err != nil {
+		return
+	}
+
+	scene := Scene{}
+
+	for node, parent := range parents {
+		if parent == -1 {
+			scene.Nodes = append(scene.Nodes, node)
+		}
+	}
+
+	gltfDoc.Scenes = append(gltfDoc.Scenes, scene)
+	report("scene", "default scene %d did not exist; added scene %d containing the %d root nodes", *gltfDoc.Scene, len(gltfDoc.Scenes)-1, len(scene.Nodes))
+	sceneIndex := len(gltfDoc.Scenes) - 1
+	gltfDoc.Scene = &sceneIndex
+}
+
+func (gltfDoc *GlTF) repairExtensionLists(report repairReporter) {
+	gltfDoc.ExtensionsUsed = dedupe(gltfDoc.ExtensionsUsed, func(name string) {
+		report("extensionsUsed", "removed duplicate %s", name)
+	})
+
+	gltfDoc.ExtensionsRequired = dedupe(gltfDoc.ExtensionsRequired, func(name string) {
+		report("extensionsRequired", "removed duplicate %s", name)
+	})
+
+	for _, required := range gltfDoc.ExtensionsRequired {
+		used := false
+
+		for _, name := range gltfDoc.ExtensionsUsed {
+			used = used || name == required
+		}
+
+		if !used {
+			gltfDoc.ExtensionsUsed = append(gltfDoc.ExtensionsUsed, required)
+			report("extensionsUsed", "added %s, which is listed in extensionsRequired", required)
+		}
+	}
+
+	// reordering isn't worth reporting; it's only so the output doesn't depend on the order things were found in.
+	sort.Strings(gltfDoc.ExtensionsUsed)
+	sort.Strings(gltfDoc.ExtensionsRequired)
+}
+
+// returns names without duplicates, keeping the first of each, and calls removed for every duplicate dropped.
+func dedupe(names []string, removed func(name string)) []string {
+	if names == nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	out := []string{}
+
+	for _, name := range names {
+		if seen[name] {
+			removed(name)
+			continue
+		}
+
+		seen[name] = true
+		out = append(out, name)
+	}
+
+	return out
+}
+
+func (gltfDoc *GlTF) repairRotations(report repairReporter) {
+	for i := range gltfDoc.Nodes {
+		r := gltfDoc.Nodes[i].Rotation
+
+		if len(r) != 4 {
+			continue
+		}
+
+		length := math.Sqrt(r[0]*r[0] + r[1]*r[1] + r[2]*r[2] + r[3]*r[3])
+
+		if length == 0 || math.Abs(length-1) < 1e-6 {
+			continue
+		}
+
+		for c := range r {
+			r[c] /= length
+		}
+
+		report(fmt.Sprintf("nodes[%d]", i), "normalized rotation with length %g", length)
+	}
+}
+
+func (gltfDoc *GlTF) repairBufferViewTargets(report repairReporter) {
+	targets := make(map[int]int)
+
+	use := func(accessorIndex int, target int) {
+		if accessorIndex >= 0 && accessorIndex < len(gltfDoc.Accessors) {
+			targets[gltfDoc.Accessors[accessorIndex].bufferViewIndex()] = target
+		}
+	}
+
+	for _, mesh := range gltfDoc.Meshes {
+		for _, primitive := range mesh.Primitives {
+			for _, accessorIndex := range primitive.Attributes {
+				use(accessorIndex, 34962)
+			}
+
+			for _, target := range primitive.Targets {
+				for _, accessorIndex := range target {
+					use(accessorIndex, 34962)
+				}
+			}
+
+			if primitive.Indices != nil {
+				use(*primitive.Indices, 34963)
+			}
+		}
+	}
+
+	for viewIndex := range gltfDoc.BufferViews {
+		target, ok := targets[viewIndex]
+
+		if !ok {
+			continue
+		}
+
+		view := &gltfDoc.BufferViews[viewIndex]
+
+		if jsonNumber(view.Target) != target {
+			report(fmt.Sprintf("bufferViews[%d]", viewIndex), "set target to %d to match its use by mesh primitives", target)
+			view.Target = target
+		}
+	}
+}
+
+func (gltfDoc *GlTF) repairPositionBounds(report repairReporter) {
+	repair := func(attributes Attributes) {
+		accessorIndex, ok := attributes["POSITION"]
+
+		if !ok || accessorIndex < 0 || accessorIndex >= len(gltfDoc.Accessors) {
+			return
+		}
+
+		accessor := &gltfDoc.Accessors[accessorIndex]
+
+		if len(accessor.Min) == 3 && len(accessor.Max) == 3 {
+			return
+		}
+
+		// the bounds are of what the accessor holds once any sparse elements are applied, not of its base.
+		positions, ok := gltfDoc.readVector3Floats(*accessor)
+
+		if !ok {
+			return
+		}
+
+		min, max := bounds(positions)
+		accessor.Min = []float32{min.X, min.Y, min.Z}
+		accessor.Max = []float32{max.X, max.Y, max.Z}
+
+		report(fmt.Sprintf("accessors[%d]", accessorIndex), "added POSITION min and max")
+	}
+
+	// morph targets' POSITION offsets need bounds just as the positions do.
+	for _, mesh := range gltfDoc.Meshes {
+		for _, primitive := range mesh.Primitives {
+			repair(primitive.Attributes)
+
+			for _, target := range primitive.Targets {
+				repair(target)
+			}
+		}
+	}
+}
+
+// reads a FLOAT VEC3 accessor's data from the document's buffers, if it's available: its buffer view's, or zeros if it
+// has none, with the elements a sparse accessor overrides replaced.  Accessors whose data isn't loaded into
+// GltfBuffer.Bytes aren't supported.
+func (gltfDoc GlTF) readVector3Floats(accessor Accessor) ([]Vector3, bool) {
+	if accessor.ComponentType != Float || accessor.Type != Vec3 || accessor.Count < 1 {
+		return nil, false
+	}
+
+	positions := make([]Vector3, accessor.Count)
+
+	if accessor.BufferView != nil {
+		data, stride, ok := gltfDoc.viewBytes(*accessor.BufferView, accessor.ByteOffset, accessor.Count, 12, 0)
+
+		if !ok {
+			return nil, false
+		}
+
+		for i := range positions {
+			offset := i * stride
+
+			positions[i] = Vector3{
+				X: math.Float32frombits(binary.LittleEndian.Uint32(data[offset:])),
+				Y: math.Float32frombits(binary.LittleEndian.Uint32(data[offset+4:])),
+				Z: math.Float32frombits(binary.LittleEndian.Uint32(data[offset+8:])),
+			}
+		}
+	}
+
+	if accessor.Sparse != nil && !gltfDoc.applySparseVector3(*accessor.Sparse, positions) {
+		return nil, false
+	}
+
+	return positions, true
+}
+
+// returns the bytes of the buffer view from byteOffset on, which hold count elements of elementSize bytes, one every
+// stride bytes, and that stride.  A stride of 0 is the view's ByteStride, or elementSize if it has none.  It returns
+// false if the view or its buffer doesn't exist, any of the offsets or strides is negative, or the elements aren't all
+// in the view and loaded into Bytes.
+func (gltfDoc GlTF) viewBytes(viewIndex int, byteOffset int, count int, elementSize int, stride int) ([]byte, int, bool) {
+	if viewIndex < 0 || viewIndex >= len(gltfDoc.BufferViews) || count < 1 || byteOffset < 0 || stride < 0 {
+		return nil, 0, false
+	}
+
+	view := gltfDoc.BufferViews[viewIndex]
+
+	// a loaded document can say anything, and a negative offset or stride would index outside the buffer.
+	if view.Buffer < 0 || view.Buffer >= len(gltfDoc.Buffers) || view.ByteOffset < 0 || view.ByteStride < 0 {
+		return nil, 0, false
+	}
+
+	data := gltfDoc.Buffers[view.Buffer].Bytes
+
+	if stride == 0 {
+		stride = view.ByteStride
+	}
+
+	if stride == 0 {
+		stride = elementSize
+	}
+
+	start := view.ByteOffset + byteOffset
+	end := start + (count-1)*stride + elementSize
+
+	if start < 0 || end < start || end > len(data) || end > view.ByteOffset+view.ByteLength {
+		return nil, 0, false
+	}
+
+	return data[start:end], stride, true
+}
+
+// untyped fields hold an int when the document was built in Go, and a float64 when it was decoded from JSON.  This
+// returns the value either way, or -1 if it isn't a number.
+func jsonNumber(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	default:
+		return -1
+	}
+}
@@ -0,0 +1,28 @@
+package gltf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRepairNegativeByteOffset(t *testing.T) {
+	document := `{
+		"asset": {"version": "2.0"},
+		"buffers": [{"byteLength": 36, "uri": "data:application/octet-stream;base64,` + strings.Repeat("A", 48) + `"}],
+		"bufferViews": [{"buffer": 0, "byteOffset": -8, "byteLength": 36}],
+		"accessors": [{"bufferView": 0, "componentType": 5126, "count": 3, "type": "VEC3"}],
+		"meshes": [{"primitives": [{"attributes": {"POSITION": 0}}]}]
+	}`
+
+	gltfDoc, err := LoadGltf(strings.NewReader(document))
+
+	if err != nil {
+		t.Fatalf("LoadGltf: %v", err)
+	}
+
+	gltfDoc.Repair()
+
+	if accessor := gltfDoc.Accessors[0]; accessor.Min != nil || accessor.Max != nil {
+		t.Errorf("bounds %v and %v were added from a view that starts before its buffer", accessor.Min, accessor.Max)
+	}
+}
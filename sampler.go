@@ -0,0 +1,88 @@
+package gltf
+
+import "fmt"
+
+// Sampler is a glTF texture sampler: how a texture is filtered when it's magnified or minified, and how it wraps
+// outside [0,1] along U (S) and V (T).  Zero fields aren't written, leaving the filtering up to the viewer and the
+// wrapping at the spec default, WrapRepeat.
+type Sampler struct {
+	MagFilter TextureFilter `json:"magFilter,omitempty"`
+	MinFilter TextureFilter `json:"minFilter,omitempty"`
+	WrapS     WrapMode      `json:"wrapS,omitempty"`
+	WrapT     WrapMode      `json:"wrapT,omitempty"`
+}
+
+// TextureFilter is a sampler's magnification or minification filter.  Only FilterNearest and FilterLinear are allowed
+// for magnification; the mipmap filters are for minification.
+type TextureFilter int
+
+// The filters glTF allows.
+const (
+	FilterNearest              TextureFilter = 9728
+	FilterLinear               TextureFilter = 9729
+	FilterNearestMipmapNearest TextureFilter = 9984
+	FilterLinearMipmapNearest  TextureFilter = 9985
+	FilterNearestMipmapLinear  TextureFilter = 9986
+	FilterLinearMipmapLinear   TextureFilter = 9987
+)
+
+// WrapMode is how a sampler treats texture coordinates outside [0,1].
+type WrapMode int
+
+// The wrap modes glTF allows.
+const (
+	WrapClampToEdge    WrapMode = 33071
+	WrapMirroredRepeat WrapMode = 33648
+	WrapRepeat         WrapMode = 10497
+)
+
+// returns the sampler the Material's textures need, and false if it leaves everything at the defaults, in which case
+// its textures get no sampler at all.
+func (material Material) sampler() (Sampler, bool) {
+	sampler := Sampler{
+		MagFilter: material.MagFilter,
+		MinFilter: material.MinFilter,
+		WrapS:     material.Wrap,
+		WrapT:     material.Wrap,
+	}
+
+	return sampler, sampler != Sampler{}
+}
+
+// returns the index of the supplied sampler in gltfSamplers, adding it if an identical one isn't already there.
+func addSampler(sampler Sampler, gltfSamplers *[]Sampler) int {
+	for i, s := range *gltfSamplers {
+		if s == sampler {
+			return i
+		}
+	}
+
+	*gltfSamplers = append(*gltfSamplers, sampler)
+
+	return len(*gltfSamplers) - 1
+}
+
+// makes sure each of the sampler's settings is one the spec allows, or unset.
+func (sampler Sampler) check() error {
+	switch sampler.MagFilter {
+	case 0, FilterNearest, FilterLinear:
+	default:
+		return fmt.Errorf("magFilter is %d; it has to be %d or %d", sampler.MagFilter, FilterNearest, FilterLinear)
+	}
+
+	switch sampler.MinFilter {
+	case 0, FilterNearest, FilterLinear, FilterNearestMipmapNearest, FilterLinearMipmapNearest, FilterNearestMipmapLinear, FilterLinearMipmapLinear:
+	default:
+		return fmt.Errorf("minFilter %d is not a filter glTF allows", sampler.MinFilter)
+	}
+
+	for _, wrap := range []WrapMode{sampler.WrapS, sampler.WrapT} {
+		switch wrap {
+		case 0, WrapClampToEdge, WrapMirroredRepeat, WrapRepeat:
+		default:
+			return fmt.Errorf("wrap mode %d is not %d, %d or %d", wrap, WrapClampToEdge, WrapMirroredRepeat, WrapRepeat)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package gltf
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestClampToEdgeSampler(t *testing.T) {
+	clamped := func(normalMap string, color float32) Material {
+		return Material{DiffuseColor: [3]float32{color, 0, 0}, Opacity: 1, NormalMapPath: normalMap, Wrap: WrapClampToEdge}
+	}
+
+	model := Model{Meshes: []Geometry{
+		testTriangle(clamped("first.png", 1)),
+		testTriangle(clamped("second.png", 0.5)),
+		// the defaults need no sampler at all.
+		testTriangle(Material{Opacity: 1, NormalMapPath: "third.png"}),
+	}}
+	gltfDoc := optimizeForTest(t, model, PipelineOptions{Options: Options{VertexColors: true}})
+
+	// the two clamped materials ask for the same sampler, so they share one.
+	if len(gltfDoc.Samplers) != 1 {
+		t.Fatalf("got %d samplers, want 1", len(gltfDoc.Samplers))
+	}
+
+	data, err := json.Marshal(gltfDoc.Samplers[0])
+
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	if want := `{"wrapS":33071,"wrapT":33071}`; string(data) != want {
+		t.Errorf("sampler marshals to %s, want %s", data, want)
+	}
+
+	for i, material := range gltfDoc.Materials {
+		texture := gltfDoc.Textures[material.NormalTexture.Index]
+
+		switch {
+		case i < 2 && (texture.Sampler == nil || *texture.Sampler != 0):
+			t.Errorf("materials[%d]'s normal map doesn't use the clamped sampler", i)
+		case i == 2 && texture.Sampler != nil:
+			t.Errorf("materials[2]'s normal map has sampler %d, want none", *texture.Sampler)
+		}
+	}
+}
+
+func TestSamplerCheck(t *testing.T) {
+	for _, sampler := range []Sampler{
+		{MagFilter: FilterLinearMipmapLinear},
+		{MinFilter: 1234},
+		{WrapS: WrapClampToEdge, WrapT: 1},
+	} {
+		if err := sampler.check(); err == nil {
+			t.Errorf("sampler %+v was accepted", sampler)
+		}
+	}
+}
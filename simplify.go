@@ -0,0 +1,418 @@
+package gltf
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Simplify returns a copy of the supplied Geometry with its triangle count reduced to about targetRatio (0..1) of the
+// original, using quadric error metric edge collapse (Garland & Heckbert, "Surface Simplification Using Quadric Error
+// Metrics", 1997).  The cheapest edge, measured by how far the merged vertex would be from the planes of the original
+// surrounding triangles, is collapsed first, over and over, until the target is reached or no edge can be collapsed.
+//
+// Vertices on a boundary edge (an edge used by only one triangle) never move and are never removed, so open edges and
+// UV or normal seams keep their shape.  Collapses that would flip the facing of any remaining triangle are skipped.
+// Normals, UVs, colors and velocities of the merged vertex are interpolated along the collapsed edge.
+func Simplify(geo Geometry, targetRatio float32) Geometry {
+	s := newSimplifier(geo)
+
+	target := int(math.Ceil(float64(len(geo.Faces)) * float64(targetRatio)))
+
+	for s.faceCount > target && s.queue.Len() > 0 {
+		e := heap.Pop(&s.queue).(collapse)
+
+		// skip collapses queued before either vertex last changed.
+		if s.removed[e.a] || s.removed[e.b] || e.versionA != s.versions[e.a] || e.versionB != s.versions[e.b] {
+			continue
+		}
+
+		if s.flips(e.a, e.b, e.position) || s.flips(e.b, e.a, e.position) {
+			continue
+		}
+
+		s.collapse(e)
+	}
+
+	return s.result()
+}
+
+// quadric is a symmetric 4x4 matrix stored as its 10 unique entries.
+type quadric [10]float64
+
+// returns the quadric of the plane ax + by + cz + d = 0.
+func planeQuadric(a, b, c, d float64) quadric {
+	return quadric{
+		a * a, a * b, a * c, a * d,
+		b * b, b * c, b * d,
+		c * c, c * d,
+		d * d,
+	}
+}
+
+func (q quadric) add(o quadric) quadric {
+	for i := range q {
+		q[i] += o[i]
+	}
+
+	return q
+}
+
+// returns the squared distance error of placing a vertex at (x, y, z).
+func (q quadric) error(x, y, z float64) float64 {
+	return q[0]*x*x + 2*q[1]*x*y + 2*q[2]*x*z + 2*q[3]*x +
+		q[4]*y*y + 2*q[5]*y*z + 2*q[6]*y +
+		q[7]*z*z + 2*q[8]*z +
+		q[9]
+}
+
+// returns the position that minimizes the error, if the quadric isn't singular.
+func (q quadric) optimal() (x, y, z float64, ok bool) {
+	det := q[0]*(q[4]*q[7]-q[5]*q[5]) - q[1]*(q[1]*q[7]-q[5]*q[2]) + q[2]*(q[1]*q[5]-q[4]*q[2])
+
+	if math.Abs(det) < 1e-12 {
+		return 0, 0, 0, false
+	}
+
+	// Cramer's rule on the upper 3x3, with the negated last column as the right hand side.
+	bx, by, bz := -q[3], -q[6], -q[8]
+
+	x = (bx*(q[4]*q[7]-q[5]*q[5]) - q[1]*(by*q[7]-q[5]*bz) + q[2]*(by*q[5]-q[4]*bz)) / det
+	y = (q[0]*(by*q[7]-bz*q[5]) - bx*(q[1]*q[7]-q[5]*q[2]) + q[2]*(q[1]*bz-by*q[2])) / det
+	z = (q[0]*(q[4]*bz-q[5]*by) - q[1]*(q[1]*bz-by*q[2]) + bx*(q[1]*q[5]-q[4]*q[2])) / det
+
+	return x, y, z, true
+}
+
+// collapse is a queued edge collapse: vertex b merges into vertex a, which moves to position.
+type collapse struct {
+	a, b               int32
+	versionA, versionB int
+	position           Vector3
+	t                  float32 // how far along the edge from a to b the position is, for interpolating attributes.
+	cost               float64
+}
+
+type collapseQueue []collapse
+
+func (q collapseQueue) Len() int            { return len(q) }
+func (q collapseQueue) Less(i, j int) bool  { return q[i].cost < q[j].cost }
+func (q collapseQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *collapseQueue) Push(x interface{}) { *q = append(*q, x.(collapse)) }
+func (q *collapseQueue) Pop() interface{} {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+
+	return c
+}
+
+type simplifier struct {
+	vertices    []Vertex
+	faces       []Triangle
+	material    Material
+	quadrics    []quadric
+	boundary    []bool
+	removed     []bool
+	faceRemoved []bool
+	versions    []int
+	vertexFaces [][]int // the faces using each vertex.
+	faceCount   int
+	queue       collapseQueue
+}
+
+func newSimplifier(geo Geometry) *simplifier {
+	s := &simplifier{
+		vertices:    append([]Vertex{}, geo.Vertices...),
+		faces:       append([]Triangle{}, geo.Faces...),
+		material:    geo.Material,
+		quadrics:    make([]quadric, len(geo.Vertices)),
+		boundary:    make([]bool, len(geo.Vertices)),
+		removed:     make([]bool, len(geo.Vertices)),
+		faceRemoved: make([]bool, len(geo.Faces)),
+		versions:    make([]int, len(geo.Vertices)),
+		vertexFaces: make([][]int, len(geo.Vertices)),
+		faceCount:   len(geo.Faces),
+	}
+
+	// count how many faces use each edge, to find the boundaries.
+	edgeUse := make(map[[2]int32]int)
+
+	for f, face := range s.faces {
+		n := faceNormal(geo, face)
+		length := math.Sqrt(float64(dot(n, n)))
+
+		if length > 0 {
+			a, b, c := float64(n.X)/length, float64(n.Y)/length, float64(n.Z)/length
+			p := s.vertices[face.TriangleIndices[0]].Position
+			d := -(a*float64(p.X) + b*float64(p.Y) + c*float64(p.Z))
+
+			q := planeQuadric(a, b, c, d)
+
+			for _, index := range face.TriangleIndices {
+				s.quadrics[index] = s.quadrics[index].add(q)
+			}
+		}
+
+		for i, index := range face.TriangleIndices {
+			s.vertexFaces[index] = append(s.vertexFaces[index], f)
+			edgeUse[edgeKey(index, face.TriangleIndices[(i+1)%3])]++
+		}
+	}
+
+	for edge, uses := range edgeUse {
+		if uses == 1 {
+			s.boundary[edge[0]] = true
+			s.boundary[edge[1]] = true
+		}
+	}
+
+	for edge := range edgeUse {
+		s.queueCollapse(edge[0], edge[1])
+	}
+
+	return s
+}
+
+func edgeKey(a, b int32) [2]int32 {
+	if a > b {
+		a, b = b, a
+	}
+
+	return [2]int32{a, b}
+}
+
+// works out the best way to collapse the edge between a and b, if it can be collapsed at all, and queues it.
+func (s *simplifier) queueCollapse(a, b int32) {
+	if s.boundary[a] && s.boundary[b] {
+		return
+	}
+
+	// boundary vertices stay where they are, so the other vertex has to merge into them.
+	if s.boundary[b] {
+		a, b = b, a
+	}
+
+	pa := s.vertices[a].Position
+	pb := s.vertices[b].Position
+	q := s.quadrics[a].add(s.quadrics[b])
+
+	best := collapse{a: a, b: b, versionA: s.versions[a], versionB: s.versions[b], position: pa, t: 0}
+	best.cost = q.error(float64(pa.X), float64(pa.Y), float64(pa.Z))
+
+	if !s.boundary[a] {
+		candidates := []float32{1, 0.5}
+		positions := []Vector3{pb, lerp3(pa, pb, 0.5)}
+
+		if x, y, z, ok := q.optimal(); ok {
+			optimal := Vector3{X: float32(x), Y: float32(y), Z: float32(z)}
+
+			candidates = append(candidates, edgeParameter(pa, pb, optimal))
+			positions = append(positions, optimal)
+		}
+
+		for i, p := range positions {
+			cost := q.error(float64(p.X), float64(p.Y), float64(p.Z))
+
+			if cost < best.cost {
+				best.cost = cost
+				best.position = p
+				best.t = candidates[i]
+			}
+		}
+	}
+
+	heap.Push(&s.queue, best)
+}
+
+// reports whether moving vertex v to position would flip any of its faces that don't also use other.  Faces that use
+// both vertices are removed by the collapse, so they don't matter.
+func (s *simplifier) flips(v, other int32, position Vector3) bool {
+	for _, f := range s.vertexFaces[v] {
+		if s.faceRemoved[f] {
+			continue
+		}
+
+		face := s.faces[f]
+		corners := [3]Vector3{}
+		usesOther := false
+
+		for i, index := range face.TriangleIndices {
+			corners[i] = s.vertices[index].Position
+
+			if index == v {
+				corners[i] = position
+			}
+
+			if index == other {
+				usesOther = true
+			}
+		}
+
+		if usesOther {
+			continue
+		}
+
+		before := s.faceNormalOf(face)
+		after := cross(sub(corners[1], corners[0]), sub(corners[2], corners[0]))
+
+		if dot(before, after) <= 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *simplifier) faceNormalOf(face Triangle) Vector3 {
+	a := s.vertices[face.TriangleIndices[0]].Position
+	b := s.vertices[face.TriangleIndices[1]].Position
+	c := s.vertices[face.TriangleIndices[2]].Position
+
+	return cross(sub(b, a), sub(c, a))
+}
+
+// merges e.b into e.a.
+func (s *simplifier) collapse(e collapse) {
+	a, b := e.a, e.b
+
+	s.vertices[a] = lerpVertex(s.vertices[a], s.vertices[b], e.t)
+	s.vertices[a].Position = e.position
+	s.quadrics[a] = s.quadrics[a].add(s.quadrics[b])
+	s.removed[b] = true
+	s.versions[a]++
+
+	for _, f := range s.vertexFaces[b] {
+		if s.faceRemoved[f] {
+			continue
+		}
+
+		usesA := false
+
+		for i, index := range s.faces[f].TriangleIndices {
+			if index == a {
+				usesA = true
+			}
+
+			if index == b {
+				s.faces[f].TriangleIndices[i] = a
+			}
+		}
+
+		if usesA {
+			// this face had the collapsed edge in it, and is now degenerate.
+			s.faceRemoved[f] = true
+			s.faceCount--
+		} else {
+			s.vertexFaces[a] = append(s.vertexFaces[a], f)
+		}
+	}
+
+	s.vertexFaces[b] = nil
+
+	// the faces list of a might now hold removed faces; drop them while queueing the new edges.
+	live := s.vertexFaces[a][:0]
+	neighbors := make(map[int32]bool)
+
+	for _, f := range s.vertexFaces[a] {
+		if s.faceRemoved[f] {
+			continue
+		}
+
+		live = append(live, f)
+
+		for _, index := range s.faces[f].TriangleIndices {
+			if index != a {
+				neighbors[index] = true
+			}
+		}
+	}
+
+	s.vertexFaces[a] = live
+
+	for n := range neighbors {
+		s.queueCollapse(a, n)
+	}
+}
+
+// builds the simplified Geometry, without the vertices and faces that were removed.
+func (s *simplifier) result() Geometry {
+	out := Geometry{Material: s.material}
+	newIndex := make([]int32, len(s.vertices))
+
+	for i, v := range s.vertices {
+		if s.removed[i] {
+			continue
+		}
+
+		newIndex[i] = int32(len(out.Vertices))
+		out.Vertices = append(out.Vertices, v)
+	}
+
+	for f, face := range s.faces {
+		if s.faceRemoved[f] {
+			continue
+		}
+
+		for i, index := range face.TriangleIndices {
+			face.TriangleIndices[i] = newIndex[index]
+		}
+
+		out.Faces = append(out.Faces, face)
+	}
+
+	return out
+}
+
+// returns where p falls along the line from a to b, clamped to [0,1]; 0 is a and 1 is b.
+func edgeParameter(a, b, p Vector3) float32 {
+	ab := sub(b, a)
+	length := dot(ab, ab)
+
+	if length == 0 {
+		return 0
+	}
+
+	t := dot(sub(p, a), ab) / length
+
+	return float32(math.Max(0, math.Min(1, float64(t))))
+}
+
+func lerp3(a, b Vector3, t float32) Vector3 {
+	return Vector3{X: a.X + (b.X-a.X)*t, Y: a.Y + (b.Y-a.Y)*t, Z: a.Z + (b.Z-a.Z)*t}
+}
+
+// interpolates every attribute of two vertices.  The normal and tangent are renormalized afterwards.
+func lerpVertex(a, b Vertex, t float32) Vertex {
+	v := a
+
+	v.Position = lerp3(a.Position, b.Position, t)
+	v.Normal = normalize(lerp3(a.Normal, b.Normal, t))
+	v.Velocity = lerp3(a.Velocity, b.Velocity, t)
+
+	// the handedness can't be interpolated, so a's is kept, as are its joints and weights.
+	tangent := normalize(lerp3(Vector3{X: a.Tangent.R, Y: a.Tangent.G, Z: a.Tangent.B}, Vector3{X: b.Tangent.R, Y: b.Tangent.G, Z: b.Tangent.B}, t))
+	v.Tangent = Vector4{R: tangent.X, G: tangent.Y, B: tangent.Z, A: a.Tangent.A}
+	v.UV = Vector2{U: a.UV.U + (b.UV.U-a.UV.U)*t, V: a.UV.V + (b.UV.V-a.UV.V)*t}
+	v.UV2 = Vector2{U: a.UV2.U + (b.UV2.U-a.UV2.U)*t, V: a.UV2.V + (b.UV2.V-a.UV2.V)*t}
+	v.Color = Vector4{
+		R: a.Color.R + (b.Color.R-a.Color.R)*t,
+		G: a.Color.G + (b.Color.G-a.Color.G)*t,
+		B: a.Color.B + (b.Color.B-a.Color.B)*t,
+		A: a.Color.A + (b.Color.A-a.Color.A)*t,
+	}
+
+	// every vertex of a Geometry has the same number of morph targets, so their offsets pair up.
+	if len(a.Morphs) > 0 && len(a.Morphs) == len(b.Morphs) {
+		v.Morphs = make([]MorphDelta, len(a.Morphs))
+
+		for i := range a.Morphs {
+			v.Morphs[i] = MorphDelta{
+				Position: lerp3(a.Morphs[i].Position, b.Morphs[i].Position, t),
+				Normal:   lerp3(a.Morphs[i].Normal, b.Morphs[i].Normal, t),
+				Tangent:  lerp3(a.Morphs[i].Tangent, b.Morphs[i].Tangent, t),
+			}
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,266 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// A skinned mesh is deformed by a set of joints, which are ordinary nodes.  Each vertex names up to four of the skin's
+// joints, by their position in Skin.Joints, in Vertex.Joints, and how much each one moves it in Vertex.Weights.  The
+// inverse bind matrices take a vertex from the mesh's space into each joint's space as it was when the mesh was bound.
+
+// Skin is a glTF skin.  InverseBindMatrices is the MAT4 accessor holding one matrix per joint; nil means every matrix
+// is the identity.  Skeleton, when set, is the node that's the common root of the joints.
+type Skin struct {
+	Extensions          interface{} `json:"extensions,omitempty"`
+	Extras              interface{} `json:"extras,omitempty"`
+	InverseBindMatrices *int        `json:"inverseBindMatrices,omitempty"`
+	Joints              []int       `json:"joints"`
+	Name                string      `json:"name,omitempty"`
+	Skeleton            *int        `json:"skeleton,omitempty"`
+}
+
+// ModelSkin skins a Geometry of a hierarchical Model.  Joints are indices into Model.Meshes, which become the joint
+// nodes, and InverseBindMatrices, if any, are column-major, one per joint.  Skeleton is the index of the Geometry at the
+// root of the joints, if there's one worth naming.
+type ModelSkin struct {
+	Joints              []int         `json:"joints"`
+	InverseBindMatrices [][16]float32 `json:"inverseBindMatrices,omitempty"`
+	Skeleton            *int          `json:"skeleton,omitempty"`
+}
+
+// the most a vertex's weights can be off from summing to 1 before checkGeometry complains.  It's loose enough for
+// weights that were quantized somewhere along the way.
+const weightTolerance = 2e-3
+
+// AddSkin adds a skin with the supplied joints and inverse bind matrices to the document, and attaches it to the node at
+// nodeIndex, whose mesh has to have JOINTS_0 and WEIGHTS_0 on every primitive.  inverseBindMatrices are column-major,
+// and either one per joint or none, which leaves them all as the identity.  skeleton may be nil.  The new skin's index
+// is returned.
+func (gltfDoc *GlTF) AddSkin(joints []int, inverseBindMatrices [][16]float32, skeleton *int, nodeIndex int) (skinIndex int, err error) {
+	if nodeIndex < 0 || nodeIndex >= len(gltfDoc.Nodes) {
+		return -1, fmt.Errorf("node %d does not exist", nodeIndex)
+	}
+
+	if err := gltfDoc.checkSkinnedNode(gltfDoc.Nodes[nodeIndex]); err != nil {
+		return -1, fmt.Errorf("node %d: %w", nodeIndex, err)
+	}
+
+	skin := Skin{Joints: append([]int(nil), joints...), Skeleton: skeleton}
+
+	if err := checkSkin(skin, len(inverseBindMatrices), len(gltfDoc.Nodes)); err != nil {
+		return -1, err
+	}
+
+	if len(inverseBindMatrices) > 0 {
+		outBuf := gltfDoc.beginAppend()
+		accessorIndex := getAccessorIndexFromMatrices(outBuf, inverseBindMatrices, &gltfDoc.BufferViews, &gltfDoc.Accessors)
+		gltfDoc.endAppend(outBuf)
+
+		skin.InverseBindMatrices = &accessorIndex
+	}
+
+	gltfDoc.Skins = append(gltfDoc.Skins, skin)
+	gltfDoc.Nodes[nodeIndex].Skin = len(gltfDoc.Skins) - 1
+
+	return len(gltfDoc.Skins) - 1, nil
+}
+
+// makes sure a skin has at least one joint, that its joints and skeleton are nodes that exist, and that it has a matrix
+// for each joint if it has any.
+func checkSkin(skin Skin, matrixCount int, nodeCount int) error {
+	if len(skin.Joints) == 0 {
+		return errors.New("skin has no joints")
+	}
+
+	seen := make(map[int]bool)
+
+	for _, joint := range skin.Joints {
+		if joint < 0 || joint >= nodeCount {
+			return fmt.Errorf("joint %d does not exist", joint)
+		}
+
+		if seen[joint] {
+			return fmt.Errorf("joint %d is listed twice", joint)
+		}
+
+		seen[joint] = true
+	}
+
+	if skin.Skeleton != nil && (*skin.Skeleton < 0 || *skin.Skeleton >= nodeCount) {
+		return fmt.Errorf("skeleton %d does not exist", *skin.Skeleton)
+	}
+
+	if matrixCount != 0 && matrixCount != len(skin.Joints) {
+		return fmt.Errorf("skin has %d joints but %d inverse bind matrices", len(skin.Joints), matrixCount)
+	}
+
+	return nil
+}
+
+// makes sure a node that's going to be skinned has a mesh, and that every primitive of the mesh says which joints move
+// each vertex and by how much.
+func (gltfDoc GlTF) checkSkinnedNode(node Node) error {
+	meshIndex := jsonNumber(node.Mesh)
+
+	if meshIndex < 0 || meshIndex >= len(gltfDoc.Meshes) {
+		return errors.New("a skinned node needs a mesh")
+	}
+
+	for p, primitive := range gltfDoc.Meshes[meshIndex].Primitives {
+		_, hasJoints := primitive.Attributes["JOINTS_0"]
+		_, hasWeights := primitive.Attributes["WEIGHTS_0"]
+
+		if !hasJoints || !hasWeights {
+			return fmt.Errorf("primitive %d of mesh %d has no JOINTS_0 or WEIGHTS_0, which a skinned mesh needs", p, meshIndex)
+		}
+	}
+
+	return nil
+}
+
+// makes sure that, if any vertex of the Geometry is weighted, every vertex's weights sum to 1 and only refer to joints
+// in the supplied skin, which may be nil.
+func checkWeights(geo Geometry, skin *ModelSkin) error {
+	if !hasWeights(geo) {
+		return nil
+	}
+
+	for i, v := range geo.Vertices {
+		sum := float64(0)
+
+		for w, weight := range v.Weights {
+			if weight < 0 {
+				return fmt.Errorf("vertex %d has a negative weight, %g", i, weight)
+			}
+
+			if weight > 0 && skin != nil && int(v.Joints[w]) >= len(skin.Joints) {
+				return fmt.Errorf("vertex %d is weighted to joint %d, but the skin has %d joints", i, v.Joints[w], len(skin.Joints))
+			}
+
+			sum += float64(weight)
+		}
+
+		if math.Abs(sum-1) > weightTolerance {
+			return fmt.Errorf("vertex %d has weights that sum to %g; they need to sum to 1", i, sum)
+		}
+	}
+
+	return nil
+}
+
+// builds the glTF skins for the Geometry of a hierarchical Model that have one, adding their inverse bind matrices to
+// outBuf, and points each Geometry's node at its skin.  The nodes line up with model.Meshes.
+func hierarchySkins(model Model, nodes []Node, outBuf *bytes.Buffer, gltfBufferViews *[]BufferView, gltfAccessors *[]Accessor) []Skin {
+	skins := []Skin{}
+
+	for i, geo := range model.Meshes {
+		if geo.Skin == nil {
+			continue
+		}
+
+		skin := Skin{Joints: append([]int(nil), geo.Skin.Joints...)}
+
+		if geo.Skin.Skeleton != nil {
+			skeleton := *geo.Skin.Skeleton
+			skin.Skeleton = &skeleton
+		}
+
+		if len(geo.Skin.InverseBindMatrices) > 0 {
+			accessorIndex := getAccessorIndexFromMatrices(outBuf, geo.Skin.InverseBindMatrices, gltfBufferViews, gltfAccessors)
+			skin.InverseBindMatrices = &accessorIndex
+		}
+
+		skins = append(skins, skin)
+		nodes[i].Skin = len(skins) - 1
+	}
+
+	return skins
+}
+
+// Appends the supplied column-major matrices to the supplied bytes.Buffer, and adds a BufferView and a MAT4 Accessor
+// for them.
+func getAccessorIndexFromMatrices(outBuf *bytes.Buffer, matrices [][16]float32, gltfBufferViews *[]BufferView, gltfAccessors *[]Accessor) (accessorIndex int) {
+	data := make([]float32, 0, len(matrices)*16)
+
+	for _, m := range matrices {
+		data = append(data, m[:]...)
+	}
+
+	return getAccessorIndexFromFloats(outBuf, data, Mat4, gltfBufferViews, gltfAccessors)
+}
+
+// Appends the supplied joint indices to the supplied bytes.Buffer, and adds a BufferView and a VEC4 Accessor for them.
+// They're written as unsigned bytes if they all fit, and unsigned shorts otherwise.
+func getAccessorIndexFromJoints(outBuf *bytes.Buffer, joints [][4]uint16, gltfBufferViews *[]BufferView, gltfAccessors *[]Accessor) (accessorIndex int) {
+	padToAlignment(outBuf)
+	byteOffset := outBuf.Len()
+	componentType := UnsignedByte
+
+	for _, j := range joints {
+		for _, joint := range j {
+			if joint > math.MaxUint8 {
+				componentType = UnsignedShort
+			}
+		}
+	}
+
+	for _, j := range joints {
+		if componentType == UnsignedByte {
+			outBuf.Write([]byte{byte(j[0]), byte(j[1]), byte(j[2]), byte(j[3])})
+		} else {
+			binary.Write(outBuf, binary.LittleEndian, j)
+		}
+	}
+
+	*gltfBufferViews = append(*gltfBufferViews, BufferView{
+		Buffer:     0,
+		ByteOffset: byteOffset,
+		ByteLength: outBuf.Len() - byteOffset,
+		Target:     34962,
+	})
+
+	*gltfAccessors = append(*gltfAccessors, Accessor{
+		BufferView:    lastBufferView(*gltfBufferViews),
+		ByteOffset:    0,
+		ComponentType: componentType,
+		Count:         len(joints),
+		Type:          Vec4,
+	})
+
+	return len(*gltfAccessors) - 1
+}
+
+func getJoints(mesh Geometry) [][4]uint16 {
+	results := [][4]uint16{}
+
+	for _, m := range mesh.Vertices {
+		results = append(results, m.Joints)
+	}
+
+	return results
+}
+
+func getWeights(mesh Geometry) []float32 {
+	results := []float32{}
+
+	for _, m := range mesh.Vertices {
+		results = append(results, m.Weights[:]...)
+	}
+
+	return results
+}
+
+// reports whether any vertex in the mesh is weighted to a joint.
+func hasWeights(mesh Geometry) bool {
+	for _, m := range mesh.Vertices {
+		if m.Weights != ([4]float32{}) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,160 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// A sparse accessor stores only the elements that differ from its base: their indices, and their values.  The base is
+// the accessor's own buffer view, or all zeros if it has none, which suits morph targets that only move a few of a
+// mesh's vertices.  See https://registry.khronos.org/glTF/specs/2.0/glTF-2.0.html#sparse-accessors
+
+// morph target offsets are written as a sparse accessor when fewer than one in sparseMorphFraction of them aren't
+// zero.  Each element written costs its value plus an index, so below that it's well under half the size of the dense
+// accessor.
+const sparseMorphFraction = 4
+
+// Sparse ...
+type Sparse struct {
+	Count      int           `json:"count" validator:"gte=1"`
+	Indices    SparseIndices `json:"indices"`
+	Values     SparseValues  `json:"values"`
+	Extensions interface{}   `json:"extensions,omitempty"`
+	Extras     interface{}   `json:"extras,omitempty"`
+}
+
+// SparseIndices is where a sparse accessor's element indices are: Count strictly increasing integers, each an
+// UnsignedByte, UnsignedShort or UnsignedInt as ComponentType says.
+type SparseIndices struct {
+	BufferView    int           `json:"bufferView" validator:"gte=0"`
+	ByteOffset    int           `json:"byteOffset,omitempty" validator:"gte=0"`
+	ComponentType ComponentType `json:"componentType"`
+	Extensions    interface{}   `json:"extensions,omitempty"`
+	Extras        interface{}   `json:"extras,omitempty"`
+}
+
+// SparseValues is where a sparse accessor's element values are, tightly packed, of the accessor's own type.
+type SparseValues struct {
+	BufferView int         `json:"bufferView" validator:"gte=0"`
+	ByteOffset int         `json:"byteOffset,omitempty" validator:"gte=0"`
+	Extensions interface{} `json:"extensions,omitempty"`
+	Extras     interface{} `json:"extras,omitempty"`
+}
+
+// Appends the supplied morph target offsets to the supplied bytes.Buffer and adds an Accessor for them.  When fewer
+// than one in sparseMorphFraction of them aren't zero, the accessor is sparse: it has no buffer view of its own, only
+// the indices and values of the offsets that aren't zero, and none at all if every offset is.  Its min and max are of
+// every offset, zeros included, as the spec asks.
+func getAccessorIndexFromMorphOffsets(outBuf *bytes.Buffer, offsets []Vector3, gltfBufferViews *[]BufferView, gltfAccessors *[]Accessor) (accessorIndex int) {
+	moved := []int{}
+
+	for i, offset := range offsets {
+		if offset != (Vector3{}) {
+			moved = append(moved, i)
+		}
+	}
+
+	if len(moved)*sparseMorphFraction >= len(offsets) {
+		return getAccessorIndexFromVector3(outBuf, offsets, gltfBufferViews, gltfAccessors)
+	}
+
+	min, max := bounds(offsets)
+
+	accessor := Accessor{
+		ComponentType: Float,
+		Count:         len(offsets),
+		Type:          Vec3,
+		Max:           []float32{max.X, max.Y, max.Z},
+		Min:           []float32{min.X, min.Y, min.Z},
+	}
+
+	if len(moved) > 0 {
+		componentType := indexComponentType(uint32(len(offsets) - 1))
+
+		// neither view is vertex data a GPU reads as it is, so they have no target.
+		padToAlignment(outBuf)
+		byteOffset := outBuf.Len()
+
+		for _, i := range moved {
+			if componentType == UnsignedShort {
+				binary.Write(outBuf, binary.LittleEndian, uint16(i))
+			} else {
+				binary.Write(outBuf, binary.LittleEndian, uint32(i))
+			}
+		}
+
+		*gltfBufferViews = append(*gltfBufferViews, BufferView{Buffer: 0, ByteOffset: byteOffset, ByteLength: outBuf.Len() - byteOffset})
+		indicesView := len(*gltfBufferViews) - 1
+
+		padToAlignment(outBuf)
+		byteOffset = outBuf.Len()
+
+		for _, i := range moved {
+			binary.Write(outBuf, binary.LittleEndian, [3]float32{offsets[i].X, offsets[i].Y, offsets[i].Z})
+		}
+
+		*gltfBufferViews = append(*gltfBufferViews, BufferView{Buffer: 0, ByteOffset: byteOffset, ByteLength: outBuf.Len() - byteOffset})
+
+		accessor.Sparse = &Sparse{
+			Count:   len(moved),
+			Indices: SparseIndices{BufferView: indicesView, ComponentType: componentType},
+			Values:  SparseValues{BufferView: len(*gltfBufferViews) - 1},
+		}
+	}
+
+	*gltfAccessors = append(*gltfAccessors, accessor)
+
+	return len(*gltfAccessors) - 1
+}
+
+// replaces the elements of a FLOAT VEC3 accessor's base data that its Sparse overrides, reading the indices and values
+// from the document's buffers.  It returns false if they aren't all available, or an index is out of range.
+func (gltfDoc GlTF) applySparseVector3(sparse Sparse, data []Vector3) bool {
+	indexSize := sparse.Indices.ComponentType.Size()
+
+	switch sparse.Indices.ComponentType {
+	case UnsignedByte, UnsignedShort, UnsignedInt:
+	default:
+		return false
+	}
+
+	// the indices and values are tightly packed, whatever stride their views give.
+	indexBytes, _, ok := gltfDoc.viewBytes(sparse.Indices.BufferView, sparse.Indices.ByteOffset, sparse.Count, indexSize, indexSize)
+
+	if !ok {
+		return false
+	}
+
+	valueBytes, _, ok := gltfDoc.viewBytes(sparse.Values.BufferView, sparse.Values.ByteOffset, sparse.Count, 12, 12)
+
+	if !ok {
+		return false
+	}
+
+	for s := 0; s < sparse.Count; s++ {
+		var index int
+
+		switch sparse.Indices.ComponentType {
+		case UnsignedByte:
+			index = int(indexBytes[s])
+		case UnsignedShort:
+			index = int(binary.LittleEndian.Uint16(indexBytes[s*2:]))
+		default:
+			index = int(binary.LittleEndian.Uint32(indexBytes[s*4:]))
+		}
+
+		if index >= len(data) {
+			return false
+		}
+
+		offset := s * 12
+		data[index] = Vector3{
+			X: math.Float32frombits(binary.LittleEndian.Uint32(valueBytes[offset:])),
+			Y: math.Float32frombits(binary.LittleEndian.Uint32(valueBytes[offset+4:])),
+			Z: math.Float32frombits(binary.LittleEndian.Uint32(valueBytes[offset+8:])),
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,84 @@
+package gltf
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// returns a strip of 98 triangles over 100 vertices, whose one morph target moves vertices 10 and 57.
+func sparselyMorphedStrip() Geometry {
+	geo := Geometry{Material: Material{Opacity: 1}, MorphWeights: []float64{1}}
+
+	for i := 0; i < 100; i++ {
+		geo.Vertices = append(geo.Vertices, Vertex{
+			Position: Vector3{X: float32(i / 2), Y: float32(i % 2)},
+			Normal:   Vector3{Z: 1},
+			Morphs:   []MorphDelta{{}},
+		})
+	}
+
+	for i := int32(0); i < 98; i++ {
+		geo.Faces = append(geo.Faces, Triangle{TriangleIndices: [3]int32{i, i + 1, i + 2}})
+	}
+
+	geo.Vertices[10].Morphs[0].Position = Vector3{Z: 1}
+	geo.Vertices[57].Morphs[0].Position = Vector3{Z: -2}
+
+	return geo
+}
+
+func TestSparseMorphOffsets(t *testing.T) {
+	geo := sparselyMorphedStrip()
+	offsets := make([]Vector3, len(geo.Vertices))
+
+	for i, v := range geo.Vertices {
+		offsets[i] = v.Morphs[0].Position
+	}
+
+	outBuf := new(bytes.Buffer)
+	gltfDoc := GlTF{}
+	index := getAccessorIndexFromMorphOffsets(outBuf, offsets, &gltfDoc.BufferViews, &gltfDoc.Accessors)
+	gltfDoc.Buffers = []GltfBuffer{{ByteLength: outBuf.Len(), Bytes: outBuf.Bytes()}}
+	accessor := gltfDoc.Accessors[index]
+
+	if accessor.BufferView != nil {
+		t.Errorf("the sparse accessor has buffer view %d, want none", *accessor.BufferView)
+	}
+
+	if accessor.Sparse == nil || accessor.Sparse.Count != 2 {
+		t.Fatalf("accessor has sparse %+v, want 2 elements", accessor.Sparse)
+	}
+
+	if accessor.Count != 100 {
+		t.Errorf("accessor has %d elements, want 100", accessor.Count)
+	}
+
+	// the bounds are of every offset, the zeros of the vertices that don't move included.
+	if !reflect.DeepEqual(accessor.Min, []float32{0, 0, -2}) || !reflect.DeepEqual(accessor.Max, []float32{0, 0, 1}) {
+		t.Errorf("accessor runs from %v to %v, want [0 0 -2] to [0 0 1]", accessor.Min, accessor.Max)
+	}
+
+	decoded, ok := gltfDoc.readVector3Floats(accessor)
+
+	if !ok {
+		t.Fatal("the sparse accessor couldn't be read back")
+	}
+
+	if !reflect.DeepEqual(decoded, offsets) {
+		t.Error("the sparse accessor doesn't decode to the offsets it was made from")
+	}
+}
+
+func TestSparseMorphTargetValidates(t *testing.T) {
+	gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{sparselyMorphedStrip()}}, PipelineOptions{Options: Options{VertexColors: true}})
+	position := gltfDoc.Accessors[gltfDoc.Meshes[0].Primitives[0].Targets[0]["POSITION"]]
+
+	if position.Sparse == nil || position.Sparse.Count != 2 {
+		t.Errorf("the morph target's POSITION has sparse %+v, want 2 elements", position.Sparse)
+	}
+
+	if err := gltfDoc.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// An STL file is a list of triangles, each with a facet normal and three corners, and nothing else: no shared
+// vertices, materials or texture coordinates.  The binary form is an 80 byte header, a little endian uint32 triangle
+// count, and a 50 byte record per triangle: the normal, the three corners, and a uint16 "attribute byte count" that's
+// meant to be 0.  The ASCII form spells the same thing out as solid, facet normal, outer loop, vertex, endloop,
+// endfacet and endsolid.
+
+const (
+	stlHeaderLen = 84
+	stlRecordLen = 50
+)
+
+// LoadSTL reads an ASCII or binary STL file from r, detecting which it is, and returns a Model holding one Geometry of
+// all its triangles, with the plain gray material and vertex color LoadOBJ gives faces without a material.  Facets
+// whose normal is missing or zero, as many exporters write it, get the normal of their winding instead, and those with
+// no area either are skipped.
+//
+// Every triangle has three vertices of its own, since STL has no way to share them.  OptimizeModel welds them back
+// together, or call WeldVertices yourself when converting the Model some other way.
+func LoadSTL(r io.Reader) (Model, error) {
+	data, err := io.ReadAll(r)
+
+	if err != nil {
+		return Model{}, err
+	}
+
+	var geo Geometry
+
+	// binary files may start with "solid" too, so one whose size matches its triangle count is taken as binary.
+	if isBinarySTL(data) || !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("solid")) {
+		geo, err = parseBinarySTL(data)
+	} else {
+		geo, err = parseASCIISTL(data)
+	}
+
+	if err != nil {
+		return Model{}, err
+	}
+
+	if len(geo.Faces) == 0 {
+		return Model{}, errors.New("stl file has no triangles")
+	}
+
+	return Model{Meshes: []Geometry{geo}}, nil
+}
+
+// reports whether data is exactly as long as a binary STL file with the triangle count in its header.
+func isBinarySTL(data []byte) bool {
+	if len(data) < stlHeaderLen {
+		return false
+	}
+
+	count := binary.LittleEndian.Uint32(data[80:stlHeaderLen])
+
+	return uint64(len(data)) == stlHeaderLen+uint64(count)*stlRecordLen
+}
+
+// reads the triangles of a binary STL file.  Any bytes after the last record are ignored.
+func parseBinarySTL(data []byte) (Geometry, error) {
+	if len(data) < stlHeaderLen {
+		return Geometry{}, fmt.Errorf("%d bytes is too short to be an STL file", len(data))
+	}
+
+	count := binary.LittleEndian.Uint32(data[80:stlHeaderLen])
+
+	if need := stlHeaderLen + uint64(count)*stlRecordLen; uint64(len(data)) < need {
+		return Geometry{}, fmt.Errorf("STL header gives %d triangles, which need %d bytes, but there are only %d", count, need, len(data))
+	}
+
+	geo := Geometry{Material: defaultImportedMaterial}
+	floats := make([]float32, 12)
+
+	for t := 0; t < int(count); t++ {
+		record := data[stlHeaderLen+t*stlRecordLen:]
+
+		for i := range floats {
+			floats[i] = math.Float32frombits(binary.LittleEndian.Uint32(record[4*i:]))
+		}
+
+		// the attribute byte count after the floats is skipped; the few exporters that use it disagree about how.
+		addSTLFacet(&geo, Vector3{X: floats[0], Y: floats[1], Z: floats[2]}, [3]Vector3{
+			{X: floats[3], Y: floats[4], Z: floats[5]},
+			{X: floats[6], Y: floats[7], Z: floats[8]},
+			{X: floats[9], Y: floats[10], Z: floats[11]},
+		})
+	}
+
+	return geo, nil
+}
+
+// reads the triangles of an ASCII STL file, from every solid in it.
+func parseASCIISTL(data []byte) (Geometry, error) {
+	geo := Geometry{Material: defaultImportedMaterial}
+
+	var normal Vector3
+	var corners []Vector3
+
+	for number, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "facet":
+			normal = Vector3{}
+			corners = corners[:0]
+
+			if len(fields) < 2 || fields[1] != "normal" {
+				break
+			}
+
+			values, err := parseFloats(fields[2:], 3, 3)
+
+			if err != nil {
+				return Geometry{}, fmt.Errorf("line %d: %w", number+1, err)
+			}
+
+			normal = Vector3{X: values[0], Y: values[1], Z: values[2]}
+		case "vertex":
+			values, err := parseFloats(fields[1:], 3, 3)
+
+			if err != nil {
+				return Geometry{}, fmt.Errorf("line %d: %w", number+1, err)
+			}
+
+			corners = append(corners, Vector3{X: values[0], Y: values[1], Z: values[2]})
+		case "endfacet":
+			if len(corners) != 3 {
+				return Geometry{}, fmt.Errorf("line %d: facet has %d vertices; it needs 3", number+1, len(corners))
+			}
+
+			addSTLFacet(&geo, normal, [3]Vector3{corners[0], corners[1], corners[2]})
+			corners = corners[:0]
+		case "solid", "outer", "endloop", "endsolid":
+		default:
+			return Geometry{}, fmt.Errorf("line %d: %q isn't an STL keyword", number+1, fields[0])
+		}
+	}
+
+	return geo, nil
+}
+
+// adds a triangle to the Geometry with three new vertices, all with the facet's normal, or that of the winding if the
+// facet's is zero.  A facet with neither, a zero normal and corners all in a line, is skipped: it has no area to draw
+// and no normal to give its vertices.
+func addSTLFacet(geo *Geometry, normal Vector3, corners [3]Vector3) {
+	if normal == (Vector3{}) {
+		normal = cross(sub(corners[1], corners[0]), sub(corners[2], corners[0]))
+	}
+
+	if normal == (Vector3{}) {
+		return
+	}
+
+	normal = normalize(normal)
+	material := geo.Material
+	color := Vector4{R: material.DiffuseColor[0], G: material.DiffuseColor[1], B: material.DiffuseColor[2], A: material.Opacity}
+	first := int32(len(geo.Vertices))
+
+	for _, p := range corners {
+		geo.Vertices = append(geo.Vertices, Vertex{Position: p, Normal: normal, Color: color})
+	}
+
+	geo.Faces = append(geo.Faces, Triangle{TriangleIndices: [3]int32{first, first + 1, first + 2}})
+}
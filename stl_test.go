@@ -0,0 +1,113 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+// returns a binary STL file of the unit cube, two triangles a side, with every facet normal left zero.
+func binarySTLCube() []byte {
+	corner := func(i int) Vector3 {
+		return Vector3{X: float32(i & 1), Y: float32(i >> 1 & 1), Z: float32(i >> 2 & 1)}
+	}
+
+	// each side's four corners, wound counter-clockwise seen from outside.
+	sides := [6][4]int{{0, 2, 3, 1}, {4, 5, 7, 6}, {0, 1, 5, 4}, {2, 6, 7, 3}, {0, 4, 6, 2}, {1, 3, 7, 5}}
+	data := make([]byte, stlHeaderLen, stlHeaderLen+12*stlRecordLen)
+	binary.LittleEndian.PutUint32(data[80:], 12)
+
+	for _, side := range sides {
+		for _, triangle := range [2][3]int{{side[0], side[1], side[2]}, {side[0], side[2], side[3]}} {
+			record := make([]byte, stlRecordLen)
+
+			for i, index := range triangle {
+				p := corner(index)
+
+				for j, f := range []float32{p.X, p.Y, p.Z} {
+					binary.LittleEndian.PutUint32(record[12+12*i+4*j:], math.Float32bits(f))
+				}
+			}
+
+			data = append(data, record...)
+		}
+	}
+
+	return data
+}
+
+func TestLoadSTLBinaryCube(t *testing.T) {
+	model, err := LoadSTL(bytes.NewReader(binarySTLCube()))
+
+	if err != nil {
+		t.Fatalf("LoadSTL: %v", err)
+	}
+
+	geo := model.Meshes[0]
+
+	if len(geo.Faces) != 12 || len(geo.Vertices) != 36 {
+		t.Fatalf("got %d triangles and %d vertices, want 12 and 36", len(geo.Faces), len(geo.Vertices))
+	}
+
+	center := Vector3{X: 0.5, Y: 0.5, Z: 0.5}
+
+	for _, face := range geo.Faces {
+		v := geo.Vertices[face.TriangleIndices[0]]
+
+		// the winding's normal of a side of the cube points away from its center.
+		if dot(v.Normal, sub(v.Position, center)) <= 0 {
+			t.Errorf("vertex at %v has normal %v, which points into the cube", v.Position, v.Normal)
+		}
+	}
+
+	checkUnitNormals(t, model)
+}
+
+func TestLoadSTLDegenerateFacet(t *testing.T) {
+	const stl = `solid test
+facet normal 0 0 0
+  outer loop
+    vertex 0 0 0
+    vertex 1 0 0
+    vertex 0 1 0
+  endloop
+endfacet
+facet normal 0 0 0
+  outer loop
+    vertex 0 0 0
+    vertex 1 1 1
+    vertex 2 2 2
+  endloop
+endfacet
+endsolid test
+`
+
+	model, err := LoadSTL(strings.NewReader(stl))
+
+	if err != nil {
+		t.Fatalf("LoadSTL: %v", err)
+	}
+
+	if faces := len(model.Meshes[0].Faces); faces != 1 {
+		t.Errorf("got %d triangles, want 1: the facet with no area should be skipped", faces)
+	}
+
+	checkUnitNormals(t, model)
+
+	const onlyDegenerate = `solid test
+facet normal 0 0 0
+  outer loop
+    vertex 0 0 0
+    vertex 1 1 1
+    vertex 2 2 2
+  endloop
+endfacet
+endsolid test
+`
+
+	if _, err := LoadSTL(strings.NewReader(onlyDegenerate)); err == nil {
+		t.Error("an STL file whose only facet has no area was accepted")
+	}
+}
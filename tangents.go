@@ -0,0 +1,136 @@
+package gltf
+
+import (
+	"errors"
+	"math"
+)
+
+// GenerateTangents replaces the tangents of the Geometry's vertices with ones worked out from their positions, normals
+// and UVs, for normal mapping.  Each triangle contributes the directions in which U and V increase across it to its
+// vertices, which then get the U direction made perpendicular to their normal as the tangent, and 1 or -1 as its A to
+// say whether the cross product of the normal and tangent points the way V decreases, as glTF's TANGENT wants: its UV
+// origin is the top left, so that's up the normal map.  This is the same per-triangle accumulation MikkTSpace starts
+// from, without its splitting of vertices at mirrored UVs.
+//
+// The normals should be final first; see GenerateNormals.  It returns an error, leaving the Geometry alone, if none of
+// the vertices have a UV or the Geometry isn't ModeTriangles.
+func (g *Geometry) GenerateTangents() error {
+	return g.generateTangents(func(v Vertex) Vector2 { return v.UV })
+}
+
+// does the work of GenerateTangents, with the UVs given by uv, so the atlas' normal maps can use Vertex.UV2.
+func (g *Geometry) generateTangents(uv func(Vertex) Vector2) error {
+	if g.Mode != ModeTriangles {
+		return errors.New("tangents can only be generated for ModeTriangles geometry")
+	}
+
+	if !hasUVs(*g, uv) {
+		return errors.New("tangents can't be generated for geometry without UVs")
+	}
+
+	uDirections := make([]Vector3, len(g.Vertices))
+	vDirections := make([]Vector3, len(g.Vertices))
+
+	for _, f := range g.Faces {
+		a, b, c := g.Vertices[f.TriangleIndices[0]], g.Vertices[f.TriangleIndices[1]], g.Vertices[f.TriangleIndices[2]]
+		edge1, edge2 := sub(b.Position, a.Position), sub(c.Position, a.Position)
+		uv0, uv1, uv2 := uv(a), uv(b), uv(c)
+		du1, dv1 := uv1.U-uv0.U, uv1.V-uv0.V
+		du2, dv2 := uv2.U-uv0.U, uv2.V-uv0.V
+
+		r := du1*dv2 - du2*dv1
+
+		// the UVs don't span an area, so there's no direction to take from them.
+		if r == 0 {
+			continue
+		}
+
+		uDirection := mul(sub(mul(edge1, dv2), mul(edge2, dv1)), 1/r)
+		vDirection := mul(sub(mul(edge2, du1), mul(edge1, du2)), 1/r)
+
+		for _, i := range f.TriangleIndices {
+			uDirections[i] = add(uDirections[i], uDirection)
+			vDirections[i] = add(vDirections[i], vDirection)
+		}
+	}
+
+	// a fresh slice, so a Geometry copied from this one keeps its own tangents.
+	vertices := make([]Vertex, len(g.Vertices))
+
+	for i, v := range g.Vertices {
+		n := normalize(v.Normal)
+		// Gram-Schmidt: take away the part of the U direction along the normal.
+		t := normalize(sub(uDirections[i], mul(n, dot(n, uDirections[i]))))
+
+		// a vertex whose triangles gave no usable direction still needs a unit tangent to be valid glTF.
+		if t == (Vector3{}) {
+			t = perpendicular(n)
+		}
+
+		handedness := float32(1)
+
+		if dot(cross(n, t), vDirections[i]) > 0 {
+			handedness = -1
+		}
+
+		v.Tangent = Vector4{R: t.X, G: t.Y, B: t.Z, A: handedness}
+		vertices[i] = v
+	}
+
+	g.Vertices = vertices
+
+	return nil
+}
+
+// reports whether any of the Geometry's vertices has a UV other than zero, as given by uv.
+func hasUVs(geo Geometry, uv func(Vertex) Vector2) bool {
+	for _, v := range geo.Vertices {
+		if uv(v) != (Vector2{}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// returns a unit vector perpendicular to the supplied one, or the X axis if it's zero.
+func perpendicular(n Vector3) Vector3 {
+	// crossing with an axis n is far from keeps the result well away from zero.
+	axis := Vector3{X: 1}
+
+	if math.Abs(float64(n.X)) > 0.9 {
+		axis = Vector3{Y: 1}
+	}
+
+	if p := normalize(cross(n, axis)); p != (Vector3{}) {
+		return p
+	}
+
+	return Vector3{X: 1}
+}
+
+// returns a copy of the Model where each Geometry with a normal map but no tangents has them generated from the UVs the
+// normal map is sampled with: Vertex.UV2 for the texture atlas, which remaps Vertex.UV, and Vertex.UV otherwise.
+// Geometry without those UVs is left as it is.  The Model passed in is left alone.
+func generateMissingTangents(model Model, atlas bool) Model {
+	uv := func(v Vertex) Vector2 { return v.UV }
+
+	if atlas {
+		uv = func(v Vertex) Vector2 { return v.UV2 }
+	}
+
+	meshes := make([]Geometry, len(model.Meshes))
+
+	for i, geo := range model.Meshes {
+		if !geo.isGroup() && geo.Material.NormalMapPath != "" && !hasTangents(geo) {
+			// an error only means there's nothing to generate them from.
+			_ = geo.generateTangents(uv)
+		}
+
+		meshes[i] = geo
+	}
+
+	model.Meshes = meshes
+
+	return model
+}
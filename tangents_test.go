@@ -0,0 +1,119 @@
+package gltf
+
+import "testing"
+
+// returns a unit quad in the XY plane facing +Z, with U increasing along +X and V along -Y, since glTF's UV origin is
+// the top left.
+func testQuad(material Material) Geometry {
+	normal := Vector3{Z: 1}
+
+	return Geometry{
+		Vertices: []Vertex{
+			{Position: Vector3{X: 0, Y: 0}, Normal: normal, UV: Vector2{U: 0, V: 1}},
+			{Position: Vector3{X: 1, Y: 0}, Normal: normal, UV: Vector2{U: 1, V: 1}},
+			{Position: Vector3{X: 1, Y: 1}, Normal: normal, UV: Vector2{U: 1, V: 0}},
+			{Position: Vector3{X: 0, Y: 1}, Normal: normal, UV: Vector2{U: 0, V: 0}},
+		},
+		Faces: []Triangle{
+			{TriangleIndices: [3]int32{0, 1, 2}},
+			{TriangleIndices: [3]int32{0, 2, 3}},
+		},
+		Material: material,
+	}
+}
+
+func TestGenerateTangentsQuad(t *testing.T) {
+	quad := testQuad(Material{})
+
+	if err := quad.GenerateTangents(); err != nil {
+		t.Fatalf("GenerateTangents: %v", err)
+	}
+
+	want := Vector4{R: 1, G: 0, B: 0, A: 1}
+
+	for i, v := range quad.Vertices {
+		if v.Tangent != want {
+			t.Errorf("vertex %d has tangent %v, want %v", i, v.Tangent, want)
+		}
+	}
+}
+
+func TestGenerateTangentsWithoutUVs(t *testing.T) {
+	quad := testQuad(Material{})
+
+	for i := range quad.Vertices {
+		quad.Vertices[i].UV = Vector2{}
+	}
+
+	if err := quad.GenerateTangents(); err == nil {
+		t.Error("tangents were generated for geometry without UVs")
+	}
+}
+
+func TestNormalMapGetsTangents(t *testing.T) {
+	mapped := Material{DiffuseColor: [3]float32{1, 1, 1}, Opacity: 1, NormalMapPath: "normal.png"}
+
+	// the atlas samples the normal map with UV2, so that's what its tangents have to follow.
+	atlasQuad := testQuad(mapped)
+
+	for i := range atlasQuad.Vertices {
+		atlasQuad.Vertices[i].UV2 = atlasQuad.Vertices[i].UV
+	}
+
+	for name, opts := range map[string]PipelineOptions{
+		"atlas":         {},
+		"vertex colors": {Options: Options{VertexColors: true}},
+	} {
+		quad := testQuad(mapped)
+
+		if !opts.VertexColors {
+			quad = atlasQuad
+		}
+
+		gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{quad}}, opts)
+		accessorIndex, ok := gltfDoc.Meshes[0].Primitives[0].Attributes["TANGENT"]
+
+		if !ok {
+			t.Errorf("%s: no TANGENT attribute was written", name)
+			continue
+		}
+
+		if accessor := gltfDoc.Accessors[accessorIndex]; accessor.Type != Vec4 || accessor.Count != 4 {
+			t.Errorf("%s: TANGENT accessor is %s with %d elements, want VEC4 with 4", name, accessor.Type, accessor.Count)
+		}
+	}
+}
+
+func TestTangentAttributeOnlyWhereGiven(t *testing.T) {
+	withTangents := testTriangle(Material{DiffuseColor: [3]float32{1, 0, 0}, Opacity: 1})
+
+	for i := range withTangents.Vertices {
+		withTangents.Vertices[i].Tangent = Vector4{R: 1, A: 1}
+	}
+
+	without := testTriangle(Material{DiffuseColor: [3]float32{0, 1, 0}, Opacity: 1})
+	gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{withTangents, without}}, PipelineOptions{Options: Options{VertexColors: true}})
+	var primitives []MeshPrimitive
+
+	for _, mesh := range gltfDoc.Meshes {
+		primitives = append(primitives, mesh.Primitives...)
+	}
+
+	if len(primitives) != 2 {
+		t.Fatalf("got %d primitives, want 2", len(primitives))
+	}
+
+	tangent, ok := primitives[0].Attributes["TANGENT"]
+
+	if !ok {
+		t.Fatal("the primitive with tangents has no TANGENT attribute")
+	}
+
+	if accessor := gltfDoc.Accessors[tangent]; accessor.Type != Vec4 || accessor.ComponentType != Float {
+		t.Errorf("TANGENT accessor is %s of %d, want %s of %d", accessor.Type, accessor.ComponentType, Vec4, Float)
+	}
+
+	if _, ok := primitives[1].Attributes["TANGENT"]; ok {
+		t.Error("the primitive without tangents has a TANGENT attribute")
+	}
+}
@@ -0,0 +1,68 @@
+package gltf
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"sync"
+)
+
+// TextureLoader decodes a single image from a document.  It is only called when an image is actually asked for.
+type TextureLoader func(img GltfImage) (image.Image, error)
+
+// ErrNoTextureLoader is returned by (*TextureCache).Image when the cache was created without a TextureLoader.  The
+// images are still available, undecoded, in the document.
+var ErrNoTextureLoader = errors.New("no texture loader supplied")
+
+// TextureCache decodes a document's images on demand and keeps the results, so each image is decoded at most once no
+// matter how many times, or from how many goroutines, it is asked for.
+type TextureCache struct {
+	doc     *GlTF
+	loader  TextureLoader
+	mutex   sync.Mutex
+	entries map[int]*textureCacheEntry
+}
+
+type textureCacheEntry struct {
+	once  sync.Once
+	image image.Image
+	err   error
+}
+
+// NewTextureCache returns a TextureCache for the images in the supplied document.  loader may be nil, in which case
+// the images are left as URIs and Image returns ErrNoTextureLoader.
+func NewTextureCache(doc *GlTF, loader TextureLoader) *TextureCache {
+	return &TextureCache{
+		doc:     doc,
+		loader:  loader,
+		entries: make(map[int]*textureCacheEntry),
+	}
+}
+
+// Image returns the decoded image at the supplied index in the document's Images, decoding it first if this is the
+// first time it has been asked for.  Loader errors are cached too, so a broken image isn't decoded over and over.
+func (c *TextureCache) Image(index int) (image.Image, error) {
+	if c.loader == nil {
+		return nil, ErrNoTextureLoader
+	}
+
+	if index < 0 || index >= len(c.doc.Images) {
+		return nil, fmt.Errorf("image %d does not exist; the document has %d images", index, len(c.doc.Images))
+	}
+
+	// only hold the lock long enough to find the entry, so that slow decodes of different images can run at once.
+	c.mutex.Lock()
+	entry, ok := c.entries[index]
+
+	if !ok {
+		entry = &textureCacheEntry{}
+		c.entries[index] = entry
+	}
+	c.mutex.Unlock()
+
+	entry.once.Do(func() {
+		entry.image, entry.err = c.loader(c.doc.Images[index])
+	})
+
+	return entry.image, entry.err
+}
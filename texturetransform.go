@@ -0,0 +1,69 @@
+package gltf
+
+// The KHR_texture_transform extension offsets, rotates and scales the texture coordinates a texture info samples with,
+// so a material can sample part of a shared texture without its vertices' UVs being rewritten.  The UVs are scaled
+// first, then rotated, then offset.
+// See https://github.com/KhronosGroup/glTF/tree/main/extensions/2.0/Khronos/KHR_texture_transform
+
+const textureTransformExtensionName = "KHR_texture_transform"
+
+// TextureTransform is the KHR_texture_transform object on a texture info.  Offset and Scale have two components, U and
+// V; leaving them out gives the spec defaults of [0, 0] and [1, 1].  Rotation is counterclockwise, in radians.
+// TexCoord, when set, overrides the texture info's own texCoord for viewers that support the extension.
+type TextureTransform struct {
+	Offset   []float64 `json:"offset,omitempty"`
+	Rotation float64   `json:"rotation,omitempty"`
+	Scale    []float64 `json:"scale,omitempty"`
+	TexCoord *int      `json:"texCoord,omitempty"`
+}
+
+// returns the transform that maps [0,1] onto the atlas tile at x, y that's w by h pixels, in an atlas that's width by
+// height pixels.
+func atlasTileTransform(x, y, w, h, width, height int) *TextureTransform {
+	return &TextureTransform{
+		Offset: []float64{float64(x) / float64(width), float64(y) / float64(height)},
+		Scale:  []float64{float64(w) / float64(width), float64(h) / float64(height)},
+	}
+}
+
+// returns the baseColorTexture for a material that samples the texture atlas, with the transform, if there is one, that
+// picks out the material's part of it.
+func atlasTextureInfo(transform *TextureTransform) interface{} {
+	if transform == nil {
+		return map[string]int{"index": 0}
+	}
+
+	return TextureInfo{
+		Index:      0,
+		Extensions: map[string]interface{}{textureTransformExtensionName: *transform},
+	}
+}
+
+// reports whether any of the material's texture infos made by this package carries a KHR_texture_transform.
+func (material GltfMaterial) hasTextureTransform() bool {
+	extensions := []interface{}{}
+
+	if info, ok := material.PbrMetallicRoughness.BaseColorTexture.(TextureInfo); ok {
+		extensions = append(extensions, info.Extensions)
+	}
+
+	if material.NormalTexture != nil {
+		extensions = append(extensions, material.NormalTexture.Extensions)
+	}
+
+	if material.OcclusionTexture != nil {
+		extensions = append(extensions, material.OcclusionTexture.Extensions)
+	}
+
+	if material.EmissiveTexture != nil {
+		extensions = append(extensions, material.EmissiveTexture.Extensions)
+	}
+
+	for _, e := range extensions {
+		if m, ok := e.(map[string]interface{}); ok && m[textureTransformExtensionName] != nil {
+			return true
+		}
+	}
+
+	return false
+}
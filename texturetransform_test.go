@@ -0,0 +1,41 @@
+package gltf
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAtlasTileTransform(t *testing.T) {
+	// a 16 pixel tile a quarter of the way into a 32 pixel atlas.
+	info := atlasTextureInfo(atlasTileTransform(8, 8, 16, 16, 32, 32))
+	data, err := json.Marshal(info)
+
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	if want := `{"extensions":{"KHR_texture_transform":{"offset":[0.25,0.25],"scale":[0.5,0.5]}},"index":0}`; string(data) != want {
+		t.Errorf("texture info marshals to %s, want %s", data, want)
+	}
+
+	gltfDoc := GlTF{Materials: []GltfMaterial{{PbrMetallicRoughness: MaterialPbrMetallicRoughness{BaseColorTexture: info}}}}
+	gltfDoc.useMaterialExtensions()
+
+	for name, list := range map[string][]string{"extensionsUsed": gltfDoc.ExtensionsUsed, "extensionsRequired": gltfDoc.ExtensionsRequired} {
+		if len(list) != 1 || list[0] != textureTransformExtensionName {
+			t.Errorf("%s is %v, want just %s", name, list, textureTransformExtensionName)
+		}
+	}
+}
+
+func TestAtlasTextureInfoWithoutTransform(t *testing.T) {
+	data, err := json.Marshal(atlasTextureInfo(nil))
+
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	if want := `{"index":0}`; string(data) != want {
+		t.Errorf("texture info marshals to %s, want %s", data, want)
+	}
+}
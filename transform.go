@@ -0,0 +1,170 @@
+package gltf
+
+import (
+	"fmt"
+	"math"
+)
+
+// Matrices here are 4x4 and column-major, the same layout glTF uses for Node.Matrix.
+
+var identityMatrix = [16]float64{
+	1, 0, 0, 0,
+	0, 1, 0, 0,
+	0, 0, 1, 0,
+	0, 0, 0, 1,
+}
+
+// WorldMatrix returns the transform from the node's local space to the scene's space: its local transform multiplied
+// by those of all of its ancestors.  glTF only stores children, so the parents are found by walking every node's
+// children first.  A node with more than one parent, or a hierarchy with a cycle in it, is an error.
+func (gltfDoc GlTF) WorldMatrix(nodeIndex int) ([16]float64, error) {
+	if nodeIndex < 0 || nodeIndex >= len(gltfDoc.Nodes) {
+		return identityMatrix, fmt.Errorf("node %d does not exist", nodeIndex)
+	}
+
+	parents, err := gltfDoc.parentMap()
+
+	if err != nil {
+		return identityMatrix, err
+	}
+
+	world := identityMatrix
+	visited := make(map[int]bool)
+
+	for node := nodeIndex; node >= 0; node = parents[node] {
+		if visited[node] {
+			return identityMatrix, fmt.Errorf("node %d is its own ancestor", node)
+		}
+
+		visited[node] = true
+		world = multiplyMatrices(gltfDoc.Nodes[node].LocalMatrix(), world)
+	}
+
+	return world, nil
+}
+
+// returns the index of each node's parent, or -1 for root nodes.
+func (gltfDoc GlTF) parentMap() ([]int, error) {
+	parents := make([]int, len(gltfDoc.Nodes))
+
+	for i := range parents {
+		parents[i] = -1
+	}
+
+	for p, node := range gltfDoc.Nodes {
+		for _, child := range node.Children {
+			if child < 0 || child >= len(gltfDoc.Nodes) {
+				return nil, fmt.Errorf("node %d has child %d, which does not exist", p, child)
+			}
+
+			if parents[child] != -1 {
+				return nil, fmt.Errorf("node %d has two parents, %d and %d", child, parents[child], p)
+			}
+
+			parents[child] = p
+		}
+	}
+
+	return parents, nil
+}
+
+// LocalMatrix returns the node's transform relative to its parent: either its Matrix, or its translation, rotation
+// and scale composed as T * R * S.  Missing properties are treated as their identity values.
+func (node Node) LocalMatrix() [16]float64 {
+	if len(node.Matrix) == 16 {
+		m := [16]float64{}
+		copy(m[:], node.Matrix)
+
+		return m
+	}
+
+	t := [3]float64{0, 0, 0}
+	r := [4]float64{0, 0, 0, 1}
+	s := [3]float64{1, 1, 1}
+
+	if len(node.Translation) == 3 {
+		copy(t[:], node.Translation)
+	}
+
+	if len(node.Rotation) == 4 {
+		copy(r[:], node.Rotation)
+	}
+
+	if len(node.Scale) == 3 {
+		copy(s[:], node.Scale)
+	}
+
+	return composeMatrix(t, r, s)
+}
+
+// builds T * R * S from a translation, a unit quaternion (x, y, z, w) and a scale.
+func composeMatrix(t [3]float64, r [4]float64, s [3]float64) [16]float64 {
+	x, y, z, w := r[0], r[1], r[2], r[3]
+
+	return [16]float64{
+		(1 - 2*(y*y+z*z)) * s[0], (2 * (x*y + z*w)) * s[0], (2 * (x*z - y*w)) * s[0], 0,
+		(2 * (x*y - z*w)) * s[1], (1 - 2*(x*x+z*z)) * s[1], (2 * (y*z + x*w)) * s[1], 0,
+		(2 * (x*z + y*w)) * s[2], (2 * (y*z - x*w)) * s[2], (1 - 2*(x*x+y*y)) * s[2], 0,
+		t[0], t[1], t[2], 1,
+	}
+}
+
+// returns a * b, for column-major matrices.
+func multiplyMatrices(a, b [16]float64) [16]float64 {
+	out := [16]float64{}
+
+	for col := 0; col < 4; col++ {
+		for row := 0; row < 4; row++ {
+			sum := 0.0
+
+			for k := 0; k < 4; k++ {
+				sum += a[k*4+row] * b[col*4+k]
+			}
+
+			out[col*4+row] = sum
+		}
+	}
+
+	return out
+}
+
+// DefaultRotationTolerance is how far from 1 a node rotation's length may be before NormalizeRotations treats it as a
+// bug rather than floating point noise.
+const DefaultRotationTolerance = 1e-6
+
+// NormalizeRotations rescales every node rotation to a unit quaternion, as the spec requires, as long as its length is
+// within tolerance of 1.  A rotation further off than that is almost certainly garbage rather than rounding error, so
+// it's reported as an error instead of being quietly fixed.  Rotations are only changed if every one of them passes.
+func (gltfDoc *GlTF) NormalizeRotations(tolerance float64) error {
+	for i, node := range gltfDoc.Nodes {
+		if len(node.Rotation) == 0 {
+			continue
+		}
+
+		if len(node.Rotation) != 4 {
+			return fmt.Errorf("node %d has a rotation with %d components; it needs 4", i, len(node.Rotation))
+		}
+
+		if length := quaternionLength(node.Rotation); math.Abs(length-1) > tolerance {
+			return fmt.Errorf("node %d has a rotation of length %g, which is too far from 1 to be rounding error", i, length)
+		}
+	}
+
+	for _, node := range gltfDoc.Nodes {
+		if len(node.Rotation) == 0 {
+			continue
+		}
+
+		length := quaternionLength(node.Rotation)
+
+		for c := range node.Rotation {
+			node.Rotation[c] /= length
+		}
+	}
+
+	return nil
+}
+
+func quaternionLength(q []float64) float64 {
+	return math.Sqrt(q[0]*q[0] + q[1]*q[1] + q[2]*q[2] + q[3]*q[3])
+}
@@ -0,0 +1,65 @@
+package gltf
+
+import "fmt"
+
+// The KHR_materials_unlit extension tells viewers to skip lighting for a material and show its base color as is, which
+// suits flat shaded and stylized models.  Viewers without the extension fall back to ordinary PBR shading.
+// See https://github.com/KhronosGroup/glTF/tree/main/extensions/2.0/Khronos/KHR_materials_unlit
+
+const unlitExtensionName = "KHR_materials_unlit"
+
+// the extension has no properties; being present on a material is all it says.
+type unlitExtension struct{}
+
+// SetUnlit marks the material at materialIndex as unlit and lists KHR_materials_unlit in ExtensionsUsed.  Materials
+// made from a Material with Unlit set already have this done for them.
+func (gltfDoc *GlTF) SetUnlit(materialIndex int) error {
+	if materialIndex < 0 || materialIndex >= len(gltfDoc.Materials) {
+		return fmt.Errorf("material %d does not exist", materialIndex)
+	}
+
+	material := &gltfDoc.Materials[materialIndex]
+
+	extensions, err := setExtension(material.Extensions, unlitExtensionName, unlitExtension{})
+
+	if err != nil {
+		return err
+	}
+
+	material.Extensions = extensions
+	gltfDoc.useExtension(unlitExtensionName)
+
+	return nil
+}
+
+// isUnlit reports whether a material carries the KHR_materials_unlit extension.
+func (material GltfMaterial) isUnlit() bool {
+	extensions, ok := material.Extensions.(map[string]interface{})
+
+	if !ok {
+		return false
+	}
+
+	_, found := extensions[unlitExtensionName]
+
+	return found
+}
+
+// lists the extensions used by the document's materials in ExtensionsUsed, for materials that had them set before they
+// were part of a document.
+func (gltfDoc *GlTF) useMaterialExtensions() {
+	for _, material := range gltfDoc.Materials {
+		if material.isUnlit() {
+			gltfDoc.useExtension(unlitExtensionName)
+		}
+
+		if material.hasEmissiveStrength() {
+			gltfDoc.useExtension(emissiveStrengthExtensionName)
+		}
+
+		// a transformed atlas shows the wrong part of the texture in viewers that don't apply the transform.
+		if material.hasTextureTransform() {
+			gltfDoc.requireExtension(textureTransformExtensionName)
+		}
+	}
+}
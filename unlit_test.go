@@ -0,0 +1,49 @@
+package gltf
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestUnlitExtension(t *testing.T) {
+	red := Material{DiffuseColor: [3]float32{1, 0, 0}, Opacity: 1, Unlit: true}
+	green := Material{DiffuseColor: [3]float32{0, 1, 0}, Opacity: 1, Unlit: true}
+	gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{testTriangle(red), testTriangle(green)}}, PipelineOptions{Options: Options{VertexColors: true}})
+
+	// setting it again, on an already unlit material, mustn't list the extension twice.
+	if err := gltfDoc.SetUnlit(0); err != nil {
+		t.Fatalf("SetUnlit: %v", err)
+	}
+
+	data, err := json.Marshal(gltfDoc)
+
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	var document struct {
+		ExtensionsUsed []string `json:"extensionsUsed"`
+		Materials      []struct {
+			Extensions map[string]json.RawMessage `json:"extensions"`
+		} `json:"materials"`
+	}
+
+	if err := json.Unmarshal(data, &document); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+
+	if used := strings.Join(document.ExtensionsUsed, ","); used != unlitExtensionName {
+		t.Errorf("extensionsUsed is %q, want %s exactly once", used, unlitExtensionName)
+	}
+
+	if len(document.Materials) != 2 {
+		t.Fatalf("got %d materials, want 2", len(document.Materials))
+	}
+
+	for i, material := range document.Materials {
+		if extension, ok := material.Extensions[unlitExtensionName]; !ok || string(extension) != "{}" {
+			t.Errorf("materials[%d] has extensions %v, want an empty %s", i, material.Extensions, unlitExtensionName)
+		}
+	}
+}
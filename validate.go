@@ -0,0 +1,766 @@
+package gltf
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ValidationError lists every constraint a document violates, one problem per entry, each naming the object and
+// field, such as "accessors[3].count: 0 is less than 1".
+type ValidationError []string
+
+func (v ValidationError) Error() string {
+	return fmt.Sprintf("%d validation problems:\n\t%s", len(v), strings.Join(v, "\n\t"))
+}
+
+// Validate checks every accessor, buffer, buffer view, camera, material and mesh primitive against the gte, lte and
+// multiple constraints in its struct's validator tags, and returns a ValidationError listing all the violations, or nil
+// if there are none.  Fields tagged omitempty that hold their zero value aren't checked, since they aren't written and
+// the spec default applies.  Cameras are also checked against the rules for their type, samplers against the
+// filters and wrap modes the spec allows, materials' texture infos against the TEXCOORD_n attributes of the primitives
+// that use them, and the extension lists for duplicates and for required extensions that extensionsUsed doesn't list,
+// as the spec forbids.
+func (gltfDoc *GlTF) Validate() error {
+	problems := ValidationError{}
+
+	check := func(name string, list interface{}) {
+		items := reflect.ValueOf(list)
+
+		for i := 0; i < items.Len(); i++ {
+			problems = append(problems, tagProblems(fmt.Sprintf("%s[%d]", name, i), items.Index(i))...)
+		}
+	}
+
+	check("accessors", gltfDoc.Accessors)
+	check("buffers", gltfDoc.Buffers)
+	check("bufferViews", gltfDoc.BufferViews)
+	check("cameras", gltfDoc.Cameras)
+	check("materials", gltfDoc.Materials)
+
+	for m, mesh := range gltfDoc.Meshes {
+		check(fmt.Sprintf("meshes[%d].primitives", m), mesh.Primitives)
+	}
+
+	// a camera's rules depend on its type, which the tags can't express.
+	for c, camera := range gltfDoc.Cameras {
+		if err := camera.check(); err != nil {
+			problems = append(problems, fmt.Sprintf("cameras[%d]: %v", c, err))
+		}
+	}
+
+	// nor can they express a sampler's lists of allowed values.
+	for s, sampler := range gltfDoc.Samplers {
+		if err := sampler.check(); err != nil {
+			problems = append(problems, fmt.Sprintf("samplers[%d]: %v", s, err))
+		}
+	}
+
+	// or a material's alpha modes.
+	for m, material := range gltfDoc.Materials {
+		if err := material.checkAlpha(); err != nil {
+			problems = append(problems, fmt.Sprintf("materials[%d]: %v", m, err))
+		}
+	}
+
+	// or which texture coordinates the primitives using a material need.
+	problems = append(problems, gltfDoc.texCoordProblems()...)
+
+	problems = append(problems, gltfDoc.extensionListProblems()...)
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return problems
+}
+
+// returns the duplicates in the document's extension lists, and the required extensions that aren't listed as used.
+func (gltfDoc *GlTF) extensionListProblems() []string {
+	problems := []string{}
+	used := make(map[string]bool)
+
+	for i, name := range gltfDoc.ExtensionsUsed {
+		if used[name] {
+			problems = append(problems, fmt.Sprintf("extensionsUsed[%d]: %s is listed more than once", i, name))
+		}
+
+		used[name] = true
+	}
+
+	required := make(map[string]bool)
+
+	for i, name := range gltfDoc.ExtensionsRequired {
+		if required[name] {
+			problems = append(problems, fmt.Sprintf("extensionsRequired[%d]: %s is listed more than once", i, name))
+		}
+
+		if !used[name] {
+			problems = append(problems, fmt.Sprintf("extensionsRequired[%d]: %s is required but not listed in extensionsUsed", i, name))
+		}
+
+		required[name] = true
+	}
+
+	return problems
+}
+
+// returns the violations of the validator tags on a struct's fields, and on the fields of any structs inside it.
+func tagProblems(path string, v reflect.Value) []string {
+	problems := []string{}
+
+	for f := 0; f < v.NumField(); f++ {
+		field := v.Type().Field(f)
+		value := v.Field(f)
+		jsonTag := strings.Split(field.Tag.Get("json"), ",")
+		fieldPath := path + "." + jsonTag[0]
+
+		if value.Kind() == reflect.Ptr && !value.IsNil() {
+			value = value.Elem()
+		}
+
+		if value.Kind() == reflect.Struct {
+			problems = append(problems, tagProblems(fieldPath, value)...)
+			continue
+		}
+
+		rules := field.Tag.Get("validator")
+
+		if rules == "" {
+			continue
+		}
+
+		if len(jsonTag) > 1 && jsonTag[1] == "omitempty" && value.IsZero() {
+			continue
+		}
+
+		var n float64
+
+		switch value.Kind() {
+		case reflect.Int:
+			n = float64(value.Int())
+		case reflect.Float64:
+			n = value.Float()
+		default:
+			continue
+		}
+
+		for _, rule := range strings.Split(rules, ",") {
+			parts := strings.SplitN(strings.TrimSpace(rule), "=", 2)
+			limit, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+
+			if len(parts) != 2 || err != nil {
+				continue
+			}
+
+			switch {
+			case parts[0] == "gte" && n < limit:
+				problems = append(problems, fmt.Sprintf("%s: %v is less than %v", fieldPath, n, limit))
+			case parts[0] == "lte" && n > limit:
+				problems = append(problems, fmt.Sprintf("%s: %v is greater than %v", fieldPath, n, limit))
+			case parts[0] == "multiple" && math.Mod(n, limit) != 0:
+				problems = append(problems, fmt.Sprintf("%s: %v is not a multiple of %v", fieldPath, n, limit))
+			}
+		}
+	}
+
+	return problems
+}
+
+// ValidationResult is the outcome of validating one glTF file.  Errors are problems that make the file invalid;
+// Warnings are the things (*GlTF).Repair would fix, which most validators also complain about.
+type ValidationResult struct {
+	Passed   bool
+	Errors   []string
+	Warnings []string
+}
+
+// ValidateFile loads and checks a single .gltf or .glb file.  The file's own buffers are used, so it's only the
+// structure of the document that's checked, and external buffer files aren't read.
+func ValidateFile(path string) *ValidationResult {
+	result := &ValidationResult{}
+
+	f, err := os.Open(path)
+
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	gltfDoc, err := LoadGltf(f)
+	f.Close()
+
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	result.Errors = gltfDoc.structureErrors()
+
+	if err := gltfDoc.Validate(); err != nil {
+		result.Errors = append(result.Errors, err.(ValidationError)...)
+	}
+
+	result.Passed = len(result.Errors) == 0
+
+	// Repair assumes the structure is sound, and its warnings wouldn't mean much for a file that's already invalid.
+	if !result.Passed {
+		return result
+	}
+
+	// the document was loaded just for this, so letting Repair change it costs nothing.
+	for _, action := range gltfDoc.Repair() {
+		result.Warnings = append(result.Warnings, action.Path+": "+action.Description)
+	}
+
+	return result
+}
+
+// calls ValidateFile, turning a panic into a failed result, so one malformed file can't take down a whole batch.
+func validateFileRecovering(path string) (result *ValidationResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = &ValidationResult{Errors: []string{fmt.Sprintf("validation panicked: %v", r)}}
+		}
+	}()
+
+	return ValidateFile(path)
+}
+
+// ValidateFiles validates each of the supplied files with ValidateFile, using at most concurrency goroutines at once,
+// and returns the results keyed by path.  Each file is loaded and checked independently, so a failure in one has no
+// effect on the others; even a panic while checking one only fails that file's result.  A concurrency below 1 is treated as 1.
+func ValidateFiles(paths []string, concurrency int) map[string]*ValidationResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	type fileResult struct {
+		path   string
+		result *ValidationResult
+	}
+
+	pending := make(chan string)
+	finished := make(chan fileResult)
+	workers := sync.WaitGroup{}
+
+	for w := 0; w < concurrency; w++ {
+		workers.Add(1)
+
+		go func() {
+			defer workers.Done()
+
+			for path := range pending {
+				finished <- fileResult{path: path, result: validateFileRecovering(path)}
+			}
+		}()
+	}
+
+	go func() {
+		seen := make(map[string]bool)
+
+		for _, path := range paths {
+			if !seen[path] {
+				seen[path] = true
+				pending <- path
+			}
+		}
+
+		close(pending)
+		workers.Wait()
+		close(finished)
+	}()
+
+	// only this goroutine touches the map, so no locking is needed.
+	results := make(map[string]*ValidationResult)
+
+	for r := range finished {
+		results[r.path] = r.result
+	}
+
+	return results
+}
+
+// ValidateReferences checks that everything the document refers to by index exists: the buffer of each buffer view,
+// the buffer views of each accessor, its sparse ones included, and of each image, the accessors, material and morph
+// targets of each mesh primitive, the children, mesh, camera and skin of each node, the nodes of each scene, the
+// accessors and nodes of each skin and animation, the source and sampler of each texture, the textures of each
+// material and the default scene.  It returns a ValidationError listing every dangling reference, each with where it
+// was found, such as "accessors[3]: bufferView 9 does not exist", or nil if there are none.  Unlike Validate it doesn't
+// look at the values themselves, so it's the check to make on a document that's been edited by hand.
+func (gltfDoc *GlTF) ValidateReferences() error {
+	if problems := gltfDoc.referenceProblems(); len(problems) > 0 {
+		return ValidationError(problems)
+	}
+
+	return nil
+}
+
+// returns a description of every reference in the document to something that doesn't exist.
+func (gltfDoc GlTF) referenceProblems() []string {
+	errs := []string{}
+
+	exists := func(index int, count int) bool {
+		return index >= 0 && index < count
+	}
+
+	for i, view := range gltfDoc.BufferViews {
+		if !exists(view.Buffer, len(gltfDoc.Buffers)) {
+			errs = append(errs, fmt.Sprintf("bufferViews[%d]: buffer %d does not exist", i, view.Buffer))
+		}
+	}
+
+	for i, accessor := range gltfDoc.Accessors {
+		// an accessor without a buffer view is all zeros, which is allowed.
+		if accessor.BufferView != nil && !exists(*accessor.BufferView, len(gltfDoc.BufferViews)) {
+			errs = append(errs, fmt.Sprintf("accessors[%d]: bufferView %d does not exist", i, *accessor.BufferView))
+		}
+
+		if sparse := accessor.Sparse; sparse != nil {
+			if !exists(sparse.Indices.BufferView, len(gltfDoc.BufferViews)) {
+				errs = append(errs, fmt.Sprintf("accessors[%d].sparse.indices: bufferView %d does not exist", i, sparse.Indices.BufferView))
+			}
+
+			if !exists(sparse.Values.BufferView, len(gltfDoc.BufferViews)) {
+				errs = append(errs, fmt.Sprintf("accessors[%d].sparse.values: bufferView %d does not exist", i, sparse.Values.BufferView))
+			}
+		}
+	}
+
+	for m, mesh := range gltfDoc.Meshes {
+		for p, primitive := range mesh.Primitives {
+			errs = append(errs, gltfDoc.primitiveReferenceProblems(fmt.Sprintf("meshes[%d].primitives[%d]", m, p), primitive)...)
+		}
+	}
+
+	for i, node := range gltfDoc.Nodes {
+		for _, child := range node.Children {
+			if !exists(child, len(gltfDoc.Nodes)) {
+				errs = append(errs, fmt.Sprintf("nodes[%d]: child %d does not exist", i, child))
+			}
+		}
+
+		if node.Mesh != nil && !exists(jsonNumber(node.Mesh), len(gltfDoc.Meshes)) {
+			errs = append(errs, fmt.Sprintf("nodes[%d]: mesh %v does not exist", i, node.Mesh))
+		}
+
+		if node.Camera != nil && !exists(jsonNumber(node.Camera), len(gltfDoc.Cameras)) {
+			errs = append(errs, fmt.Sprintf("nodes[%d]: camera %v does not exist", i, node.Camera))
+		}
+
+		if node.Skin != nil && !exists(jsonNumber(node.Skin), len(gltfDoc.Skins)) {
+			errs = append(errs, fmt.Sprintf("nodes[%d]: skin %v does not exist", i, node.Skin))
+		}
+	}
+
+	for i, scene := range gltfDoc.Scenes {
+		for _, node := range scene.Nodes {
+			if !exists(node, len(gltfDoc.Nodes)) {
+				errs = append(errs, fmt.Sprintf("scenes[%d]: node %d does not exist", i, node))
+			}
+		}
+	}
+
+	for i, skin := range gltfDoc.Skins {
+		if skin.InverseBindMatrices != nil && !exists(*skin.InverseBindMatrices, len(gltfDoc.Accessors)) {
+			errs = append(errs, fmt.Sprintf("skins[%d]: inverseBindMatrices accessor %d does not exist", i, *skin.InverseBindMatrices))
+		}
+
+		for _, joint := range skin.Joints {
+			if !exists(joint, len(gltfDoc.Nodes)) {
+				errs = append(errs, fmt.Sprintf("skins[%d]: joint %d does not exist", i, joint))
+			}
+		}
+
+		if skin.Skeleton != nil && !exists(*skin.Skeleton, len(gltfDoc.Nodes)) {
+			errs = append(errs, fmt.Sprintf("skins[%d]: skeleton %d does not exist", i, *skin.Skeleton))
+		}
+	}
+
+	for a, animation := range gltfDoc.Animations {
+		for c, channel := range animation.Channels {
+			if !exists(channel.Sampler, len(animation.Samplers)) {
+				errs = append(errs, fmt.Sprintf("animations[%d].channels[%d]: sampler %d does not exist", a, c, channel.Sampler))
+			}
+
+			if node := channel.Target.Node; node != nil && !exists(*node, len(gltfDoc.Nodes)) {
+				errs = append(errs, fmt.Sprintf("animations[%d].channels[%d]: node %d does not exist", a, c, *node))
+			}
+		}
+
+		for s, sampler := range animation.Samplers {
+			for _, accessor := range []int{sampler.Input, sampler.Output} {
+				if !exists(accessor, len(gltfDoc.Accessors)) {
+					errs = append(errs, fmt.Sprintf("animations[%d].samplers[%d]: accessor %d does not exist", a, s, accessor))
+				}
+			}
+		}
+	}
+
+	for i, texture := range gltfDoc.Textures {
+		if texture.Sampler != nil && !exists(*texture.Sampler, len(gltfDoc.Samplers)) {
+			errs = append(errs, fmt.Sprintf("textures[%d]: sampler %d does not exist", i, *texture.Sampler))
+		}
+
+		if texture.Source != nil && !exists(jsonNumber(texture.Source), len(gltfDoc.Images)) {
+			errs = append(errs, fmt.Sprintf("textures[%d]: source %v does not exist", i, texture.Source))
+		}
+	}
+
+	for i, img := range gltfDoc.Images {
+		if img.BufferView != nil && !exists(*img.BufferView, len(gltfDoc.BufferViews)) {
+			errs = append(errs, fmt.Sprintf("images[%d]: bufferView %d does not exist", i, *img.BufferView))
+		}
+	}
+
+	for i, material := range gltfDoc.Materials {
+		indices := material.textureIndices()
+
+		// in a fixed order, rather than the map's, so the same document always gives the same list.
+		for _, name := range materialTextureNames {
+			if index, ok := indices[name]; ok && !exists(index, len(gltfDoc.Textures)) {
+				errs = append(errs, fmt.Sprintf("materials[%d]: %s %d does not exist", i, name, index))
+			}
+		}
+	}
+
+	if _, ok := gltfDoc.defaultScene(); gltfDoc.Scene != nil && !ok {
+		errs = append(errs, fmt.Sprintf("scene: default scene %d does not exist", *gltfDoc.Scene))
+	}
+
+	return errs
+}
+
+// returns a description of every reference the mesh primitive makes to something that doesn't exist, each starting
+// with path.
+func (gltfDoc GlTF) primitiveReferenceProblems(path string, primitive MeshPrimitive) []string {
+	errs := []string{}
+
+	checkAttributes := func(path string, attributes Attributes) {
+		names := make([]string, 0, len(attributes))
+
+		for name := range attributes {
+			names = append(names, name)
+		}
+
+		// sorted, so the same document always gives the same list.
+		sort.Strings(names)
+
+		for _, name := range names {
+			if index := attributes[name]; index < 0 || index >= len(gltfDoc.Accessors) {
+				errs = append(errs, fmt.Sprintf("%s: %s accessor %d does not exist", path, name, index))
+			}
+		}
+	}
+
+	checkAttributes(path, primitive.Attributes)
+
+	for t, target := range primitive.Targets {
+		checkAttributes(fmt.Sprintf("%s.targets[%d]", path, t), target)
+	}
+
+	if primitive.Indices != nil && (*primitive.Indices < 0 || *primitive.Indices >= len(gltfDoc.Accessors)) {
+		errs = append(errs, fmt.Sprintf("%s: indices accessor %d does not exist", path, *primitive.Indices))
+	}
+
+	// a document without materials is decoded with every primitive's missing material as 0, which means the default.
+	if len(gltfDoc.Materials) > 0 && (primitive.Material < 0 || primitive.Material >= len(gltfDoc.Materials)) {
+		errs = append(errs, fmt.Sprintf("%s: material %d does not exist", path, primitive.Material))
+	}
+
+	return errs
+}
+
+// the properties of a material that refer to a texture, in the order their problems are reported.
+var materialTextureNames = []string{"baseColorTexture", "normalTexture", "occlusionTexture", "emissiveTexture"}
+
+// returns the indices of the textures the material samples, keyed by the property that refers to each.  A base color
+// texture is found whichever of the forms this package writes, or encoding/json decodes, it's stored in.
+func (material GltfMaterial) textureIndices() map[string]int {
+	indices := make(map[string]int)
+
+	switch info := material.PbrMetallicRoughness.BaseColorTexture.(type) {
+	case TextureInfo:
+		indices["baseColorTexture"] = info.Index
+	case *TextureInfo:
+		if info != nil {
+			indices["baseColorTexture"] = info.Index
+		}
+	case map[string]int:
+		indices["baseColorTexture"] = info["index"]
+	case map[string]interface{}:
+		indices["baseColorTexture"] = jsonNumber(info["index"])
+	}
+
+	if material.NormalTexture != nil {
+		indices["normalTexture"] = material.NormalTexture.Index
+	}
+
+	if material.OcclusionTexture != nil {
+		indices["occlusionTexture"] = material.OcclusionTexture.Index
+	}
+
+	if material.EmissiveTexture != nil {
+		indices["emissiveTexture"] = material.EmissiveTexture.Index
+	}
+
+	return indices
+}
+
+// returns the texture coordinate set each of the material's textures is sampled with, keyed like textureIndices.  A
+// KHR_texture_transform with a texCoord of its own overrides the texture info's, as the extension says.
+func (material GltfMaterial) texCoords() map[string]int {
+	texCoords := make(map[string]int)
+
+	set := func(name string, texCoord int, extensions interface{}) {
+		texCoords[name] = transformTexCoord(extensions, texCoord)
+	}
+
+	switch info := material.PbrMetallicRoughness.BaseColorTexture.(type) {
+	case TextureInfo:
+		set("baseColorTexture", info.TexCoord, info.Extensions)
+	case *TextureInfo:
+		if info != nil {
+			set("baseColorTexture", info.TexCoord, info.Extensions)
+		}
+	case map[string]int:
+		set("baseColorTexture", info["texCoord"], nil)
+	case map[string]interface{}:
+		// a texCoord that's left out is 0, which jsonNumber would make -1.
+		texCoord := 0
+
+		if _, ok := info["texCoord"]; ok {
+			texCoord = jsonNumber(info["texCoord"])
+		}
+
+		set("baseColorTexture", texCoord, info["extensions"])
+	}
+
+	if info := material.NormalTexture; info != nil {
+		set("normalTexture", info.TexCoord, info.Extensions)
+	}
+
+	if info := material.OcclusionTexture; info != nil {
+		set("occlusionTexture", info.TexCoord, info.Extensions)
+	}
+
+	if info := material.EmissiveTexture; info != nil {
+		set("emissiveTexture", info.TexCoord, info.Extensions)
+	}
+
+	return texCoords
+}
+
+// returns the texCoord of the KHR_texture_transform in a texture info's extensions, if it has one, or the texture
+// info's own texCoord otherwise.
+func transformTexCoord(extensions interface{}, texCoord int) int {
+	e, ok := extensions.(map[string]interface{})
+
+	if !ok {
+		return texCoord
+	}
+
+	switch transform := e[textureTransformExtensionName].(type) {
+	case TextureTransform:
+		if transform.TexCoord != nil {
+			return *transform.TexCoord
+		}
+	case *TextureTransform:
+		if transform != nil && transform.TexCoord != nil {
+			return *transform.TexCoord
+		}
+	case map[string]interface{}:
+		if _, ok := transform["texCoord"]; ok {
+			return jsonNumber(transform["texCoord"])
+		}
+	}
+
+	return texCoord
+}
+
+// returns a problem for every texture coordinate set a material samples that a mesh primitive using it doesn't have,
+// since viewers sample garbage rather than refusing to draw it.
+func (gltfDoc *GlTF) texCoordProblems() []string {
+	problems := []string{}
+
+	for m, material := range gltfDoc.Materials {
+		texCoords := material.texCoords()
+
+		if len(texCoords) == 0 {
+			continue
+		}
+
+		for meshIndex, mesh := range gltfDoc.Meshes {
+			for p, primitive := range mesh.Primitives {
+				if primitive.Material != m {
+					continue
+				}
+
+				for _, name := range materialTextureNames {
+					texCoord, ok := texCoords[name]
+
+					if !ok {
+						continue
+					}
+
+					if _, found := primitive.Attributes[fmt.Sprintf("TEXCOORD_%d", texCoord)]; !found || texCoord < 0 {
+						problems = append(problems, fmt.Sprintf("materials[%d].%s: samples TEXCOORD_%d, which meshes[%d].primitives[%d] doesn't have",
+							m, name, texCoord, meshIndex, p))
+					}
+				}
+			}
+		}
+	}
+
+	return problems
+}
+
+// returns a description of every structural problem in the document: the dangling references ValidateReferences
+// finds, misaligned accessors, mesh primitives whose attributes don't agree, and images that aren't stored the way the
+// spec allows.  Checks that would need a dangling reference are skipped, since it's already been reported.
+func (gltfDoc GlTF) structureErrors() []string {
+	errs := gltfDoc.referenceProblems()
+
+	for i, buffer := range gltfDoc.Buffers {
+		if buffer.Bytes != nil && len(buffer.Bytes) < buffer.ByteLength {
+			errs = append(errs, fmt.Sprintf("buffers[%d]: byteLength is %d but only %d bytes are present", i, buffer.ByteLength, len(buffer.Bytes)))
+		}
+	}
+
+	for i, view := range gltfDoc.BufferViews {
+		if view.Buffer < 0 || view.Buffer >= len(gltfDoc.Buffers) {
+			continue
+		}
+
+		if end := view.ByteOffset + view.ByteLength; end > gltfDoc.Buffers[view.Buffer].ByteLength {
+			errs = append(errs, fmt.Sprintf("bufferViews[%d]: ends at byte %d, past the end of buffer %d", i, end, view.Buffer))
+		}
+	}
+
+	for i, accessor := range gltfDoc.Accessors {
+		view := accessor.bufferViewIndex()
+
+		if view < 0 || view >= len(gltfDoc.BufferViews) {
+			continue
+		}
+
+		// the data has to be aligned to its component size, counting from the start of the buffer.
+		size := accessor.ComponentType.Size()
+		offset := gltfDoc.BufferViews[view].ByteOffset + accessor.ByteOffset
+
+		if size > 0 && offset%size != 0 {
+			errs = append(errs, fmt.Sprintf("accessors[%d]: starts at byte %d, which isn't aligned to its %d byte components", i, offset, size))
+		}
+	}
+
+	for m, mesh := range gltfDoc.Meshes {
+		for p, primitive := range mesh.Primitives {
+			path := fmt.Sprintf("meshes[%d].primitives[%d]", m, p)
+
+			if len(gltfDoc.primitiveReferenceProblems(path, primitive)) == 0 {
+				if err := gltfDoc.checkPrimitive(primitive); err != nil {
+					errs = append(errs, fmt.Sprintf("%s: %v", path, err))
+				}
+			}
+
+			// the mesh's weights apply to every primitive, so they all need the same number of targets.
+			if len(primitive.Targets) != len(mesh.Primitives[0].Targets) {
+				errs = append(errs, fmt.Sprintf("%s: has %d morph targets, but primitive 0 has %d", path, len(primitive.Targets), len(mesh.Primitives[0].Targets)))
+			}
+		}
+
+		if len(mesh.Weights) > 0 && len(mesh.Primitives) > 0 && len(mesh.Weights) != len(mesh.Primitives[0].Targets) {
+			errs = append(errs, fmt.Sprintf("meshes[%d]: has %d weights but %d morph targets", m, len(mesh.Weights), len(mesh.Primitives[0].Targets)))
+		}
+	}
+
+	for i, node := range gltfDoc.Nodes {
+		if err := checkTransform(node.Translation, node.Rotation, node.Scale, node.Matrix); err != nil {
+			errs = append(errs, fmt.Sprintf("nodes[%d]: %v", i, err))
+		}
+
+		skinIndex, meshIndex := jsonNumber(node.Skin), jsonNumber(node.Mesh)
+
+		// a skinned node without a mesh is a problem of its own, but one whose mesh is missing was reported above.
+		if node.Skin != nil && skinIndex >= 0 && skinIndex < len(gltfDoc.Skins) && (node.Mesh == nil || meshIndex >= 0 && meshIndex < len(gltfDoc.Meshes)) {
+			if err := gltfDoc.checkSkinnedNode(node); err != nil {
+				errs = append(errs, fmt.Sprintf("nodes[%d]: %v", i, err))
+			}
+		}
+	}
+
+	for i, skin := range gltfDoc.Skins {
+		matrixCount := 0
+
+		if skin.InverseBindMatrices != nil {
+			a := *skin.InverseBindMatrices
+
+			if a < 0 || a >= len(gltfDoc.Accessors) {
+				continue
+			}
+
+			if gltfDoc.Accessors[a].Type != Mat4 {
+				errs = append(errs, fmt.Sprintf("skins[%d]: inverseBindMatrices %d is not a MAT4 accessor", i, a))
+				continue
+			}
+
+			matrixCount = gltfDoc.Accessors[a].Count
+		}
+
+		// checkSkin would report a missing joint or skeleton again.
+		if !gltfDoc.skinNodesExist(skin) {
+			continue
+		}
+
+		if err := checkSkin(skin, matrixCount, len(gltfDoc.Nodes)); err != nil {
+			errs = append(errs, fmt.Sprintf("skins[%d]: %v", i, err))
+		}
+	}
+
+	for i, img := range gltfDoc.Images {
+		switch {
+		case img.BufferView != nil && img.URI != "":
+			errs = append(errs, fmt.Sprintf("images[%d]: has both a uri and a bufferView", i))
+		case img.BufferView != nil && img.MimeType == "":
+			errs = append(errs, fmt.Sprintf("images[%d]: is a bufferView, but has no mimeType", i))
+		}
+	}
+
+	// a child that doesn't exist has been reported already; the other thing parentMap catches is a node with two parents.
+	if gltfDoc.childrenExist() {
+		if _, err := gltfDoc.parentMap(); err != nil {
+			errs = append(errs, "nodes: "+err.Error())
+		}
+	}
+
+	return errs
+}
+
+// reports whether every joint of the skin, and its skeleton if it has one, is a node of the document.
+func (gltfDoc GlTF) skinNodesExist(skin Skin) bool {
+	for _, joint := range skin.Joints {
+		if joint < 0 || joint >= len(gltfDoc.Nodes) {
+			return false
+		}
+	}
+
+	return skin.Skeleton == nil || (*skin.Skeleton >= 0 && *skin.Skeleton < len(gltfDoc.Nodes))
+}
+
+// reports whether every child of every node is a node of the document.
+func (gltfDoc GlTF) childrenExist() bool {
+	for _, node := range gltfDoc.Nodes {
+		for _, child := range node.Children {
+			if child < 0 || child >= len(gltfDoc.Nodes) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,85 @@
+package gltf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{testTriangle(Material{Opacity: 1})}}, PipelineOptions{Options: Options{VertexColors: true}})
+	good := filepath.Join(dir, "good")
+
+	if err := WriteGltf(gltfDoc, good, OutputEmbedded, false); err != nil {
+		t.Fatalf("WriteGltf: %v", err)
+	}
+
+	// a view starting before its buffer, which once made Repair panic.
+	bad := filepath.Join(dir, "bad.gltf")
+	document := `{"asset":{"version":"2.0"},"buffers":[{"byteLength":12,"uri":"data:application/octet-stream;base64,AAAAAAAAAAAAAAAA"}],` +
+		`"bufferViews":[{"buffer":0,"byteOffset":-8,"byteLength":12}],"accessors":[{"bufferView":0,"componentType":5126,"count":1,"type":"VEC3"}],` +
+		`"meshes":[{"primitives":[{"attributes":{"POSITION":0}}]}]}`
+
+	if err := os.WriteFile(bad, []byte(document), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	missing := filepath.Join(dir, "missing.gltf")
+	results := ValidateFiles([]string{good + ".gltf", bad, missing}, 2)
+
+	if result := results[good+".gltf"]; result == nil || !result.Passed {
+		t.Errorf("the written file didn't pass: %+v", result)
+	}
+
+	for _, path := range []string{bad, missing} {
+		if result := results[path]; result == nil || result.Passed || len(result.Errors) == 0 {
+			t.Errorf("%s passed, or failed without errors: %+v", filepath.Base(path), result)
+		}
+	}
+}
+
+func TestValidateMissingTexCoord(t *testing.T) {
+	gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{testTriangle(Material{Opacity: 1})}}, PipelineOptions{})
+
+	if err := gltfDoc.Validate(); err != nil {
+		t.Fatalf("the pipeline's own output doesn't validate: %v", err)
+	}
+
+	// the atlas primitive only has TEXCOORD_0, so a normal map sampled with TEXCOORD_1 has nothing to sample.
+	gltfDoc.Images = append(gltfDoc.Images, GltfImage{URI: "normal.png"})
+	gltfDoc.Textures = append(gltfDoc.Textures, GltfTexture{Source: len(gltfDoc.Images) - 1})
+	gltfDoc.Materials[0].NormalTexture = &NormalTextureInfo{Index: len(gltfDoc.Textures) - 1, TexCoord: 1}
+
+	err := gltfDoc.Validate()
+
+	if err == nil || !strings.Contains(err.Error(), "materials[0].normalTexture: samples TEXCOORD_1") {
+		t.Errorf("Validate returned %v, want the missing TEXCOORD_1 of material 0 reported", err)
+	}
+}
+
+func TestValidateExtensionLists(t *testing.T) {
+	gltfDoc := optimizeForTest(t, Model{Meshes: []Geometry{testTriangle(Material{Opacity: 1})}}, PipelineOptions{Options: Options{VertexColors: true}})
+	gltfDoc.ExtensionsRequired = []string{"KHR_mesh_quantization"}
+
+	err := gltfDoc.Validate()
+
+	if err == nil || !strings.Contains(err.Error(), "KHR_mesh_quantization is required but not listed in extensionsUsed") {
+		t.Errorf("Validate returned %v, want the required but unused extension reported", err)
+	}
+
+	gltfDoc.ExtensionsUsed = []string{"KHR_mesh_quantization", "KHR_materials_unlit", "KHR_mesh_quantization"}
+	err = gltfDoc.Validate()
+
+	if err == nil || !strings.Contains(err.Error(), "extensionsUsed[2]: KHR_mesh_quantization is listed more than once") {
+		t.Errorf("Validate returned %v, want the duplicate in extensionsUsed reported", err)
+	}
+
+	gltfDoc.SortExtensions()
+
+	if used := strings.Join(gltfDoc.ExtensionsUsed, ","); used != "KHR_materials_unlit,KHR_mesh_quantization" {
+		t.Errorf("SortExtensions left extensionsUsed as %s, want them deduplicated and sorted", used)
+	}
+}
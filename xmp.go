@@ -0,0 +1,144 @@
+package gltf
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// The KHR_xmp_json_ld extension stores XMP metadata as JSON-LD packets in a document-level array.  Any object in the
+// document (most usefully the asset) can then point at one of those packets by index.
+// See https://github.com/KhronosGroup/glTF/tree/main/extensions/2.0/Khronos/KHR_xmp_json_ld
+
+const xmpExtensionName = "KHR_xmp_json_ld"
+
+// XmpPacket holds the metadata most asset management systems care about.  Only a few of the Dublin Core and XMP
+// properties are typed here; that covers who made the asset, who may use it, and when it was made.
+type XmpPacket struct {
+	Context    map[string]string `json:"@context"`
+	Creator    *XmpList          `json:"dc:creator,omitempty"`
+	Rights     string            `json:"dc:rights,omitempty"`
+	CreateDate string            `json:"xmp:CreateDate,omitempty"`
+}
+
+// XmpList is an ordered JSON-LD list, which is how XMP represents properties such as dc:creator.
+type XmpList struct {
+	List []string `json:"@list"`
+}
+
+// xmpExtension is the document-level KHR_xmp_json_ld object.
+type xmpExtension struct {
+	Packets []XmpPacket `json:"packets"`
+}
+
+// xmpReference is the object-level KHR_xmp_json_ld object.
+type xmpReference struct {
+	Packet int `json:"packet"`
+}
+
+// NewXmpPacket returns a packet with the dc and xmp namespaces declared and the supplied common fields set.  createDate
+// should be an ISO 8601 date, such as "2024-01-31" or "2024-01-31T12:00:00Z".
+func NewXmpPacket(creators []string, rights string, createDate string) XmpPacket {
+	packet := XmpPacket{
+		Context: map[string]string{
+			"dc":  "http://purl.org/dc/elements/1.1/",
+			"xmp": "http://ns.adobe.com/xap/1.0/",
+		},
+		Rights:     rights,
+		CreateDate: createDate,
+	}
+
+	if len(creators) > 0 {
+		packet.Creator = &XmpList{List: creators}
+	}
+
+	return packet
+}
+
+// AddXmpPacket adds the supplied packet to the document's KHR_xmp_json_ld packets and returns its index.
+func (gltfDoc *GlTF) AddXmpPacket(packet XmpPacket) (packetIndex int, err error) {
+	xmp, err := gltfDoc.xmpExtension()
+
+	if err != nil {
+		return -1, err
+	}
+
+	xmp.Packets = append(xmp.Packets, packet)
+
+	extensions, err := setExtension(gltfDoc.Extensions, xmpExtensionName, xmp)
+
+	if err != nil {
+		return -1, err
+	}
+
+	gltfDoc.Extensions = extensions
+	gltfDoc.useExtension(xmpExtensionName)
+
+	return len(xmp.Packets) - 1, nil
+}
+
+// SetAssetXmpPacket adds the supplied packet to the document and makes the asset refer to it, which is where consumers
+// look for the metadata describing the whole file.
+func (gltfDoc *GlTF) SetAssetXmpPacket(packet XmpPacket) error {
+	packetIndex, err := gltfDoc.AddXmpPacket(packet)
+
+	if err != nil {
+		return err
+	}
+
+	switch asset := gltfDoc.Asset.(type) {
+	case nil:
+		a := Asset{Version: "2.0"}
+		a.Extensions, err = setExtension(a.Extensions, xmpExtensionName, xmpReference{Packet: packetIndex})
+		gltfDoc.Asset = a
+	case Asset:
+		asset.Extensions, err = setExtension(asset.Extensions, xmpExtensionName, xmpReference{Packet: packetIndex})
+		gltfDoc.Asset = asset
+	case *Asset:
+		asset.Extensions, err = setExtension(asset.Extensions, xmpExtensionName, xmpReference{Packet: packetIndex})
+	case map[string]interface{}:
+		// this is what an asset looks like after the document has been decoded with encoding/json.
+		asset["extensions"], err = setExtension(asset["extensions"], xmpExtensionName, xmpReference{Packet: packetIndex})
+	default:
+		err = fmt.Errorf("don't know how to attach metadata to an asset of type %T", gltfDoc.Asset)
+	}
+
+	return err
+}
+
+// XmpPackets returns the document's KHR_xmp_json_ld packets.  This works for documents built in Go as well as for
+// documents decoded from JSON, where the extension is just a map.
+func (gltfDoc GlTF) XmpPackets() ([]XmpPacket, error) {
+	xmp, err := gltfDoc.xmpExtension()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return xmp.Packets, nil
+}
+
+// returns the document's KHR_xmp_json_ld object, or an empty one if it doesn't have one yet.
+func (gltfDoc GlTF) xmpExtension() (xmpExtension, error) {
+	xmp := xmpExtension{}
+
+	extensions, ok := gltfDoc.Extensions.(map[string]interface{})
+
+	if !ok || extensions[xmpExtensionName] == nil {
+		return xmp, nil
+	}
+
+	if typed, ok := extensions[xmpExtensionName].(xmpExtension); ok {
+		return typed, nil
+	}
+
+	// decoded documents hold a generic map, so round-trip it through JSON to get the typed version.
+	raw, err := json.Marshal(extensions[xmpExtensionName])
+
+	if err != nil {
+		return xmp, err
+	}
+
+	err = json.Unmarshal(raw, &xmp)
+
+	return xmp, err
+}